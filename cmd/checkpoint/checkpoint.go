@@ -0,0 +1,144 @@
+package checkpoint
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/checkpoint"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	step  string
+	key   string
+	state string
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run-with-checkpoint --step <name> -- <command> [args...]",
+		Short: "Run a deployment step unless it already completed",
+		Long: `Run a deployment step (resolution, schema, data, deploy, ...) and record it as completed in the --state store.
+When a build is retried after a flaky step, steps that already completed for the same key are skipped instead of rerun.
+
+A Cloud Build retry starts with a fresh /workspace, so --state must be a gs://<bucket>/<prefix> URI or a path outside the
+workspace that survives the retry.
+
+The key defaults to $COMMIT_SHA, so checkpoints never carry over to a different commit. Cloud Build only sets COMMIT_SHA
+in a step that passes it with env: ['COMMIT_SHA=$COMMIT_SHA'], and the command fails when neither is set.`,
+		Example: "  deployment-tools run-with-checkpoint --state gs://my-deploy-state/checkpoints --step schema -- deployment-tools db spanner bootstrap --data-dir ''",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd, args); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&c.step, "step", "", "Name of the step")
+	cmd.Flags().StringVar(&c.key, "key", "", "Key scoping the checkpoint, defaults to $COMMIT_SHA")
+	cmd.Flags().StringVar(&c.state, "state", "", "Where completed steps are recorded: a gs://<bucket>/<prefix> URI, or the path of a file outside the workspace")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(_ *cobra.Command) error {
+	if c.step == "" {
+		return errors.New("--step is required")
+	}
+	if c.state == "" {
+		return errors.New("--state is required")
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	if c.key == "" {
+		c.key = conf.commitSHA
+	}
+	// an empty key would match the checkpoints of every earlier commit
+	if c.key == "" {
+		return deployerr.New(deployerr.Config, "invalid_flags", errors.New("--key is required when COMMIT_SHA is not set"))
+	}
+
+	if conf.buildID != "" && inWorkspace(c.state) {
+		return deployerr.New(deployerr.Config, "invalid_flags", errors.Newf("--state %s is in the workspace, which a retried build does not keep. Use a gs:// URI or a path outside %s", c.state, workspaceDir))
+	}
+
+	store, err := checkpoint.Open(c.state)
+	if err != nil {
+		return errors.Wrap(err, "checkpoint.Open()")
+	}
+
+	cp, ok, err := store.Completed(ctx, c.step, c.key)
+	if err != nil {
+		return errors.Wrap(err, "checkpoint.Store.Completed()")
+	}
+	if ok {
+		log.Printf("Skipping step %q: already completed at %s (took %s)\n", c.step, cp.CompletedAt.Format(time.RFC3339), cp.Duration.Round(time.Second))
+
+		return nil
+	}
+
+	log.Printf("Running step %q\n", c.step)
+	start := time.Now()
+
+	run := exec.CommandContext(ctx, args[0], args[1:]...) //nolint:gosec // running the given command is the purpose of the wrapper
+	run.Stdin = os.Stdin
+	run.Stdout = cmd.OutOrStdout()
+	run.Stderr = cmd.ErrOrStderr()
+	if err := run.Run(); err != nil {
+		return errors.Wrapf(err, "step %q failed", c.step)
+	}
+
+	if err := store.Complete(ctx, c.step, c.key, time.Since(start)); err != nil {
+		return errors.Wrap(err, "checkpoint.Store.Complete()")
+	}
+	log.Printf("Step %q completed\n", c.step)
+
+	return nil
+}
+
+// workspaceDir is the directory Cloud Build mounts the source in, recreated for every build
+const workspaceDir = "/workspace"
+
+// inWorkspace reports whether the local path state resolves inside the Cloud Build workspace
+func inWorkspace(state string) bool {
+	if strings.HasPrefix(state, "gs://") {
+		return false
+	}
+
+	abs, err := filepath.Abs(state)
+	if err != nil {
+		return true
+	}
+
+	return abs == workspaceDir || strings.HasPrefix(abs, workspaceDir+"/")
+}
@@ -0,0 +1,30 @@
+package checkpoint
+
+import (
+	"context"
+
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+)
+
+type envConfig struct {
+	CommitSHA string `env:"COMMIT_SHA"`
+	BuildID   string `env:"BUILD_ID"`
+}
+
+type config struct {
+	commitSHA string
+	buildID   string
+}
+
+func newConfig(ctx context.Context) (*config, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return nil, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	return &config{
+		commitSHA: envVars.CommitSHA,
+		buildID:   envVars.BuildID,
+	}, nil
+}
@@ -2,24 +2,199 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"os"
 
+	"github.com/cccteam/deployment-tools/cmd/checkpoint"
 	"github.com/cccteam/deployment-tools/cmd/db"
+	"github.com/cccteam/deployment-tools/cmd/dev"
+	"github.com/cccteam/deployment-tools/cmd/plugins"
+	"github.com/cccteam/deployment-tools/cmd/release"
+	"github.com/cccteam/deployment-tools/cmd/whoami"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/environments"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/notify"
+	"github.com/cccteam/deployment-tools/internal/plugin"
+	"github.com/cccteam/deployment-tools/internal/redact"
 	"github.com/go-playground/errors/v5"
 	"github.com/spf13/cobra"
 )
 
-// Execute configures the root command for the application and executes it
+// Execute configures the root command for the application and executes it. An unknown command runs the
+// deployment-tools-<command> plugin if one is on PATH. Secrets are masked in the log and command output. When
+// DEPLOYMENT_TOOLS_ERROR_FORMAT is json, a failure is written to stderr as a JSON report instead of text. Inside
+// Cloud Build, a failure is also posted to DEPLOYMENT_TOOLS_FAILURE_WEBHOOK with a link to the build logs.
 func Execute(ctx context.Context) error {
-	cmd := &cobra.Command{
-		Use:   "deployment-tools",
-		Short: "A command line to to be used for executing different actions during a deployment process",
+	redactor, err := redact.FromEnv(ctx, os.Environ())
+	if err != nil {
+		return errors.Wrap(err, "redact.FromEnv()")
 	}
+	logOutput := redactor.Writer(os.Stderr)
+	log.SetOutput(logOutput)
 
-	cmd.AddCommand(db.Command(ctx))
+	jsonErrors, err := deployerr.JSONOutput(ctx)
+	if err != nil {
+		return errors.Wrap(err, "deployerr.JSONOutput()")
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+
+	// the output of hooks, plugins and checkpointed commands is written through these as well
+	stdout, stderr := redactor.LineWriter(os.Stdout), redactor.LineWriter(os.Stderr)
+	defer func() {
+		for _, w := range []*redact.LineWriter{stdout, stderr} {
+			if err := w.Flush(); err != nil {
+				log.Printf("failed to flush output: %v", err)
+			}
+		}
+	}()
+
+	root := Command(ctx)
+	root.SetOut(stdout)
+	root.SetErr(stderr)
+	root.SilenceErrors = jsonErrors
+
+	if p, args, ok := findPlugin(root, os.Args[1:]); ok {
+		if err := p.Run(ctx, args, stdout, stderr); err != nil {
+			reportBuildFailure(ctx, conf, redactor, root.CommandPath()+" "+p.Name, err)
+
+			return errors.Wrap(err, "plugin.Plugin.Run()")
+		}
+
+		return nil
+	}
+
+	cmd, err := root.ExecuteC()
+	// --quiet filters the log while the command runs, not the failure reported below
+	log.SetOutput(logOutput)
+	if err != nil {
+		reportBuildFailure(ctx, conf, redactor, cmd.CommandPath(), err)
+
+		if jsonErrors {
+			if werr := deployerr.WriteJSON(root.ErrOrStderr(), err); werr != nil {
+				return errors.Wrap(werr, "deployerr.WriteJSON()")
+			}
+
+			return deployerr.Reported(err)
+		}
 
-	if err := cmd.Execute(); err != nil {
 		return errors.Wrap(err, "cmd.Execute()")
 	}
 
 	return nil
 }
+
+// reportBuildFailure logs the link to the build logs when command failed inside Cloud Build, and posts it with a
+// summary of the failure to the failure webhook, if one is configured. Configuration errors, such as a mistyped
+// flag, are not posted, as they are fixed in the build file rather than investigated.
+func reportBuildFailure(ctx context.Context, conf *config, redactor *redact.Redactor, command string, err error) {
+	if conf.buildID == "" {
+		return
+	}
+
+	logURL := notify.BuildLogURL(conf.projectID, conf.location, conf.buildID)
+	log.Printf("Build logs: %s\n", logURL)
+
+	if category, _ := deployerr.Classify(err); category == deployerr.Config {
+		return
+	}
+
+	text := fmt.Sprintf("%s failed in build %s: %s\nLogs: %s", command, conf.buildID, deployerr.NewReport(err).Message, logURL)
+	notify.Send(ctx, conf.failureWebhook, redactor.Redact(text))
+}
+
+// findPlugin returns the plugin named by the first argument, and the arguments to pass it, when the argument
+// is not a built-in command. Built-in commands always take precedence.
+func findPlugin(root *cobra.Command, args []string) (plugin.Plugin, []string, bool) {
+	if len(args) == 0 {
+		return plugin.Plugin{}, nil, false
+	}
+	if cmd, _, err := root.Find(args); err == nil && cmd != root {
+		return plugin.Plugin{}, nil, false
+	}
+
+	p, ok := plugin.Find(args[0])
+
+	return p, args[1:], ok
+}
+
+const (
+	environmentFlag      = "environment"
+	environmentsFileFlag = "environments-file"
+)
+
+// applyEnvironment sets the environment variables of the environment named by --environment, so every command
+// reads its database from the environments file
+func applyEnvironment(cmd *cobra.Command) error {
+	name, err := cmd.Flags().GetString(environmentFlag)
+	if err != nil {
+		return errors.Wrapf(err, "cobra.Command.Flags().GetString(%q)", environmentFlag)
+	}
+	if name == "" {
+		return nil
+	}
+
+	path, err := cmd.Flags().GetString(environmentsFileFlag)
+	if err != nil {
+		return errors.Wrapf(err, "cobra.Command.Flags().GetString(%q)", environmentsFileFlag)
+	}
+
+	file, err := environments.Load(path)
+	if err != nil {
+		return errors.Wrap(err, "environments.Load()")
+	}
+
+	if err := file.Apply(name); err != nil {
+		return errors.Wrap(err, "environments.File.Apply()")
+	}
+
+	return nil
+}
+
+// Command returns the configured root command for the application
+func Command(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deployment-tools",
+		Short: "A command line to to be used for executing different actions during a deployment process",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := applyEnvironment(cmd); err != nil {
+				return errors.Wrap(err, "applyEnvironment()")
+			}
+			if err := applyOutputFlags(cmd); err != nil {
+				return errors.Wrap(err, "applyOutputFlags()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return deployerr.New(deployerr.Config, "invalid_flags", err)
+	})
+
+	cmd.PersistentFlags().
+		Duration(heartbeat.FlagName, heartbeat.DefaultInterval, "Interval between progress log lines during long-running operations, so builds do not look hung. Zero disables them.")
+
+	cmd.PersistentFlags().
+		Bool(quietFlag, false, "Only log warnings and failures, and disable heartbeats unless --heartbeat-interval is set. Command output such as tables and reports is still written.")
+	cmd.PersistentFlags().
+		Bool(noColorFlag, false, "Set NO_COLOR for hooks, plugins and checkpointed commands, so their output has no ANSI colors")
+	cmd.PersistentFlags().
+		String(environmentFlag, "", "Environment from the environments file whose project, instance and database the command uses. Fails if one of its variables is already set to a different value.")
+	cmd.PersistentFlags().
+		String(environmentsFileFlag, environments.DefaultFile, "Path to the environments file read for --environment")
+
+	cmd.AddCommand(checkpoint.Command(ctx))
+	cmd.AddCommand(db.Command(ctx))
+	cmd.AddCommand(dev.Command(ctx))
+	cmd.AddCommand(plugins.Command(ctx))
+	cmd.AddCommand(release.Command(ctx))
+	cmd.AddCommand(whoami.Command(ctx))
+
+	return cmd
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+)
+
+// envConfig is read before --environment applies, so the build variables must be set in the environment of the
+// step, e.g. with env: entries in the Cloud Build file, rather than in the environments file
+type envConfig struct {
+	BuildID        string `env:"BUILD_ID"`
+	ProjectID      string `env:"PROJECT_ID"`
+	Location       string `env:"LOCATION"`
+	FailureWebhook string `env:"DEPLOYMENT_TOOLS_FAILURE_WEBHOOK"`
+}
+
+type config struct {
+	buildID        string
+	projectID      string
+	location       string
+	failureWebhook string
+}
+
+func newConfig(ctx context.Context) (*config, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return nil, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	return &config{
+		buildID:        envVars.BuildID,
+		projectID:      envVars.ProjectID,
+		location:       envVars.Location,
+		failureWebhook: envVars.FailureWebhook,
+	}, nil
+}
@@ -4,10 +4,18 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/cccteam/deployment-tools/internal/compat"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/gcs"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/hooks"
+	"github.com/cccteam/deployment-tools/internal/spanneriam"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
+	"github.com/cccteam/deployment-tools/internal/spannerrole"
 	"github.com/go-playground/errors/v5"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/spf13/cobra"
@@ -23,6 +31,22 @@ func Command(ctx context.Context) *cobra.Command {
 type command struct {
 	dataMigrationDirs   []string
 	SchemaMigrationDirs []string
+	statementTimeout    time.Duration
+	mutationLimit       int64
+	partitionLargeDML   bool
+	iamBindingsFile     string
+	rolesFile           string
+	optimizerOptions    spannermigrate.OptimizerOptions
+	compatibilityFile   string
+	runningAppVersion   string
+	allowIncompatible   bool
+	reportFile          string
+	reportGCSURI        string
+	report              *spannermigrate.Report
+	hooksFile           string
+	hooks               *hooks.Config
+	lockTTL             time.Duration
+	lockWait            time.Duration
 }
 
 // Setup returns the configured cli command
@@ -33,7 +57,7 @@ func (c *command) Setup(ctx context.Context) *cobra.Command {
 		Long:  "Bootstrap database by running specified migrations. This will first run the schema migrations (if they are provided), followed by data migrations",
 		RunE: func(cmd *cobra.Command, _ []string) (err error) {
 			if err := c.ValidateFlags(cmd); err != nil {
-				return err
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
 			}
 
 			if err := c.Run(ctx, cmd); err != nil {
@@ -45,140 +69,299 @@ func (c *command) Setup(ctx context.Context) *cobra.Command {
 	}
 
 	cmd.Flags().
-		StringSliceVar(&c.SchemaMigrationDirs, "schema-dir", []string{"file://schema/migrations"}, "Directories containing schema migration files, using the file URI syntax. Multiple directories should be comma-separated. When using multiple directories the first migration version should resume where the previous directory ended.")
+		StringSliceVar(&c.SchemaMigrationDirs, "schema-dir", []string{"file://schema/migrations"}, "Directories containing schema migration files, using the file URI syntax. Multiple directories should be comma-separated. When using multiple directories the first migration version should resume where the previous directory ended. A directory given as <name>=<dir> is applied in order with its own version table, SchemaMigrations_<name>.")
 	cmd.Flags().
 		StringSliceVar(&c.dataMigrationDirs, "data-dir", []string{"file://bootstrap/testdata"}, "Directories containing data migration files, using the file URI syntax. Multiple directories should be comma-separated. When using multiple directories the first migration version should resume where the previous directory ended.")
+	cmd.Flags().DurationVar(&c.statementTimeout, "statement-timeout", 0, "Maximum time each statement of a DML-only data migration may run, e.g. 10m. Zero disables the timeout.")
+	cmd.Flags().
+		Int64Var(&c.mutationLimit, "mutation-limit", spannermigrate.DefaultMutationLimit, "Reject pending data migrations estimated to exceed this many mutations in a single transaction. Zero disables the check.")
+	cmd.Flags().
+		BoolVar(&c.partitionLargeDML, "partition-large-dml", false, "Execute UPDATE and DELETE statements that exceed the mutation limit as partitioned DML instead of rejecting them. These statements must be idempotent.")
+	cmd.Flags().
+		StringVar(&c.iamBindingsFile, "iam-bindings", "", "Path to a JSON file of database IAM bindings to apply after migrations, see 'db spanner grant'")
+	cmd.Flags().
+		StringVar(&c.rolesFile, "roles-file", "", "Path to a YAML file of fine-grained access control database roles and grants to create after migrations. Existing roles are verified against it.")
+	c.optimizerOptions.AddFlags(cmd)
+	cmd.Flags().
+		StringVar(&c.compatibilityFile, "compatibility-file", "", "Path to a YAML file declaring the minimum running application version required by schema versions. Pending schema migrations are checked against --running-app-version before any are applied.")
+	cmd.Flags().
+		StringVar(&c.runningAppVersion, "running-app-version", "", "Version of the application currently serving traffic, e.g. v1.4.2. Empty when nothing is running yet.")
+	cmd.Flags().BoolVar(&c.allowIncompatible, "allow-incompatible-schema", false, "Apply schema migrations even when the compatibility check fails")
+	cmd.Flags().
+		StringVar(&c.reportFile, "report-file", "", "Write a JSON report of the applied migrations to this path, e.g. migration-report.json. The report is written even when a migration fails.")
+	cmd.Flags().StringVar(&c.reportGCSURI, "report-gcs-uri", "", "Also upload the JSON report under this gs://<bucket>/<prefix> URI as <database>-<timestamp>.json")
+	cmd.Flags().StringVar(&c.hooksFile, "hooks-file", "", "Path to a YAML file of preMigrate, postMigrate and migrateFailed hooks to run around the migrations")
+	cmd.Flags().DurationVar(&c.lockTTL, "lock-ttl", spannermigrate.DefaultLockTTL, "How long the migration lock is held if the bootstrap dies without releasing it")
+	cmd.Flags().
+		DurationVar(&c.lockWait, "lock-wait", 0, "How long to wait for another deploy or reseed of the database to release its lock before failing, e.g. 30m, so overlapping deploys queue. Zero fails fast.")
 
 	return cmd
 }
 
 func (c *command) ValidateFlags(cmd *cobra.Command) error {
+	if c.statementTimeout < 0 {
+		return errors.Newf("--statement-timeout must not be negative, got %s", c.statementTimeout)
+	}
+	if c.mutationLimit < 0 {
+		return errors.Newf("--mutation-limit must not be negative, got %d", c.mutationLimit)
+	}
+	if err := c.optimizerOptions.ValidateFlags(); err != nil {
+		return errors.Wrap(err, "spannermigrate.OptimizerOptions.ValidateFlags()")
+	}
+	if c.reportGCSURI != "" {
+		if _, _, err := gcs.ParseURI(c.reportGCSURI); err != nil {
+			return errors.Wrap(err, "--report-gcs-uri")
+		}
+	}
+	if c.lockTTL <= 0 {
+		return errors.Newf("--lock-ttl must be greater than 0, got %s", c.lockTTL)
+	}
+	if c.lockWait < 0 {
+		return errors.Newf("--lock-wait must not be negative, got %s", c.lockWait)
+	}
+
 	return nil
 }
 
 func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	interval, err := heartbeat.Interval(cmd)
+	if err != nil {
+		return err
+	}
+
+	if c.hooksFile != "" {
+		c.hooks, err = hooks.Load(c.hooksFile)
+		if err != nil {
+			return errors.Wrap(err, "hooks.Load()")
+		}
+		c.hooks.SetOutput(cmd.OutOrStdout(), cmd.ErrOrStderr())
+	}
+
+	var roles *spannerrole.Definition
+	if c.rolesFile != "" {
+		roles, err = spannerrole.Load(c.rolesFile)
+		if err != nil {
+			return errors.Wrap(err, "spannerrole.Load()")
+		}
+	}
+
+	var bindings []spanneriam.Binding
+	if c.iamBindingsFile != "" {
+		bindings, err = spanneriam.Load(c.iamBindingsFile)
+		if err != nil {
+			return errors.Wrap(err, "spanneriam.Load()")
+		}
+	}
+
 	conf, err := newConfig(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to initialize config")
 	}
 	defer conf.close()
 
-	switch len(c.SchemaMigrationDirs) {
-	case 0:
-		log.Println("No schema migration directory specified, skipping schema migrations")
-	case 1:
-		if err := migrateSchema(ctx, conf, c.SchemaMigrationDirs[0]); err != nil {
-			return errors.Wrap(err, "migrateSchema()")
-		}
-	default:
-		if err := linkAndMigrateDirs(ctx, conf, c.SchemaMigrationDirs, schemaMigrateType); err != nil {
-			return err
+	holder := spannermigrate.LockHolder("bootstrap", conf.buildID)
+	if err := conf.migrateClient.AcquireLockWait(ctx, spannermigrate.MigrationLock, holder, c.lockTTL, c.lockWait); err != nil {
+		return errors.Wrap(err, "database is being rebuilt or migrated by another build, retry once it has finished or pass --lock-wait to queue")
+	}
+	defer func() {
+		if err := conf.migrateClient.ReleaseLock(context.WithoutCancel(ctx), spannermigrate.MigrationLock, holder); err != nil {
+			log.Printf("failed to release migration lock: %v", err)
 		}
+	}()
+
+	conf.migrateClient.
+		WithHeartbeat(interval).
+		WithStatementTimeout(c.statementTimeout).
+		WithMutationLimit(c.mutationLimit).
+		WithPartitionLargeDML(c.partitionLargeDML)
+
+	if c.reportFile == "" && c.reportGCSURI == "" {
+		return c.migrateWithHooks(ctx, conf, roles, bindings)
 	}
 
-	switch len(c.dataMigrationDirs) {
-	case 0:
-		log.Println("No Data Migration scripts provided. No changes applied.")
-	case 1:
-		if err := migrateData(ctx, conf, c.dataMigrationDirs[0]); err != nil {
-			return errors.Wrap(err, "migrateData()")
-		}
-	default:
-		if err := linkAndMigrateDirs(ctx, conf, c.dataMigrationDirs, dataMigrateType); err != nil {
-			return err
+	c.report = spannermigrate.NewReport()
+	conf.migrateClient.WithReport(c.report)
+	migrateErr := c.migrateWithHooks(ctx, conf, roles, bindings)
+	c.report.Finish(migrateErr)
+
+	if err := c.writeReport(ctx, conf.databaseName); err != nil {
+		if migrateErr != nil {
+			log.Printf("ERROR: failed to write migration report: %v", err)
+
+			return migrateErr
 		}
+
+		return err
 	}
 
-	return nil
+	return migrateErr
 }
 
-type migrateType string
+// migrateWithHooks runs migrate between the preMigrate and postMigrate hooks, running the migrateFailed hooks instead
+// of postMigrate when it fails
+func (c *command) migrateWithHooks(ctx context.Context, conf *config, roles *spannerrole.Definition, bindings []spanneriam.Binding) error {
+	vars := map[string]string{"DEPLOY_HOOK_DATABASE": conf.databaseName}
+	if err := c.hooks.Run(ctx, hooks.PreMigrate, vars); err != nil {
+		return errors.Wrap(err, "hooks.Config.Run()")
+	}
 
-const (
-	schemaMigrateType migrateType = "schema"
-	dataMigrateType   migrateType = "data"
-)
+	if err := c.migrate(ctx, conf.migrateClient, roles, bindings); err != nil {
+		vars["DEPLOY_HOOK_ERROR"] = errors.Cause(err).Error()
+		if hookErr := c.hooks.Run(ctx, hooks.MigrateFailed, vars); hookErr != nil {
+			log.Printf("ERROR: %v", hookErr)
+		}
 
-// linkAndMigrateDirs expects migrateType to be `schema` or `data`, corresponding to the schema migrations and
-// data migrations tables, respectively.
-func linkAndMigrateDirs(ctx context.Context, conf *config, migrationSourceURLs []string, mt migrateType) error {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return errors.Wrap(err, "os.Getwd()")
+		return err
 	}
 
-	tempAllMigrationsDirPath, err := os.MkdirTemp(cwd, "all_migrations")
-	if err != nil {
-		return errors.Wrap(err, "os.MkdirTemp()")
+	if err := c.hooks.Run(ctx, hooks.PostMigrate, vars); err != nil {
+		return errors.Wrap(err, "hooks.Config.Run()")
 	}
-	defer func() {
-		if err := os.RemoveAll(tempAllMigrationsDirPath); err != nil {
-			log.Printf("error: %v\n", errors.Wrap(err, "os.RemoveAll()"))
+
+	return nil
+}
+
+// migrate runs the compatibility check, migrations, optimizer, database role and IAM steps of the bootstrap
+func (c *command) migrate(ctx context.Context, client *spannermigrate.Client, roles *spannerrole.Definition, bindings []spanneriam.Binding) error {
+	if c.compatibilityFile != "" && len(c.SchemaMigrationDirs) > 0 {
+		if err := c.checkCompatibility(client); err != nil {
+			return err
 		}
-	}()
+	}
 
-	for _, migrationSourceURL := range migrationSourceURLs {
-		migrationDirClean := strings.TrimPrefix(migrationSourceURL, "file://")
-		migrationDir, err := os.ReadDir(migrationDirClean)
+	if len(c.SchemaMigrationDirs) == 0 {
+		log.Println("No schema migration directory specified, skipping schema migrations")
+	} else if err := migrateDirs(ctx, c.SchemaMigrationDirs, client.MigrateUpSchemaDirs, "Schema"); err != nil {
+		return err
+	}
+
+	if !c.optimizerOptions.IsZero() {
+		changed, err := client.SetOptimizerOptions(ctx, c.optimizerOptions)
 		if err != nil {
-			return errors.Wrap(err, "os.ReadDir()")
+			return errors.Wrap(err, "spannermigrate.Client.SetOptimizerOptions()")
+		}
+		if changed {
+			log.Printf("Optimizer options set: %s\n", c.optimizerOptions)
 		}
+	}
 
-		for _, dirEntry := range migrationDir {
-			if dirEntry.IsDir() {
-				continue
-			}
+	if len(c.dataMigrationDirs) == 0 {
+		log.Println("No Data Migration scripts provided. No changes applied.")
+	} else if err := migrateDirs(ctx, c.dataMigrationDirs, client.MigrateUpDataDirs, "Data"); err != nil {
+		return err
+	}
 
-			oldPath := filepath.Join(migrationDirClean, dirEntry.Name())
-			newPath := filepath.Join(tempAllMigrationsDirPath, dirEntry.Name())
+	if roles != nil {
+		log.Printf("Applying database roles from %s\n", c.rolesFile)
+		plan, err := client.ApplyRoles(ctx, roles)
+		if err != nil {
+			return errors.Wrap(err, "spannermigrate.Client.ApplyRoles()")
+		}
+		for _, stmt := range plan.Statements {
+			log.Printf("Applied: %s\n", stmt)
+		}
+		for _, drift := range plan.Drift {
+			c.report.Warn("database roles: %s", drift)
+		}
+	}
 
-			if err := os.Link(oldPath, newPath); err != nil {
-				return errors.Wrap(err, "os.Link()")
-			}
+	if len(bindings) > 0 {
+		log.Printf("Applying database IAM bindings from %s\n", c.iamBindingsFile)
+		if _, err := client.GrantIAM(ctx, bindings, false); err != nil {
+			return errors.Wrap(err, "spannermigrate.Client.GrantIAM()")
 		}
 	}
 
-	switch mt {
-	case schemaMigrateType:
-		if err := migrateSchema(ctx, conf, fmt.Sprintf("file://%s", tempAllMigrationsDirPath)); err != nil {
-			return errors.Wrap(err, "migrateSchema()")
+	return nil
+}
+
+// checkCompatibility verifies that the pending schema migrations do not require a newer application than the one running
+func (c *command) checkCompatibility(client *spannermigrate.Client) error {
+	declaration, err := compat.Load(c.compatibilityFile)
+	if err != nil {
+		return errors.Wrap(err, "compat.Load()")
+	}
+
+	sets, err := spannermigrate.ParseSchemaDirs(c.SchemaMigrationDirs)
+	if err != nil {
+		return errors.Wrap(err, "spannermigrate.ParseSchemaDirs()")
+	}
+	for _, name := range declaration.SchemaSets() {
+		if !slices.ContainsFunc(sets, func(s spannermigrate.SchemaSet) bool { return s.Name == name }) {
+			return deployerr.New(deployerr.Config, "unknown_schema_set", errors.Newf("%s declares requirements for schema set %q, which --schema-dir does not include", c.compatibilityFile, name))
 		}
+	}
 
-	case dataMigrateType:
-		if err := migrateData(ctx, conf, fmt.Sprintf("file://%s", tempAllMigrationsDirPath)); err != nil {
-			return errors.Wrap(err, "migrateData()")
+	pending := make(map[string][]uint, len(sets))
+	for _, set := range sets {
+		versions, err := pendingSchemaVersions(client, set)
+		if err != nil {
+			return err
 		}
+		pending[set.Name] = versions
+	}
 
-	default:
-		return errors.Newf("expected %q or %q migration type, got %q", schemaMigrateType, dataMigrateType, mt)
+	if err := declaration.Check(pending, c.runningAppVersion); err != nil {
+		if !c.allowIncompatible {
+			return errors.Wrap(err, "schema compatibility check failed, use --allow-incompatible-schema to override")
+		}
+		c.report.Warn("applying schema migrations despite failed compatibility check: %v", err)
 	}
 
 	return nil
 }
 
-func migrateSchema(ctx context.Context, conf *config, migrationSourceURL string) error {
-	log.Printf("Running bootstrap migrations with schema dir: %s \n", migrationSourceURL)
-	if err := conf.migrateClient.MigrateUpSchema(ctx, migrationSourceURL); err != nil &&
-		!errors.Is(err, migrate.ErrNoChange) {
-		return errors.Wrap(err, "failed to run schema migrations")
-	} else if errors.Is(err, migrate.ErrNoChange) {
-		log.Println("No new Migration scripts found. No changes applied.")
-	} else {
-		log.Println("Schema migrations successful")
+// pendingSchemaVersions returns the versions of the migrations of set that have not been applied yet
+func pendingSchemaVersions(client *spannermigrate.Client, set spannermigrate.SchemaSet) ([]uint, error) {
+	sourceURL, cleanup, err := spannermigrate.LinkDirs(set.Dirs)
+	if err != nil {
+		return nil, errors.Wrap(err, "spannermigrate.LinkDirs()")
+	}
+	defer cleanup()
+
+	versions, err := client.PendingSchemaVersions(sourceURL, set.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "spannermigrate.Client.PendingSchemaVersions()")
+	}
+
+	return versions, nil
+}
+
+// writeReport writes the migration report to the report file and uploads it to Cloud Storage, as configured
+func (c *command) writeReport(ctx context.Context, databaseName string) error {
+	if c.reportFile != "" {
+		if err := c.report.WriteFile(c.reportFile); err != nil {
+			return errors.Wrap(err, "spannermigrate.Report.WriteFile()")
+		}
+		log.Printf("Migration report written to %s\n", c.reportFile)
+	}
+
+	if c.reportGCSURI != "" {
+		b, err := c.report.JSON()
+		if err != nil {
+			return errors.Wrap(err, "spannermigrate.Report.JSON()")
+		}
+
+		name := fmt.Sprintf("%s-%s.json", databaseName, c.report.StartedAt.Format("20060102T150405Z"))
+		uri, err := gcs.Upload(ctx, c.reportGCSURI, name, "application/json", b)
+		if err != nil {
+			return errors.Wrap(err, "gcs.Upload()")
+		}
+		log.Printf("Migration report uploaded to %s\n", uri)
 	}
 
 	return nil
 }
 
-func migrateData(ctx context.Context, conf *config, migrationSourceURL string) error {
-	log.Println("Running bootstrap data migrations")
-	if err := conf.migrateClient.MigrateUpData(ctx, migrationSourceURL); err != nil &&
-		!errors.Is(err, migrate.ErrNoChange) {
-		return errors.Wrap(err, "failed to run data migrations")
+// migrateDirs runs the migrations of all dirs with run, treating no change as success
+func migrateDirs(ctx context.Context, dirs []string, run func(ctx context.Context, sourceURLs []string) error, kind string) error {
+	log.Printf("Running bootstrap %s migrations from: %s\n", strings.ToLower(kind), strings.Join(dirs, ", "))
+	if err := run(ctx, dirs); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return errors.Wrapf(err, "failed to run %s migrations", strings.ToLower(kind))
 	} else if errors.Is(err, migrate.ErrNoChange) {
 		log.Println("No new Migration scripts found. No changes applied.")
 	} else {
-		log.Println("Data migrations successful")
+		log.Printf("%s migrations successful\n", kind)
 	}
 
 	return nil
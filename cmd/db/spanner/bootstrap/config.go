@@ -4,7 +4,7 @@ import (
 	"context"
 	"log"
 
-	dbinitiator "github.com/cccteam/db-initiator"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
 	"github.com/go-playground/errors/v5"
 	"github.com/sethvargo/go-envconfig"
 	"google.golang.org/api/option"
@@ -14,10 +14,14 @@ type envConfig struct {
 	SpannerProjectID    string `env:"GOOGLE_CLOUD_SPANNER_PROJECT"`
 	SpannerInstanceID   string `env:"GOOGLE_CLOUD_SPANNER_INSTANCE_ID"`
 	SpannerDatabaseName string `env:"GOOGLE_CLOUD_SPANNER_DATABASE_NAME"`
+	AppEnv              string `env:"_APP_ENV"`
+	BuildID             string `env:"BUILD_ID"`
 }
 
 type config struct {
-	migrateClient *dbinitiator.SpannerMigrator
+	migrateClient *spannermigrate.Client
+	databaseName  string
+	buildID       string
 }
 
 func newConfig(ctx context.Context) (*config, error) {
@@ -26,7 +30,7 @@ func newConfig(ctx context.Context) (*config, error) {
 		return nil, errors.Wrap(err, "envconfig.Process()")
 	}
 
-	db, err := dbinitiator.NewSpannerMigrator(
+	db, err := spannermigrate.Connect(
 		ctx,
 		envVars.SpannerProjectID,
 		envVars.SpannerInstanceID,
@@ -38,7 +42,9 @@ func newConfig(ctx context.Context) (*config, error) {
 	}
 
 	return &config{
-		migrateClient: db,
+		migrateClient: db.WithEnvironment(envVars.AppEnv),
+		databaseName:  envVars.SpannerDatabaseName,
+		buildID:       envVars.BuildID,
 	}, nil
 }
 
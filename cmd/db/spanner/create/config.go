@@ -0,0 +1,64 @@
+package create
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+	"google.golang.org/api/option"
+)
+
+type envConfig struct {
+	SpannerProjectID    string `env:"GOOGLE_CLOUD_SPANNER_PROJECT"`
+	SpannerInstanceID   string `env:"GOOGLE_CLOUD_SPANNER_INSTANCE_ID"`
+	SpannerDatabaseName string `env:"GOOGLE_CLOUD_SPANNER_DATABASE_NAME"`
+}
+
+type config struct {
+	instanceStr  string
+	databaseName string
+	admin        *database.DatabaseAdminClient
+}
+
+func newConfig(ctx context.Context) (*config, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return nil, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	admin, err := database.NewDatabaseAdminClient(ctx, option.WithTelemetryDisabled())
+	if err != nil {
+		return nil, errors.Wrap(err, "database.NewDatabaseAdminClient()")
+	}
+
+	return &config{
+		instanceStr:  fmt.Sprintf("projects/%s/instances/%s", envVars.SpannerProjectID, envVars.SpannerInstanceID),
+		databaseName: envVars.SpannerDatabaseName,
+		admin:        admin,
+	}, nil
+}
+
+// databaseStr returns the full name of the database dbID in the configured instance
+func (c *config) databaseStr(dbID string) string {
+	return c.instanceStr + "/databases/" + dbID
+}
+
+// connect returns a data client for the database dbID
+func (c *config) connect(ctx context.Context, dbID string) (*spanner.Client, error) {
+	client, err := spanner.NewClient(ctx, c.databaseStr(dbID), option.WithTelemetryDisabled())
+	if err != nil {
+		return nil, errors.Wrapf(err, "spanner.NewClient(): %s", dbID)
+	}
+
+	return client, nil
+}
+
+func (c *config) close() {
+	if err := c.admin.Close(); err != nil {
+		log.Printf("failed to close admin: %v", err)
+	}
+}
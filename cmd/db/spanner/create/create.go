@@ -0,0 +1,152 @@
+package create
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/spannercopy"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	template   string
+	withData   bool
+	skipTables []string
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create the database, optionally from a template database",
+		Long: `Create the database named by GOOGLE_CLOUD_SPANNER_DATABASE_NAME. With --from-template the new database gets the schema of
+the template database in the same instance, along with its schema migration versions, so bootstrap only applies
+migrations newer than the template. This is much faster than replaying every migration for each feature environment.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&c.template, "from-template", "", "ID of the template database in the same instance to copy the schema from")
+	cmd.Flags().
+		BoolVar(&c.withData, "with-data", false, "Also copy every row of the template, including the data migration version and history, so data migrations are not replayed either")
+	cmd.Flags().StringSliceVar(&c.skipTables, "skip-table", nil, "Tables whose rows are not copied with --with-data. Multiple tables should be comma-separated.")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(_ *cobra.Command) error {
+	if c.template == "" && (c.withData || len(c.skipTables) > 0) {
+		return errors.New("--with-data and --skip-table require --from-template")
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	interval, err := heartbeat.Interval(cmd)
+	if err != nil {
+		return err
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	if c.template == conf.databaseName {
+		return errors.Newf("the template database can not be the database being created: %s", c.template)
+	}
+
+	templateStr := ""
+	if c.template != "" {
+		templateStr = conf.databaseStr(c.template)
+		log.Printf("Creating database %s from template %s\n", conf.databaseName, c.template)
+	} else {
+		log.Printf("Creating database %s\n", conf.databaseName)
+	}
+
+	start := time.Now()
+	stop := heartbeat.Start(ctx, interval, "create database", "database", conf.databaseStr(conf.databaseName))
+	err = spannercopy.CreateDatabase(ctx, conf.admin, conf.instanceStr, conf.databaseName, templateStr)
+	stop()
+	if err != nil {
+		return errors.Wrap(err, "spannercopy.CreateDatabase()")
+	}
+	log.Printf("Database created in %s\n", time.Since(start).Round(time.Second))
+
+	if c.template == "" {
+		return nil
+	}
+
+	return c.copyRows(ctx, conf, interval)
+}
+
+// copyRows copies the schema migration versions, and with --with-data every other row, from the template
+func (c *command) copyRows(ctx context.Context, conf *config, interval time.Duration) error {
+	src, err := conf.connect(ctx, c.template)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := conf.connect(ctx, conf.databaseName)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	tables, err := spannercopy.Tables(ctx, src)
+	if err != nil {
+		return errors.Wrap(err, "spannercopy.Tables()")
+	}
+	tables = slices.DeleteFunc(tables, func(t spannercopy.Table) bool { return !c.copied(t.Name) })
+
+	start := time.Now()
+	stop := heartbeat.Start(ctx, interval, "copy rows", "template", c.template, "tables", len(tables))
+	rows, err := spannercopy.CopyRows(ctx, src, dst, tables)
+	stop()
+	if err != nil {
+		return errors.Wrap(err, "spannercopy.CopyRows()")
+	}
+	log.Printf("Copied %d row(s) from %d table(s) in %s\n", rows, len(tables), time.Since(start).Round(time.Second))
+
+	return nil
+}
+
+// copied reports whether the rows of table are copied from the template
+func (c *command) copied(table string) bool {
+	switch {
+	case table == spannermigrate.LockTable, slices.Contains(c.skipTables, table):
+		return false
+	case table == spannermigrate.SchemaMigrationsTable, strings.HasPrefix(table, spannermigrate.SchemaMigrationsTable+"_"):
+		return true
+	default:
+		return c.withData
+	}
+}
@@ -0,0 +1,42 @@
+package dataexport
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+	"google.golang.org/api/option"
+)
+
+type envConfig struct {
+	SpannerProjectID    string `env:"GOOGLE_CLOUD_SPANNER_PROJECT"`
+	SpannerInstanceID   string `env:"GOOGLE_CLOUD_SPANNER_INSTANCE_ID"`
+	SpannerDatabaseName string `env:"GOOGLE_CLOUD_SPANNER_DATABASE_NAME"`
+}
+
+type config struct {
+	client *spanner.Client
+}
+
+func newConfig(ctx context.Context) (*config, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return nil, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	dbStr := fmt.Sprintf("projects/%s/instances/%s/databases/%s", envVars.SpannerProjectID, envVars.SpannerInstanceID, envVars.SpannerDatabaseName)
+	client, err := spanner.NewClient(ctx, dbStr, option.WithTelemetryDisabled())
+	if err != nil {
+		return nil, errors.Wrap(err, "spanner.NewClient()")
+	}
+
+	return &config{
+		client: client,
+	}, nil
+}
+
+func (c *config) close() {
+	c.client.Close()
+}
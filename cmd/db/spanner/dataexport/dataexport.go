@@ -0,0 +1,169 @@
+package dataexport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/gcs"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/spannercopy"
+	"github.com/cccteam/deployment-tools/internal/spannercsv"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	to        string
+	tables    []string
+	nullValue string
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export table rows to CSV files in a local directory or Cloud Storage",
+		Long: `Export the rows of small and medium sized tables to CSV files, so a data set can be captured from one database
+and shared with 'db spanner import' or 'db spanner seed'. All tables are read at the same timestamp in a single read-only
+transaction, so the export is consistent, and each table is held in memory while it is written.
+
+Files are named <NNN>_<Table>.csv, numbered so every table follows its interleave parent and the tables its foreign keys
+reference. The migrations and deployment lock tables are never exported. ARRAY, STRUCT and PROTO columns are not supported.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&c.to, "to", "", "Destination directory, using the file URI syntax, or Cloud Storage location (gs://<bucket>/<prefix>)")
+	cmd.Flags().StringSliceVar(&c.tables, "table", nil, "Tables to export. Multiple tables should be comma-separated. The default exports every table.")
+	cmd.Flags().StringVar(&c.nullValue, "null-value", "", "CSV field value written for NULL. The default writes NULL as an empty field, so tables with empty strings can not be exported; use e.g. --null-value '\\N'")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(_ *cobra.Command) error {
+	if c.to == "" {
+		return errors.New("--to is required")
+	}
+	if !strings.HasPrefix(c.to, "file://") && !strings.HasPrefix(c.to, "gs://") {
+		return errors.Newf("--to must start with file:// or gs://, got %q", c.to)
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	interval, err := heartbeat.Interval(cmd)
+	if err != nil {
+		return err
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	tables, err := spannercopy.Tables(ctx, conf.client)
+	if err != nil {
+		return errors.Wrap(err, "spannercopy.Tables()")
+	}
+	if err := c.selectTables(&tables); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	stop := heartbeat.Start(ctx, interval, "export", "to", c.to, "tables", len(tables))
+	defer stop()
+
+	txn := conf.client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	var total int64
+	for i, t := range tables {
+		var buf bytes.Buffer
+		rows, err := spannercsv.Export(ctx, txn, t.Name, t.Columns, c.nullValue, &buf)
+		if err != nil {
+			return errors.Wrapf(err, "spannercsv.Export(): %s", t.Name)
+		}
+
+		name := fmt.Sprintf("%03d_%s.csv", i+1, t.Name)
+		dest, err := c.write(ctx, name, buf.Bytes())
+		if err != nil {
+			return err
+		}
+		log.Printf("Exported %d row(s) of %s to %s\n", rows, t.Name, dest)
+		total += rows
+	}
+
+	log.Printf("Exported %d row(s) from %d table(s) in %s\n", total, len(tables), time.Since(start).Round(time.Second))
+
+	return nil
+}
+
+// selectTables removes the tables that are not exported, keeping the dependency order
+func (c *command) selectTables(tables *[]spannercopy.Table) error {
+	for _, name := range c.tables {
+		switch {
+		case spannermigrate.IsDeploymentTable(name):
+			return errors.Newf("--table %s is a deployment table and can not be exported", name)
+		case !slices.ContainsFunc(*tables, func(t spannercopy.Table) bool { return t.Name == name }):
+			return errors.Newf("--table %s does not exist", name)
+		}
+	}
+
+	*tables = slices.DeleteFunc(*tables, func(t spannercopy.Table) bool {
+		return spannermigrate.IsDeploymentTable(t.Name) || (len(c.tables) > 0 && !slices.Contains(c.tables, t.Name))
+	})
+
+	return nil
+}
+
+// write stores a file at the destination and returns its location
+func (c *command) write(ctx context.Context, name string, data []byte) (string, error) {
+	if strings.HasPrefix(c.to, "gs://") {
+		uri, err := gcs.Upload(ctx, c.to, name, "text/csv", data)
+		if err != nil {
+			return "", errors.Wrap(err, "gcs.Upload()")
+		}
+
+		return uri, nil
+	}
+
+	dir := strings.TrimPrefix(c.to, "file://")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", errors.Wrap(err, "os.MkdirAll()")
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec // exports are shared between developers
+		return "", errors.Wrap(err, "os.WriteFile()")
+	}
+
+	return path, nil
+}
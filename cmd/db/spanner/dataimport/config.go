@@ -0,0 +1,49 @@
+package dataimport
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cccteam/deployment-tools/internal/spannertag"
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+	"google.golang.org/api/option"
+)
+
+type envConfig struct {
+	SpannerProjectID    string `env:"GOOGLE_CLOUD_SPANNER_PROJECT"`
+	SpannerInstanceID   string `env:"GOOGLE_CLOUD_SPANNER_INSTANCE_ID"`
+	SpannerDatabaseName string `env:"GOOGLE_CLOUD_SPANNER_DATABASE_NAME"`
+	AppEnv              string `env:"_APP_ENV"`
+}
+
+type config struct {
+	client *spanner.Client
+	appEnv string
+}
+
+func newConfig(ctx context.Context) (*config, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return nil, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	dbStr := fmt.Sprintf("projects/%s/instances/%s/databases/%s", envVars.SpannerProjectID, envVars.SpannerInstanceID, envVars.SpannerDatabaseName)
+	client, err := spanner.NewClientWithConfig(ctx, dbStr, spanner.ClientConfig{
+		SessionPoolConfig: spanner.DefaultSessionPoolConfig,
+		SessionLabels:     spannertag.SessionLabels("import"),
+	}, option.WithTelemetryDisabled())
+	if err != nil {
+		return nil, errors.Wrap(err, "spanner.NewClientWithConfig()")
+	}
+
+	return &config{
+		client: client,
+		appEnv: envVars.AppEnv,
+	}, nil
+}
+
+func (c *config) close() {
+	c.client.Close()
+}
@@ -0,0 +1,169 @@
+package dataimport
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/appenv"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/gcs"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/spannercsv"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	from      string
+	groupSize int
+	nullValue string
+	confirm   bool
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import table rows from CSV files in a local directory or Cloud Storage",
+		Long: `Import the CSV files written by 'db spanner export' into a database that already has the schema, for example one
+created with 'db spanner create' or bootstrapped from the same migrations. Files are loaded in name order with BatchWrite
+using insert-or-update mutations, the same way 'db spanner seed' loads CSV files, so existing rows with the same key are
+overwritten. Importing into a production environment (_APP_ENV of prd, prod or production) requires --confirm.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&c.from, "from", "", "Source directory, using the file URI syntax, or Cloud Storage location (gs://<bucket>/<prefix>)")
+	cmd.Flags().
+		IntVar(&c.groupSize, "group-size", 500, "Number of CSV rows written atomically in each BatchWrite mutation group. Rows multiplied by columns must not exceed 80,000 mutations.")
+	cmd.Flags().StringVar(&c.nullValue, "null-value", "", "CSV field value written as NULL. Must match the --null-value of the export.")
+	cmd.Flags().BoolVar(&c.confirm, "confirm", false, "Confirm importing into a production environment")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(_ *cobra.Command) error {
+	if c.from == "" {
+		return errors.New("--from is required")
+	}
+	if !strings.HasPrefix(c.from, "file://") && !strings.HasPrefix(c.from, "gs://") {
+		return errors.Newf("--from must start with file:// or gs://, got %q", c.from)
+	}
+	if c.groupSize < 1 {
+		return errors.Newf("--group-size must be greater than 0, got %d", c.groupSize)
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	interval, err := heartbeat.Interval(cmd)
+	if err != nil {
+		return err
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	if appenv.IsProduction(conf.appEnv) && !c.confirm {
+		return deployerr.New(deployerr.Policy, "confirm_required", errors.Newf("refusing to import into production environment %q without --confirm", conf.appEnv))
+	}
+
+	dir, cleanup, err := c.localDir(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.csv"))
+	if err != nil {
+		return errors.Wrap(err, "filepath.Glob()")
+	}
+	if len(paths) == 0 {
+		return errors.Newf("no CSV files found in %s", c.from)
+	}
+	slices.Sort(paths)
+
+	start := time.Now()
+	for _, path := range paths {
+		stop := heartbeat.Start(ctx, interval, "import", "file", filepath.Base(path))
+		err := spannercsv.Load(ctx, conf.client, path, c.groupSize, c.nullValue)
+		stop()
+		if err != nil {
+			return errors.Wrapf(err, "spannercsv.Load(): %s", filepath.Base(path))
+		}
+	}
+
+	log.Printf("Imported %d file(s) in %s\n", len(paths), time.Since(start).Round(time.Second))
+
+	return nil
+}
+
+// localDir returns a local directory containing the CSV files to import, downloading them first
+// when --from is a Cloud Storage location, and a func that removes any downloaded files
+func (c *command) localDir(ctx context.Context) (dir string, cleanup func(), err error) {
+	if !strings.HasPrefix(c.from, "gs://") {
+		return strings.TrimPrefix(c.from, "file://"), func() {}, nil
+	}
+
+	names, err := gcs.List(ctx, c.from)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "gcs.List()")
+	}
+
+	dir, err = os.MkdirTemp("", "import-")
+	if err != nil {
+		return "", nil, errors.Wrap(err, "os.MkdirTemp()")
+	}
+	cleanup = func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("failed to remove %s: %v", dir, err)
+		}
+	}
+
+	for _, name := range names {
+		if filepath.Ext(name) != ".csv" {
+			continue
+		}
+
+		data, err := gcs.Download(ctx, c.from, name)
+		if err != nil {
+			cleanup()
+
+			return "", nil, errors.Wrap(err, "gcs.Download()")
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			cleanup()
+
+			return "", nil, errors.Wrap(err, "os.WriteFile()")
+		}
+	}
+
+	return dir, cleanup, nil
+}
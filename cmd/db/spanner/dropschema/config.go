@@ -4,7 +4,7 @@ import (
 	"context"
 	"log"
 
-	dbinitiator "github.com/cccteam/db-initiator"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
 	"github.com/go-playground/errors/v5"
 	"github.com/sethvargo/go-envconfig"
 	"google.golang.org/api/option"
@@ -17,7 +17,7 @@ type envConfig struct {
 }
 
 type config struct {
-	migrateClient *dbinitiator.SpannerMigrator
+	migrateClient *spannermigrate.Client
 }
 
 func newConfig(ctx context.Context) (*config, error) {
@@ -26,9 +26,15 @@ func newConfig(ctx context.Context) (*config, error) {
 		return nil, errors.Wrap(err, "envconfig.Process()")
 	}
 
-	db, err := dbinitiator.NewSpannerMigrator(ctx, envVars.SpannerProjectID, envVars.SpannerInstanceID, envVars.SpannerDatabaseName, option.WithTelemetryDisabled())
+	db, err := spannermigrate.Connect(
+		ctx,
+		envVars.SpannerProjectID,
+		envVars.SpannerInstanceID,
+		envVars.SpannerDatabaseName,
+		option.WithTelemetryDisabled(),
+	)
 	if err != nil {
-		return nil, errors.Wrapf(err, "dbinitiator.NewSpannerMigrator()")
+		return nil, errors.Wrapf(err, "spannermigrate.Connect()")
 	}
 
 	return &config{
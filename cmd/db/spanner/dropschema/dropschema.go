@@ -3,9 +3,10 @@ package dropschema
 import (
 	"context"
 	"log"
-	"os"
-	"strings"
 
+	"github.com/cccteam/deployment-tools/internal/appenv"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
 	"github.com/go-playground/errors/v5"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/source/file" // up/down script file source driver for the migrate package
@@ -31,7 +32,7 @@ func (c *command) Setup(ctx context.Context) *cobra.Command {
 		Long:  "Drop all database tables",
 		RunE: func(cmd *cobra.Command, _ []string) (err error) {
 			if err := c.ValidateFlags(cmd); err != nil {
-				return err
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
 			}
 
 			if err := c.Run(ctx, cmd); err != nil {
@@ -53,6 +54,11 @@ func (c *command) ValidateFlags(cmd *cobra.Command) error {
 
 // Run executes the command
 func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	interval, err := heartbeat.Interval(cmd)
+	if err != nil {
+		return err
+	}
+
 	conf, err := newConfig(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to initialize config")
@@ -60,24 +66,15 @@ func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
 	defer conf.close()
 
 	// verify _APP_ENV is set and matches one of the allowed environments
-	appEnv, ok := os.LookupEnv("_APP_ENV")
-	if !ok {
-		return errors.New("_APP_ENV environment variable is not set. This will not run if it is not set")
-	}
-	allowedEnvsStr, ok := os.LookupEnv("_DB_DROP_ENV_WHITELIST")
-	if !ok {
-		return errors.New("_DB_DROP_ENV_WHITELIST environment variable is not set. This will not run if it is not set")
-	}
-	allowedEnvs := make(map[string]bool)
-	for env := range strings.SplitSeq(allowedEnvsStr, ",") {
-		allowedEnvs[strings.TrimSpace(env)] = true
-	}
-	if !allowedEnvs[appEnv] {
-		return errors.Newf("dropping schema is only allowed in allowed environments (%s), current environment: %s", allowedEnvsStr, appEnv)
+	if err := appenv.CheckDropAllowed(); err != nil {
+		return err
 	}
 
 	log.Println("Dropping schema tables...")
 
+	stop := heartbeat.Start(ctx, interval, "drop schema")
+	defer stop()
+
 	if err := conf.migrateClient.MigrateDropSchema(ctx); err != nil &&
 		!errors.Is(err, migrate.ErrNoChange) {
 		return errors.Wrap(err, "failed to drop schema")
@@ -0,0 +1,157 @@
+package generate
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/appenv"
+	"github.com/cccteam/deployment-tools/internal/datagen"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/spannercopy"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	specFile string
+	rows     string
+	seed     uint64
+	spec     *datagen.Spec
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate synthetic rows that honor the live schema",
+		Long: `Generate synthetic data for load testing a feature environment instead of copying production data. The schema is
+read from the database: every table is filled after its interleave parent and the tables its foreign keys reference,
+and interleaved and foreign key columns take the values of rows generated for the referenced table. Primary key and
+UNIQUE index columns are derived from the row number. When a key is made up entirely of interleaved or foreign key
+columns, as in join tables, every row takes a distinct combination of referenced rows, and the row count is capped at
+the number of combinations. Other columns get random values of their type unless the spec overrides them:
+
+  rows: 10k
+  tables:
+    Users:
+      rows: 500
+      columns:
+        Email:
+          format: user%d@example.com
+        Status:
+          values: [active, suspended]
+    AuditLog:
+      rows: 0
+
+Rows are inserted, so the tables should be empty. The migrations and deployment lock tables are never filled, and
+the command will not run when _APP_ENV is prd, prod or production.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&c.specFile, "spec", "", "YAML file with row counts and column values per table. Empty generates --rows rows for every table.")
+	cmd.Flags().StringVar(&c.rows, "rows", "1k", "Number of rows generated for tables without their own count, e.g. 500, 100k or 2m. Overrides rows in the spec.")
+	cmd.Flags().Uint64Var(&c.seed, "seed", 1, "Seed for the random values. The same seed, spec and schema generate the same rows.")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(cmd *cobra.Command) error {
+	c.spec = &datagen.Spec{}
+	if c.specFile != "" {
+		spec, err := datagen.LoadSpec(c.specFile)
+		if err != nil {
+			return errors.Wrap(err, "datagen.LoadSpec()")
+		}
+		c.spec = spec
+	}
+
+	if c.specFile == "" || cmd.Flags().Changed("rows") {
+		rows, err := datagen.ParseCount(c.rows)
+		if err != nil {
+			return errors.Wrap(err, "--rows")
+		}
+		c.spec.Rows = rows
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	interval, err := heartbeat.Interval(cmd)
+	if err != nil {
+		return err
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	if appenv.IsProduction(conf.appEnv) {
+		return deployerr.New(deployerr.Policy, "production_refused", errors.Newf("refusing to generate data in production environment %q", conf.appEnv))
+	}
+
+	ordered, err := spannercopy.Tables(ctx, conf.client)
+	if err != nil {
+		return errors.Wrap(err, "spannercopy.Tables()")
+	}
+	ordered = slices.DeleteFunc(ordered, func(t spannercopy.Table) bool { return spannermigrate.IsDeploymentTable(t.Name) })
+
+	tables, err := datagen.Describe(ctx, conf.client, ordered)
+	if err != nil {
+		return errors.Wrap(err, "datagen.Describe()")
+	}
+
+	start := time.Now()
+	gen := datagen.New(c.spec, tables, c.seed)
+	var total int64
+	for _, t := range tables {
+		count, err := gen.Rows(t)
+		if err != nil {
+			return errors.Wrap(err, "datagen.Generator.Rows()")
+		}
+		if count == 0 {
+			continue
+		}
+		if spec := c.spec.TableRows(t.Name); count < spec {
+			log.Printf("Generating %d row(s) in %s instead of %d, the number of distinct combinations of the rows its key references\n", count, t.Name, spec)
+		}
+
+		stop := heartbeat.Start(ctx, interval, "generate", "table", t.Name, "rows", count)
+		rows, err := gen.Generate(ctx, conf.client, t)
+		stop()
+		if err != nil {
+			return errors.Wrapf(err, "datagen.Generator.Generate(): %s", t.Name)
+		}
+		log.Printf("Generated %d row(s) in %s\n", rows, t.Name)
+		total += rows
+	}
+
+	log.Printf("Generated %d row(s) in %s\n", total, time.Since(start).Round(time.Second))
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package grant
+
+import (
+	"context"
+	"log"
+
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/spanneriam"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	bindingsFile string
+	prune        bool
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grant",
+		Short: "Apply database-level IAM bindings",
+		Long: `Apply a declarative set of database-level IAM bindings, such as application service accounts and read-only analysts.
+
+The bindings file is JSON of the form:
+  {"bindings": [{"role": "roles/spanner.databaseUser", "members": ["serviceAccount:app@${GOOGLE_CLOUD_SPANNER_PROJECT}.iam.gserviceaccount.com"]}]}
+
+Environment variables in the file are expanded. Members are added to the listed roles; other roles are left unchanged.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&c.bindingsFile, "bindings", "bootstrap/iam.json", "Path to the JSON file of database IAM bindings")
+	cmd.Flags().BoolVar(&c.prune, "prune", false, "Remove members of the listed roles that are not in the bindings file")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(_ *cobra.Command) error {
+	if c.bindingsFile == "" {
+		return errors.New("--bindings is required")
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, _ *cobra.Command) error {
+	bindings, err := spanneriam.Load(c.bindingsFile)
+	if err != nil {
+		return errors.Wrap(err, "spanneriam.Load()")
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	changes, err := conf.migrateClient.GrantIAM(ctx, bindings, c.prune)
+	if err != nil {
+		return errors.Wrap(err, "spannermigrate.Client.GrantIAM()")
+	}
+
+	if len(changes) == 0 {
+		log.Println("Database IAM bindings are up to date. No changes applied.")
+	} else {
+		log.Printf("Applied %d database IAM binding change(s)\n", len(changes))
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	limit int64
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show applied data migrations",
+		Long:  "Show the data migrations that have been applied to the database, newest first, including the checksum of each file, how long it took and the environment it ran in",
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().Int64VarP(&c.limit, "limit", "n", 50, "Maximum number of migrations to show")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(cmd *cobra.Command) error {
+	if c.limit < 1 {
+		return errors.Newf("--limit must be greater than 0, got %d", c.limit)
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	records, err := conf.migrateClient.DataMigrationHistory(ctx, c.limit)
+	if err != nil {
+		return errors.Wrap(err, "spannermigrate.Client.DataMigrationHistory()")
+	}
+
+	if len(records) == 0 {
+		log.Println("No data migration history found")
+
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tCHECKSUM\tDURATION\tENVIRONMENT\tAPPLIED AT")
+	for _, r := range records {
+		fmt.Fprintf(w, "%d\t%s\t%.12s\t%s\t%s\t%s\n", r.Version, r.Name, r.Checksum, r.Duration, r.Environment, r.AppliedAt.Format(time.RFC3339))
+	}
+
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "tabwriter.Writer.Flush()")
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package create
+
+import (
+	"context"
+	"log"
+
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+	"google.golang.org/api/option"
+)
+
+type envConfig struct {
+	SpannerProjectID  string `env:"GOOGLE_CLOUD_SPANNER_PROJECT"`
+	SpannerInstanceID string `env:"GOOGLE_CLOUD_SPANNER_INSTANCE_ID"`
+}
+
+type config struct {
+	projectID     string
+	instanceID    string
+	instanceAdmin *instance.InstanceAdminClient
+}
+
+func newConfig(ctx context.Context) (*config, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return nil, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	instanceAdmin, err := instance.NewInstanceAdminClient(ctx, option.WithTelemetryDisabled())
+	if err != nil {
+		return nil, errors.Wrap(err, "instance.NewInstanceAdminClient()")
+	}
+
+	return &config{
+		projectID:     envVars.SpannerProjectID,
+		instanceID:    envVars.SpannerInstanceID,
+		instanceAdmin: instanceAdmin,
+	}, nil
+}
+
+func (c *config) close() {
+	if err := c.instanceAdmin.Close(); err != nil {
+		log.Printf("failed to close instanceAdmin: %v", err)
+	}
+}
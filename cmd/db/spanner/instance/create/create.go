@@ -0,0 +1,114 @@
+package create
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/spannerinstance"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	spec spannerinstance.Spec
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create the spanner instance",
+		Long: `Create the spanner instance with a fixed number of processing units or autoscaling limits, for brand-new projects and
+ephemeral load-test environments. The instance can be described with flags or a --spec-file:
+
+  config: regional-us-central1
+  displayName: Load test
+  autoscaling:
+    minProcessingUnits: 1000
+    maxProcessingUnits: 5000`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&c.spec.Config, "config", "", "Instance configuration, e.g. regional-us-central1")
+	c.spec.AddFlags(cmd)
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(cmd *cobra.Command) error {
+	if err := c.spec.LoadFile(cmd); err != nil {
+		return errors.Wrap(err, "spannerinstance.Spec.LoadFile()")
+	}
+	if c.spec.Config == "" {
+		return errors.New("--config or config in --spec-file is required")
+	}
+	if !c.spec.HasCapacity() {
+		return errors.New("either processing units or autoscaling limits are required")
+	}
+
+	return c.spec.Validate(cmd)
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	interval, err := heartbeat.Interval(cmd)
+	if err != nil {
+		return err
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	inst := &instancepb.Instance{
+		Name:        fmt.Sprintf("projects/%s/instances/%s", conf.projectID, conf.instanceID),
+		Config:      fmt.Sprintf("projects/%s/instanceConfigs/%s", conf.projectID, c.spec.Config),
+		DisplayName: conf.instanceID,
+	}
+	c.spec.Apply(inst)
+
+	log.Printf("Creating instance %s with %s\n", inst.GetName(), spannerinstance.DescribeCapacity(inst))
+
+	op, err := conf.instanceAdmin.CreateInstance(ctx, &instancepb.CreateInstanceRequest{
+		Parent:     fmt.Sprintf("projects/%s", conf.projectID),
+		InstanceId: conf.instanceID,
+		Instance:   inst,
+	})
+	if err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "instance.InstanceAdminClient.CreateInstance()")
+	}
+
+	stop := heartbeat.Start(ctx, interval, "create instance", "instance", inst.GetName())
+	defer stop()
+
+	if _, err := op.Wait(ctx); err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "instance.CreateInstanceOperation.Wait()")
+	}
+
+	log.Println("Instance created successfully")
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package instance
+
+import (
+	"context"
+
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/instance/create"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/instance/update"
+	"github.com/spf13/cobra"
+)
+
+type command struct{}
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+func (command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "instance",
+		Short: "Commands for provisioning spanner instances",
+		Long:  "Commands for provisioning spanner instances, such as creating an instance for a new project and resizing its compute capacity",
+	}
+
+	cmd.AddCommand(create.Command(ctx))
+	cmd.AddCommand(update.Command(ctx))
+
+	return cmd
+}
@@ -0,0 +1,123 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	"github.com/cccteam/deployment-tools/internal/appenv"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/spannerinstance"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	spec    spannerinstance.Spec
+	confirm bool
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "Update the spanner instance compute capacity",
+		Long: `Update the display name and compute capacity (processing units or autoscaling limits) of the spanner instance, from
+flags or a --spec-file as read by create. The instance configuration can not be changed. Requires _APP_ENV to be set,
+and resizing a production instance requires --confirm.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+	c.spec.AddFlags(cmd)
+	cmd.Flags().BoolVar(&c.confirm, "confirm", false, "Confirm resizing an instance in a production environment (_APP_ENV of prd, prod or production)")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(cmd *cobra.Command) error {
+	if err := c.spec.LoadFile(cmd); err != nil {
+		return errors.Wrap(err, "spannerinstance.Spec.LoadFile()")
+	}
+	if c.spec.Config != "" {
+		return errors.New("the instance configuration can not be updated, remove config from --spec-file")
+	}
+	if !c.spec.HasCapacity() && c.spec.DisplayName == "" {
+		return errors.New("nothing to update, specify --display-name, --processing-units or autoscaling limits")
+	}
+
+	return c.spec.Validate(cmd)
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	interval, err := heartbeat.Interval(cmd)
+	if err != nil {
+		return err
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	if conf.appEnv == "" {
+		return errors.New("_APP_ENV environment variable is not set. This will not run if it is not set")
+	}
+
+	name := fmt.Sprintf("projects/%s/instances/%s", conf.projectID, conf.instanceID)
+	inst, err := conf.instanceAdmin.GetInstance(ctx, &instancepb.GetInstanceRequest{Name: name})
+	if err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "instance.InstanceAdminClient.GetInstance()")
+	}
+
+	current := spannerinstance.DescribeCapacity(inst)
+	paths := c.spec.Apply(inst)
+	desired := spannerinstance.DescribeCapacity(inst)
+
+	if c.spec.HasCapacity() && current != desired {
+		log.Printf("Resizing instance %s from %s to %s\n", name, current, desired)
+		if appenv.IsProduction(conf.appEnv) && !c.confirm {
+			return deployerr.New(deployerr.Policy, "confirm_required", errors.Newf("refusing to resize instance in production environment %q without --confirm", conf.appEnv))
+		}
+	}
+
+	op, err := conf.instanceAdmin.UpdateInstance(ctx, &instancepb.UpdateInstanceRequest{
+		Instance:  inst,
+		FieldMask: &fieldmaskpb.FieldMask{Paths: paths},
+	})
+	if err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "instance.InstanceAdminClient.UpdateInstance()")
+	}
+
+	stop := heartbeat.Start(ctx, interval, "update instance", "instance", name)
+	defer stop()
+
+	if _, err := op.Wait(ctx); err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "instance.UpdateInstanceOperation.Wait()")
+	}
+
+	log.Println("Instance updated successfully")
+
+	return nil
+}
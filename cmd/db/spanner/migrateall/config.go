@@ -0,0 +1,65 @@
+package migrateall
+
+import (
+	"context"
+
+	"github.com/cccteam/deployment-tools/internal/dbplan"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+	"google.golang.org/api/option"
+)
+
+type envConfig struct {
+	SpannerProjectID  string `env:"GOOGLE_CLOUD_SPANNER_PROJECT"`
+	SpannerInstanceID string `env:"GOOGLE_CLOUD_SPANNER_INSTANCE_ID"`
+	AppEnv            string `env:"_APP_ENV"`
+}
+
+type config struct {
+	projectID  string
+	instanceID string
+	appEnv     string
+}
+
+func newConfig(ctx context.Context) (*config, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return nil, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	return &config{
+		projectID:  envVars.SpannerProjectID,
+		instanceID: envVars.SpannerInstanceID,
+		appEnv:     envVars.AppEnv,
+	}, nil
+}
+
+// instance returns the instance of db, defaulting to the configured instance
+func (c *config) instance(db dbplan.Database) string {
+	if db.Instance != "" {
+		return db.Instance
+	}
+
+	return c.instanceID
+}
+
+// connect returns a migrate client for db
+func (c *config) connect(ctx context.Context, db dbplan.Database) (*spannermigrate.Client, error) {
+	client, err := spannermigrate.Connect(ctx, c.projectID, c.instance(db), db.Database, option.WithTelemetryDisabled())
+	if err != nil {
+		return nil, errors.Wrapf(err, "spannermigrate.Connect(): %s", db.Name)
+	}
+
+	return client.WithEnvironment(c.appEnv), nil
+}
+
+// connectReadOnly returns a read-only client for db
+func (c *config) connectReadOnly(ctx context.Context, db dbplan.Database) (*spannermigrate.ReadOnlyClient, error) {
+	client, err := spannermigrate.ConnectReadOnly(ctx, c.projectID, c.instance(db), db.Database, "", option.WithTelemetryDisabled())
+	if err != nil {
+		return nil, errors.Wrapf(err, "spannermigrate.ConnectReadOnly(): %s", db.Name)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,260 @@
+package migrateall
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/dbplan"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
+	"github.com/go-playground/errors/v5"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	planFile          string
+	planOnly          bool
+	statementTimeout  time.Duration
+	mutationLimit     int64
+	partitionLargeDML bool
+	lockTTL           time.Duration
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate-all",
+		Short: "Run the migrations of several databases in dependency order",
+		Long: `Run the schema and data migrations of every database listed in a databases file. A database is migrated after the
+databases it depends on, e.g. a reporting database after the primary database it reads from. The combined plan, with the
+pending migrations of each database, is printed first. Migration stops at the first database that fails.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&c.planFile, "databases", "databases.yaml", "Path to the YAML file listing the databases, their migration directories and dependencies")
+	cmd.Flags().BoolVar(&c.planOnly, "plan", false, "Print the combined plan without applying any migrations")
+	cmd.Flags().DurationVar(&c.statementTimeout, "statement-timeout", 0, "Maximum time each statement of a DML-only data migration may run, e.g. 10m. Zero disables the timeout.")
+	cmd.Flags().
+		Int64Var(&c.mutationLimit, "mutation-limit", spannermigrate.DefaultMutationLimit, "Reject pending data migrations estimated to exceed this many mutations in a single transaction. Zero disables the check.")
+	cmd.Flags().
+		BoolVar(&c.partitionLargeDML, "partition-large-dml", false, "Execute UPDATE and DELETE statements that exceed the mutation limit as partitioned DML instead of rejecting them.")
+	cmd.Flags().DurationVar(&c.lockTTL, "lock-ttl", spannermigrate.DefaultLockTTL, "How long the migration lock of a database is held if migrate-all dies without releasing it")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(_ *cobra.Command) error {
+	if c.statementTimeout < 0 {
+		return errors.Newf("--statement-timeout must not be negative, got %s", c.statementTimeout)
+	}
+	if c.mutationLimit < 0 {
+		return errors.Newf("--mutation-limit must not be negative, got %d", c.mutationLimit)
+	}
+	if c.lockTTL <= 0 {
+		return errors.Newf("--lock-ttl must be greater than 0, got %s", c.lockTTL)
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	interval, err := heartbeat.Interval(cmd)
+	if err != nil {
+		return err
+	}
+
+	plan, err := dbplan.Load(c.planFile)
+	if err != nil {
+		return errors.Wrap(err, "dbplan.Load()")
+	}
+
+	ordered, err := plan.Order()
+	if err != nil {
+		return errors.Wrap(err, "dbplan.Plan.Order()")
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+
+	if err := c.writePlan(ctx, cmd.OutOrStdout(), conf, ordered); err != nil {
+		return err
+	}
+
+	if c.planOnly {
+		return nil
+	}
+
+	for _, db := range ordered {
+		log.Printf("Migrating database %s (%s)\n", db.Name, db.Database)
+		if err := c.migrate(ctx, conf, db, interval); err != nil {
+			return errors.Wrapf(err, "database %s failed, databases after it in the plan were not migrated", db.Name)
+		}
+	}
+
+	log.Printf("Migrated %d database(s)\n", len(ordered))
+
+	return nil
+}
+
+// migrate applies the schema and data migrations of db
+func (c *command) migrate(ctx context.Context, conf *config, db dbplan.Database, interval time.Duration) error {
+	client, err := conf.connect(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			log.Printf("failed to close migrateClient: %v", err)
+		}
+	}()
+
+	holder := spannermigrate.LockHolder("migrate-all", "")
+	if err := client.AcquireLock(ctx, spannermigrate.MigrationLock, holder, c.lockTTL); err != nil {
+		return errors.Wrap(err, "spannermigrate.Client.AcquireLock()")
+	}
+	defer func() {
+		if err := client.ReleaseLock(context.WithoutCancel(ctx), spannermigrate.MigrationLock, holder); err != nil {
+			log.Printf("failed to release migration lock: %v", err)
+		}
+	}()
+
+	client.
+		WithHeartbeat(interval).
+		WithStatementTimeout(c.statementTimeout).
+		WithMutationLimit(c.mutationLimit).
+		WithPartitionLargeDML(c.partitionLargeDML)
+
+	if len(db.SchemaDirs) > 0 {
+		if err := client.MigrateUpSchemaDirs(ctx, db.SchemaDirs); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return errors.Wrap(err, "spannermigrate.Client.MigrateUpSchemaDirs()")
+		}
+	}
+
+	if len(db.DataDirs) > 0 {
+		if err := client.MigrateUpDataDirs(ctx, db.DataDirs); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return errors.Wrap(err, "spannermigrate.Client.MigrateUpDataDirs()")
+		}
+	}
+
+	return nil
+}
+
+// writePlan writes the databases in migration order with the number of pending migrations of each
+func (c *command) writePlan(ctx context.Context, w io.Writer, conf *config, ordered []dbplan.Database) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STEP\tNAME\tDATABASE\tDEPENDS ON\tPENDING SCHEMA\tPENDING DATA")
+	for i, db := range ordered {
+		schema, data, err := pending(ctx, conf, db)
+		if err != nil {
+			return err
+		}
+		dependsOn := strings.Join(db.DependsOn, ", ")
+		if dependsOn == "" {
+			dependsOn = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s/%s\t%s\t%s\t%s\n", i+1, db.Name, conf.instance(db), db.Database, dependsOn, schema, data)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return errors.Wrap(err, "tabwriter.Writer.Flush()")
+	}
+
+	return nil
+}
+
+// pending describes the pending schema and data migrations of db
+func pending(ctx context.Context, conf *config, db dbplan.Database) (schema, data string, err error) {
+	client, err := conf.connectReadOnly(ctx, db)
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			log.Printf("failed to close client: %v", err)
+		}
+	}()
+
+	sets, err := spannermigrate.ParseSchemaDirs(db.SchemaDirs)
+	if err != nil {
+		return "", "", errors.Wrap(err, "spannermigrate.ParseSchemaDirs()")
+	}
+
+	var schemaPending []string
+	for _, set := range sets {
+		n, err := countPending(ctx, func(ctx context.Context) (spannermigrate.MigrationState, error) {
+			return client.SchemaState(ctx, set.Name)
+		}, set.Dirs)
+		if err != nil {
+			return "", "", err
+		}
+		if set.Name != "" {
+			n = set.Name + ": " + n
+		}
+		schemaPending = append(schemaPending, n)
+	}
+	schema = "-"
+	if len(schemaPending) > 0 {
+		schema = strings.Join(schemaPending, ", ")
+	}
+
+	data = "-"
+	if len(db.DataDirs) > 0 {
+		if data, err = countPending(ctx, client.DataState, db.DataDirs); err != nil {
+			return "", "", err
+		}
+	}
+
+	return schema, data, nil
+}
+
+// countPending describes the number of migrations in dirs newer than the recorded state
+func countPending(ctx context.Context, state func(context.Context) (spannermigrate.MigrationState, error), dirs []string) (string, error) {
+	s, err := state(ctx)
+	if err != nil {
+		return "", err
+	}
+	if s.Dirty {
+		return s.String(), nil
+	}
+
+	sourceURL, cleanup, err := spannermigrate.LinkDirs(dirs)
+	if err != nil {
+		return "", errors.Wrap(err, "spannermigrate.LinkDirs()")
+	}
+	defer cleanup()
+
+	versions, err := spannermigrate.PendingVersions(s, sourceURL)
+	if err != nil {
+		return "", errors.Wrap(err, "spannermigrate.PendingVersions()")
+	}
+
+	return fmt.Sprint(len(versions)), nil
+}
@@ -0,0 +1,99 @@
+package optimizer
+
+import (
+	"context"
+	"log"
+
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	options spannermigrate.OptimizerOptions
+	verify  bool
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "optimizer",
+		Short: "Pin or verify the query optimizer version and statistics package",
+		Long: `Pin the database's default query optimizer version and statistics package, or verify them with --verify.
+
+Pinning both in every environment avoids plans that are fast in tst but slow in prd because the databases use different optimizer versions.
+Without any flags the current options are printed.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+	c.options.AddFlags(cmd)
+	cmd.Flags().BoolVar(&c.verify, "verify", false, "Only verify the options, failing if they differ from the database")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(cmd *cobra.Command) error {
+	if err := c.options.ValidateFlags(); err != nil {
+		return errors.Wrap(err, "spannermigrate.OptimizerOptions.ValidateFlags()")
+	}
+	if c.verify && c.options.IsZero() {
+		return errors.New("--verify requires --optimizer-version or --optimizer-statistics-package")
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	current, err := conf.migrateClient.OptimizerOptions(ctx)
+	if err != nil {
+		return errors.Wrap(err, "spannermigrate.Client.OptimizerOptions()")
+	}
+	log.Printf("Current optimizer options: %s\n", current)
+
+	switch {
+	case c.options.IsZero():
+		return nil
+	case c.verify:
+		if err := conf.migrateClient.VerifyOptimizerOptions(ctx, c.options); err != nil {
+			return errors.Wrap(err, "spannermigrate.Client.VerifyOptimizerOptions()")
+		}
+		log.Println("Optimizer options verified")
+	default:
+		changed, err := conf.migrateClient.SetOptimizerOptions(ctx, c.options)
+		if err != nil {
+			return errors.Wrap(err, "spannermigrate.Client.SetOptimizerOptions()")
+		}
+		if changed {
+			log.Println("Optimizer options updated")
+		} else {
+			log.Println("Optimizer options are up to date. No changes applied.")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package purge
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+	"google.golang.org/api/option"
+)
+
+type envConfig struct {
+	SpannerProjectID    string `env:"GOOGLE_CLOUD_SPANNER_PROJECT"`
+	SpannerInstanceID   string `env:"GOOGLE_CLOUD_SPANNER_INSTANCE_ID"`
+	SpannerDatabaseName string `env:"GOOGLE_CLOUD_SPANNER_DATABASE_NAME"`
+	AppEnv              string `env:"_APP_ENV"`
+	AuditSecret         string `env:"PURGE_AUDIT_SECRET"`
+}
+
+type config struct {
+	dbStr  string
+	appEnv string
+	// auditSecret is the key of the subject hashes in the audit table
+	auditSecret string
+	client      *spanner.Client
+	admin       *database.DatabaseAdminClient
+}
+
+func newConfig(ctx context.Context) (*config, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return nil, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	dbStr := fmt.Sprintf("projects/%s/instances/%s/databases/%s", envVars.SpannerProjectID, envVars.SpannerInstanceID, envVars.SpannerDatabaseName)
+	client, err := spanner.NewClient(ctx, dbStr, option.WithTelemetryDisabled())
+	if err != nil {
+		return nil, errors.Wrap(err, "spanner.NewClient()")
+	}
+
+	admin, err := database.NewDatabaseAdminClient(ctx, option.WithTelemetryDisabled())
+	if err != nil {
+		client.Close()
+
+		return nil, errors.Wrap(err, "database.NewDatabaseAdminClient()")
+	}
+
+	return &config{
+		dbStr:       dbStr,
+		appEnv:      envVars.AppEnv,
+		auditSecret: envVars.AuditSecret,
+		client:      client,
+		admin:       admin,
+	}, nil
+}
+
+func (c *config) close() {
+	c.client.Close()
+	if err := c.admin.Close(); err != nil {
+		log.Printf("failed to close admin: %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package purge
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/purge"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// minAuditSecretLength is the minimum length of PURGE_AUDIT_SECRET, so the subject hashes can not be brute forced
+const minAuditSecretLength = 32
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	subjectID string
+	planFile  string
+	batchSize int
+	dryRun    bool
+	plan      *purge.Plan
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Delete or anonymize the data of one subject across tables",
+		Long: `Delete or anonymize every row belonging to one data subject, e.g. for a right-to-be-forgotten request, following a
+declarative plan. Steps run in order, so child tables must come before the tables they reference:
+
+  subjectType: INT64
+  steps:
+    - table: Orders
+      where: UserId = @subjectId
+      action: anonymize
+      set:
+        ShippingAddress: redacted
+        Phone: null
+    - table: Sessions
+      where: UserId = @subjectId
+      action: delete
+    - table: Users
+      where: UserId = @subjectId
+      action: delete
+
+Each step reads the keys of the rows it matches and changes them in transactions of at most --batch-size rows.
+Where clauses are parsed and must compare a column with @subjectId, directly or in an IN subquery, and may only narrow
+that down with AND.
+
+Every purge is recorded in the ` + purge.AuditTable + ` table, which is created if needed, with the HMAC-SHA256 of the subject
+ID keyed with the PURGE_AUDIT_SECRET environment variable, the plan checksum, the rows changed by each step and any
+error. Keep the secret in Secret Manager and use the same one for every purge of a database, so the audit rows of a
+subject can be found again without storing its ID.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&c.subjectID, "subject-id", "", "ID of the data subject, bound to @subjectId in each step")
+	cmd.Flags().StringVar(&c.planFile, "plan", "purge.yaml", "YAML file with the purge steps")
+	cmd.Flags().IntVar(&c.batchSize, "batch-size", 500, "Maximum number of rows changed in each transaction")
+	cmd.Flags().BoolVar(&c.dryRun, "dry-run", false, "Count the rows each step matches without changing or recording anything")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(_ *cobra.Command) error {
+	if c.subjectID == "" {
+		return errors.New("--subject-id is required")
+	}
+	if c.batchSize < 1 {
+		return errors.Newf("--batch-size must be greater than 0, got %d", c.batchSize)
+	}
+
+	plan, err := purge.Load(c.planFile)
+	if err != nil {
+		return errors.Wrap(err, "purge.Load()")
+	}
+	c.plan = plan
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	if c.dryRun {
+		results, err := purge.Run(ctx, conf.client, c.plan, c.subjectID, c.batchSize, true)
+		if err != nil {
+			return errors.Wrap(err, "purge.Run()")
+		}
+
+		return writeResults(cmd.OutOrStdout(), results, "MATCHING ROWS")
+	}
+
+	if len(conf.auditSecret) < minAuditSecretLength {
+		return deployerr.New(deployerr.Config, "missing_audit_secret", errors.Newf("PURGE_AUDIT_SECRET must be set to at least %d characters to record the purge", minAuditSecretLength))
+	}
+	subjectHash := purge.SubjectHash([]byte(conf.auditSecret), c.subjectID)
+
+	if err := purge.EnsureAuditTable(ctx, conf.client, conf.admin, conf.dbStr); err != nil {
+		return errors.Wrap(err, "purge.EnsureAuditTable()")
+	}
+
+	results, purgeErr := purge.Run(ctx, conf.client, c.plan, c.subjectID, c.batchSize, false)
+	if err := purge.Record(ctx, conf.client, c.plan, subjectHash, conf.appEnv, results, purgeErr); err != nil {
+		if purgeErr != nil {
+			log.Printf("failed to record purge: %v", err)
+		} else {
+			return errors.Wrap(err, "purge.Record()")
+		}
+	}
+
+	if err := writeResults(cmd.OutOrStdout(), results, "ROWS"); err != nil {
+		return err
+	}
+	if purgeErr != nil {
+		return errors.Wrap(purgeErr, "purge.Run()")
+	}
+
+	log.Printf("Purge of subject %s recorded in %s\n", subjectHash, purge.AuditTable)
+
+	return nil
+}
+
+// writeResults writes the rows matched or changed by each step
+func writeResults(w io.Writer, results []purge.StepResult, rowsHeader string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "STEP\tTABLE\tACTION\t%s\n", rowsHeader)
+	for i, r := range results {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\n", i+1, r.Table, r.Action, r.Rows)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return errors.Wrap(err, "tabwriter.Writer.Flush()")
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/rbac"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	definitionFile string
+	tables         rbac.Tables
+	diff           bool
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rbac",
+		Short: "Seed role and permission tables from a YAML definition",
+		Long: `Seed the RBAC tables from a canonical YAML definition instead of ad-hoc DML files.
+
+The definition is validated before anything is written: names must be unique and roles may only reference defined permissions.
+The tables are then made to match the definition in a single transaction, including removing roles, permissions and grants
+that are no longer defined. Use --diff to print the permission changes without writing them, e.g. to review a release.
+
+Definition format:
+  permissions:
+    - name: users.read
+      description: View users
+  roles:
+    - name: admin
+      description: Administrator
+      permissions: [users.read]`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&c.definitionFile, "definition", "bootstrap/rbac.yaml", "Path to the YAML RBAC definition")
+	cmd.Flags().StringVar(&c.tables.Permissions, "permissions-table", "Permissions", "Table of permissions, with Name and Description columns")
+	cmd.Flags().StringVar(&c.tables.Roles, "roles-table", "Roles", "Table of roles, with Name and Description columns")
+	cmd.Flags().StringVar(&c.tables.RolePermissions, "role-permissions-table", "RolePermissions", "Table of permissions granted to roles, with RoleName and PermissionName columns")
+	cmd.Flags().BoolVar(&c.diff, "diff", false, "Print the changes between the database and the definition without applying them")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(_ *cobra.Command) error {
+	if c.tables.Permissions == "" || c.tables.Roles == "" || c.tables.RolePermissions == "" {
+		return errors.New("--permissions-table, --roles-table and --role-permissions-table must not be empty")
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	def, err := rbac.Load(c.definitionFile)
+	if err != nil {
+		return errors.Wrap(err, "rbac.Load()")
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	var changes []rbac.Change
+	if c.diff {
+		txn := conf.client.ReadOnlyTransaction()
+		defer txn.Close()
+
+		current, err := rbac.Read(ctx, txn, c.tables)
+		if err != nil {
+			return errors.Wrap(err, "rbac.Read()")
+		}
+		changes = rbac.Diff(current, def.State())
+	} else {
+		changes, err = rbac.Apply(ctx, conf.client, c.tables, def)
+		if err != nil {
+			return errors.Wrap(err, "rbac.Apply()")
+		}
+	}
+
+	for _, change := range changes {
+		fmt.Fprintln(cmd.OutOrStdout(), change)
+	}
+
+	switch {
+	case len(changes) == 0:
+		log.Println("RBAC tables match the definition. No changes.")
+	case c.diff:
+		log.Printf("%d change(s) would be applied\n", len(changes))
+	default:
+		log.Printf("Applied %d change(s)\n", len(changes))
+	}
+
+	return nil
+}
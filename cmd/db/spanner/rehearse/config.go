@@ -0,0 +1,65 @@
+package rehearse
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+	"google.golang.org/api/option"
+)
+
+type envConfig struct {
+	SpannerProjectID    string `env:"GOOGLE_CLOUD_SPANNER_PROJECT"`
+	SpannerInstanceID   string `env:"GOOGLE_CLOUD_SPANNER_INSTANCE_ID"`
+	SpannerDatabaseName string `env:"GOOGLE_CLOUD_SPANNER_DATABASE_NAME"`
+}
+
+type config struct {
+	projectID   string
+	instanceStr string
+	instanceID  string
+	dbStr       string
+	admin       *database.DatabaseAdminClient
+}
+
+func newConfig(ctx context.Context) (*config, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return nil, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	admin, err := database.NewDatabaseAdminClient(ctx, option.WithTelemetryDisabled())
+	if err != nil {
+		return nil, errors.Wrap(err, "database.NewDatabaseAdminClient()")
+	}
+
+	instanceStr := fmt.Sprintf("projects/%s/instances/%s", envVars.SpannerProjectID, envVars.SpannerInstanceID)
+
+	return &config{
+		projectID:   envVars.SpannerProjectID,
+		instanceID:  envVars.SpannerInstanceID,
+		instanceStr: instanceStr,
+		dbStr:       fmt.Sprintf("%s/databases/%s", instanceStr, envVars.SpannerDatabaseName),
+		admin:       admin,
+	}, nil
+}
+
+// connect returns a migrate client for the rehearsal database dbID
+func (c *config) connect(ctx context.Context, dbID string) (*spannermigrate.Client, error) {
+	client, err := spannermigrate.Connect(ctx, c.projectID, c.instanceID, dbID, option.WithTelemetryDisabled())
+	if err != nil {
+		return nil, errors.Wrap(err, "spannermigrate.Connect()")
+	}
+
+	return client, nil
+}
+
+func (c *config) close() {
+	if err := c.admin.Close(); err != nil {
+		log.Printf("failed to close admin: %v", err)
+	}
+}
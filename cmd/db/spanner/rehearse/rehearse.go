@@ -0,0 +1,244 @@
+package rehearse
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/spannerbackup"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
+	"github.com/go-playground/errors/v5"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	schemaMigrationDirs []string
+	dataMigrationDirs   []string
+	backup              string
+	rehearsalDatabase   string
+	statementTimeout    time.Duration
+	mutationLimit       int64
+	partitionLargeDML   bool
+	keep                bool
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rehearse",
+		Short: "Rehearse pending migrations against a restored copy of the database",
+		Long: `Restore the latest backup of the database into a temporary database, run the pending schema and data migrations against it,
+report the time and outcome of each step, then delete the temporary database. The source database is never written to.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().
+		StringSliceVar(&c.schemaMigrationDirs, "schema-dir", []string{"file://schema/migrations"}, "Directories containing schema migration files, using the file URI syntax. Multiple directories should be comma-separated.")
+	cmd.Flags().
+		StringSliceVar(&c.dataMigrationDirs, "data-dir", nil, "Directories containing data migration files, using the file URI syntax. Multiple directories should be comma-separated.")
+	cmd.Flags().StringVar(&c.backup, "backup", "", "Full name of the backup to restore. Defaults to the most recent ready backup of the database.")
+	cmd.Flags().
+		StringVar(&c.rehearsalDatabase, "rehearsal-database", "", "ID of the temporary database to restore into. Defaults to rehearsal-<timestamp>.")
+	cmd.Flags().DurationVar(&c.statementTimeout, "statement-timeout", 0, "Maximum time each statement of a DML-only data migration may run, e.g. 10m. Zero disables the timeout.")
+	cmd.Flags().
+		Int64Var(&c.mutationLimit, "mutation-limit", spannermigrate.DefaultMutationLimit, "Reject pending data migrations estimated to exceed this many mutations in a single transaction. Zero disables the check.")
+	cmd.Flags().
+		BoolVar(&c.partitionLargeDML, "partition-large-dml", false, "Execute UPDATE and DELETE statements that exceed the mutation limit as partitioned DML instead of rejecting them.")
+	cmd.Flags().BoolVar(&c.keep, "keep", false, "Keep the temporary database after the rehearsal for inspection")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(_ *cobra.Command) error {
+	if c.rehearsalDatabase == "" {
+		c.rehearsalDatabase = "rehearsal-" + time.Now().UTC().Format("20060102150405")
+	}
+	if !regexp.MustCompile(`^[a-z][a-z0-9_-]{0,28}[a-z0-9]$`).MatchString(c.rehearsalDatabase) {
+		return errors.Newf("--rehearsal-database %q is not a valid database ID", c.rehearsalDatabase)
+	}
+	if c.statementTimeout < 0 {
+		return errors.Newf("--statement-timeout must not be negative, got %s", c.statementTimeout)
+	}
+	if c.mutationLimit < 0 {
+		return errors.Newf("--mutation-limit must not be negative, got %d", c.mutationLimit)
+	}
+
+	return nil
+}
+
+// step is the outcome of one stage of the rehearsal
+type step struct {
+	name     string
+	duration time.Duration
+	err      error
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	interval, err := heartbeat.Interval(cmd)
+	if err != nil {
+		return err
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	backup := c.backup
+	if backup == "" {
+		latest, err := spannerbackup.Latest(ctx, conf.admin, conf.instanceStr, conf.dbStr)
+		if err != nil {
+			return errors.Wrap(err, "spannerbackup.Latest()")
+		}
+		backup = latest.GetName()
+		log.Printf("Using backup %s taken at %s\n", backup, latest.GetVersionTime().AsTime().UTC().Format(time.RFC3339))
+	}
+
+	steps := c.rehearse(ctx, conf, backup, interval)
+	if err := writeReport(cmd.OutOrStdout(), backup, c.rehearsalDatabase, steps); err != nil {
+		return err
+	}
+
+	for _, s := range steps {
+		if s.err != nil {
+			return errors.Newf("rehearsal failed at %s", s.name)
+		}
+	}
+
+	log.Println("Rehearsal successful")
+
+	return nil
+}
+
+// rehearse restores backup and runs the migrations against it, stopping at the first failed step
+func (c *command) rehearse(ctx context.Context, conf *config, backup string, interval time.Duration) []step {
+	var steps []step
+	run := func(name string, fn func() error) bool {
+		log.Printf("Rehearsal: %s\n", name)
+		start := time.Now()
+		err := fn()
+		if errors.Is(err, migrate.ErrNoChange) {
+			err = nil
+		}
+		steps = append(steps, step{name: name, duration: time.Since(start), err: err})
+
+		return err == nil
+	}
+
+	rehearsalDBStr := conf.instanceStr + "/databases/" + c.rehearsalDatabase
+	var started bool
+	restored := run("restore backup", func() error {
+		stop := heartbeat.Start(ctx, interval, "restore backup", "backup", backup, "database", rehearsalDBStr)
+		defer stop()
+
+		op, err := spannerbackup.StartRestore(ctx, conf.admin, conf.instanceStr, c.rehearsalDatabase, backup)
+		if err != nil {
+			return errors.Wrap(err, "spannerbackup.StartRestore()")
+		}
+		started = true
+
+		if _, err := op.Wait(ctx); err != nil {
+			return errors.Wrap(deployerr.FromSpanner(err), "database.RestoreDatabaseOperation.Wait()")
+		}
+
+		return nil
+	})
+	// the database exists once the restore has started, so it is deleted even when the restore failed
+	if started {
+		defer c.deleteRehearsalDatabase(ctx, conf, rehearsalDBStr)
+	}
+	if !restored {
+		return steps
+	}
+
+	client, err := conf.connect(ctx, c.rehearsalDatabase)
+	if err != nil {
+		return append(steps, step{name: "connect", err: err})
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			log.Printf("failed to close migrateClient: %v", err)
+		}
+	}()
+
+	client.
+		WithEnvironment("rehearsal").
+		WithHeartbeat(interval).
+		WithStatementTimeout(c.statementTimeout).
+		WithMutationLimit(c.mutationLimit).
+		WithPartitionLargeDML(c.partitionLargeDML)
+
+	if len(c.schemaMigrationDirs) > 0 {
+		if !run("schema migrations", func() error { return client.MigrateUpSchemaDirs(ctx, c.schemaMigrationDirs) }) {
+			return steps
+		}
+	}
+
+	if len(c.dataMigrationDirs) > 0 {
+		run("data migrations", func() error { return client.MigrateUpDataDirs(ctx, c.dataMigrationDirs) })
+	}
+
+	return steps
+}
+
+// writeReport writes the duration and outcome of each step to w
+func writeReport(w io.Writer, backup, database string, steps []step) error {
+	fmt.Fprintf(w, "Rehearsal of %s in %s\n", backup, database)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STEP\tDURATION\tRESULT")
+	var total time.Duration
+	for _, s := range steps {
+		result := "ok"
+		if s.err != nil {
+			result = "FAILED: " + s.err.Error()
+		}
+		total += s.duration
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.name, s.duration.Round(time.Millisecond), result)
+	}
+	fmt.Fprintf(tw, "total\t%s\t\n", total.Round(time.Millisecond))
+	if err := tw.Flush(); err != nil {
+		return errors.Wrap(err, "tabwriter.Writer.Flush()")
+	}
+
+	return nil
+}
+
+// deleteRehearsalDatabase deletes the rehearsal database unless --keep is set
+func (c *command) deleteRehearsalDatabase(ctx context.Context, conf *config, rehearsalDBStr string) {
+	if c.keep {
+		log.Printf("Keeping rehearsal database %s\n", rehearsalDBStr)
+
+		return
+	}
+	if err := spannerbackup.Drop(context.WithoutCancel(ctx), conf.admin, rehearsalDBStr); err != nil {
+		log.Printf("ERROR: failed to delete rehearsal database %s, delete it manually: %v", rehearsalDBStr, err)
+	}
+}
@@ -0,0 +1,161 @@
+package reseed
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/appenv"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/notify"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
+	"github.com/go-playground/errors/v5"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	schemaMigrationDirs []string
+	dataMigrationDirs   []string
+	lockTTL             time.Duration
+	notifyURL           string
+	notifyTemplatesFile string
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reseed",
+		Short: "Drop and rebuild a shared test database from the latest migrations and fixtures",
+		Long: `Drop the schema and rebuild it from the latest schema and data migrations. Intended for scheduled runs against shared test databases.
+
+While the rebuild runs the database holds a maintenance lock, and bootstrap refuses to run against it, so PR deploys fail fast
+instead of migrating a half-built database. The reseed likewise refuses to start while a bootstrap holds the migration lock. Like drop, this only runs when _APP_ENV is listed in _DB_DROP_ENV_WHITELIST.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().
+		StringSliceVar(&c.schemaMigrationDirs, "schema-dir", []string{"file://schema/migrations"}, "Directories containing schema migration files, using the file URI syntax. Multiple directories should be comma-separated.")
+	cmd.Flags().
+		StringSliceVar(&c.dataMigrationDirs, "data-dir", []string{"file://bootstrap/testdata"}, "Directories containing data migration and fixture files, using the file URI syntax. Multiple directories should be comma-separated.")
+	cmd.Flags().DurationVar(&c.lockTTL, "lock-ttl", spannermigrate.DefaultLockTTL, "How long the maintenance lock is held if the reseed dies without releasing it")
+	cmd.Flags().StringVar(&c.notifyURL, "notify-url", "", "Slack or Google Chat incoming webhook URL notified when the reseed starts, succeeds or fails")
+	cmd.Flags().
+		StringVar(&c.notifyTemplatesFile, "notify-templates", "", "Path to a YAML file of Go templates overriding the started, succeeded and failed notification messages")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(_ *cobra.Command) error {
+	if c.lockTTL <= 0 {
+		return errors.Newf("--lock-ttl must be greater than 0, got %s", c.lockTTL)
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	interval, err := heartbeat.Interval(cmd)
+	if err != nil {
+		return err
+	}
+
+	var templates notify.Templates
+	if c.notifyTemplatesFile != "" {
+		if templates, err = notify.LoadTemplates(c.notifyTemplatesFile); err != nil {
+			return errors.Wrap(err, "notify.LoadTemplates()")
+		}
+	}
+
+	// verify _APP_ENV is set and matches one of the allowed environments
+	if err := appenv.CheckDropAllowed(); err != nil {
+		return err
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	conf.migrateClient.WithHeartbeat(interval)
+
+	holder := spannermigrate.LockHolder("reseed", conf.buildID)
+	if err := conf.migrateClient.AcquireLock(ctx, spannermigrate.MaintenanceLock, holder, c.lockTTL); err != nil {
+		return errors.Wrap(err, "spannermigrate.Client.AcquireLock()")
+	}
+	defer func() {
+		if err := conf.migrateClient.ReleaseLock(context.WithoutCancel(ctx), spannermigrate.MaintenanceLock, holder); err != nil {
+			log.Printf("failed to release maintenance lock: %v", err)
+		}
+	}()
+
+	msg := notify.Message{
+		Event:       notify.EventStarted,
+		Command:     "reseed",
+		Environment: conf.appEnv,
+		Database:    conf.databaseName,
+		BuildID:     conf.buildID,
+	}
+	notify.Send(ctx, c.notifyURL, templates.Render(msg))
+	start := time.Now()
+
+	if err := c.reseed(ctx, conf, interval); err != nil {
+		msg.Event, msg.Duration, msg.Error = notify.EventFailed, time.Since(start).Round(time.Second), errors.Cause(err).Error()
+		notify.Send(ctx, c.notifyURL, templates.Render(msg))
+
+		return err
+	}
+
+	msg.Event, msg.Duration = notify.EventSucceeded, time.Since(start).Round(time.Second)
+	notify.Send(ctx, c.notifyURL, templates.Render(msg))
+	log.Println("Reseed successful")
+
+	return nil
+}
+
+func (c *command) reseed(ctx context.Context, conf *config, interval time.Duration) error {
+	log.Println("Dropping schema tables...")
+	stop := heartbeat.Start(ctx, interval, "drop schema")
+	err := conf.migrateClient.MigrateDropSchema(ctx)
+	stop()
+	if err != nil {
+		return errors.Wrap(err, "spannermigrate.Client.MigrateDropSchema()")
+	}
+
+	if len(c.schemaMigrationDirs) > 0 {
+		log.Println("Running schema migrations")
+		if err := conf.migrateClient.MigrateUpSchemaDirs(ctx, c.schemaMigrationDirs); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return errors.Wrap(err, "spannermigrate.Client.MigrateUpSchemaDirs()")
+		}
+	}
+
+	if len(c.dataMigrationDirs) > 0 {
+		log.Println("Running data migrations")
+		if err := conf.migrateClient.MigrateUpDataDirs(ctx, c.dataMigrationDirs); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return errors.Wrap(err, "spannermigrate.Client.MigrateUpDataDirs()")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package seed
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cccteam/deployment-tools/internal/appenv"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/hooks"
+	"github.com/cccteam/deployment-tools/internal/spannercsv"
+	"github.com/cccteam/deployment-tools/internal/spannersql"
+	"github.com/cccteam/deployment-tools/internal/spannertag"
+	"github.com/cloudspannerecosystem/memefish"
+	"github.com/cloudspannerecosystem/memefish/ast"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	seedDirs          []string
+	groupSize         int
+	nullValue         string
+	heartbeatInterval time.Duration
+	hooksFile         string
+	confirm           bool
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Load large fixture data sets using high-throughput writes",
+		Long: `Load fixture data without running it through migrate. This is intended for large seed data sets where transactional DML is too slow.
+
+Files in each directory are loaded in name order:
+  - <Table>.csv files (optionally prefixed with a number, e.g. 001_<Table>.csv) must have a header row of column names
+    and are written with BatchWrite using insert-or-update mutations. By default empty fields are written as NULL,
+    so an empty string cannot be loaded; set --null-value (e.g. --null-value '\N') to load empty fields as empty strings
+  - .sql files may contain UPDATE and DELETE statements, which are executed as partitioned DML, and INSERT statements,
+    which are each executed in their own read-write transaction
+
+Seeding a production environment (_APP_ENV of prd, prod or production) requires --confirm.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().
+		StringSliceVar(&c.seedDirs, "seed-dir", []string{"file://bootstrap/seed"}, "Directories containing seed files, using the file URI syntax. Multiple directories should be comma-separated and are loaded in the order given.")
+	cmd.Flags().
+		IntVar(&c.groupSize, "group-size", 500, "Number of CSV rows written atomically in each BatchWrite mutation group. Rows multiplied by columns must not exceed 80,000 mutations.")
+	cmd.Flags().StringVar(&c.nullValue, "null-value", "", "CSV field value written as NULL. The default writes empty fields as NULL.")
+	cmd.Flags().StringVar(&c.hooksFile, "hooks-file", "", "Path to a YAML file of preSeed and postSeed hooks to run around the seed")
+	cmd.Flags().BoolVar(&c.confirm, "confirm", false, "Confirm seeding a production environment")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(cmd *cobra.Command) error {
+	if c.groupSize < 1 {
+		return errors.Newf("--group-size must be greater than 0, got %d", c.groupSize)
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	interval, err := heartbeat.Interval(cmd)
+	if err != nil {
+		return err
+	}
+	c.heartbeatInterval = interval
+
+	var hookConfig *hooks.Config
+	if c.hooksFile != "" {
+		hookConfig, err = hooks.Load(c.hooksFile)
+		if err != nil {
+			return errors.Wrap(err, "hooks.Load()")
+		}
+		hookConfig.SetOutput(cmd.OutOrStdout(), cmd.ErrOrStderr())
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	if appenv.IsProduction(conf.appEnv) && !c.confirm {
+		return deployerr.New(deployerr.Policy, "confirm_required", errors.Newf("refusing to seed production environment %q without --confirm", conf.appEnv))
+	}
+
+	vars := map[string]string{"DEPLOY_HOOK_DATABASE": conf.databaseName}
+	if err := hookConfig.Run(ctx, hooks.PreSeed, vars); err != nil {
+		return errors.Wrap(err, "hooks.Config.Run()")
+	}
+
+	for _, seedDir := range c.seedDirs {
+		if err := c.seedDir(ctx, conf.client, strings.TrimPrefix(seedDir, "file://")); err != nil {
+			return err
+		}
+	}
+
+	if err := hookConfig.Run(ctx, hooks.PostSeed, vars); err != nil {
+		return errors.Wrap(err, "hooks.Config.Run()")
+	}
+
+	log.Println("Seeding successful")
+
+	return nil
+}
+
+func (c *command) seedDir(ctx context.Context, client *spanner.Client, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Wrap(err, "os.ReadDir()")
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if err := c.seedFile(ctx, client, filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *command) seedFile(ctx context.Context, client *spanner.Client, path string) error {
+	stop := heartbeat.Start(ctx, c.heartbeatInterval, "seed", "file", path)
+	defer stop()
+
+	switch filepath.Ext(path) {
+	case ".csv":
+		if err := spannercsv.Load(ctx, client, path, c.groupSize, c.nullValue); err != nil {
+			return errors.Wrapf(err, "spannercsv.Load(): %s", path)
+		}
+	case ".sql":
+		if err := execSQL(ctx, client, path); err != nil {
+			return errors.Wrapf(err, "execSQL(): %s", path)
+		}
+	default:
+		log.Printf("Skipping %s: unsupported seed file type\n", path)
+	}
+
+	return nil
+}
+
+func execSQL(ctx context.Context, client *spanner.Client, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "os.ReadFile()")
+	}
+
+	stmts, err := spannersql.Split(path, string(b))
+	if err != nil {
+		return errors.Wrap(err, "spannersql.Split()")
+	}
+
+	tag := spannertag.Tag("seed", filepath.Base(path))
+	log.Printf("Executing %d statements from %s\n", len(stmts), path)
+	for _, stmt := range stmts {
+		dml, err := memefish.ParseDML(path, stmt)
+		if err != nil {
+			return errors.Wrap(err, "memefish.ParseDML()")
+		}
+
+		var count int64
+		switch dml.(type) {
+		case *ast.Update, *ast.Delete:
+			count, err = client.PartitionedUpdateWithOptions(ctx, spanner.Statement{SQL: stmt}, spanner.QueryOptions{RequestTag: tag})
+			if err != nil {
+				return errors.Wrap(err, "spanner.Client.PartitionedUpdateWithOptions()")
+			}
+		case *ast.Insert:
+			if _, err := client.ReadWriteTransactionWithOptions(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+				count, err = txn.UpdateWithOptions(ctx, spanner.Statement{SQL: stmt}, spanner.QueryOptions{RequestTag: tag})
+				if err != nil {
+					return errors.Wrap(err, "spanner.ReadWriteTransaction.UpdateWithOptions()")
+				}
+
+				return nil
+			}, spanner.TransactionOptions{TransactionTag: tag}); err != nil {
+				return errors.Wrap(err, "spanner.Client.ReadWriteTransactionWithOptions()")
+			}
+		default:
+			return errors.Newf("unsupported statement type %T", dml)
+		}
+
+		log.Printf("%d rows affected\n", count)
+	}
+
+	return nil
+}
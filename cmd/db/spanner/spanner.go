@@ -4,7 +4,22 @@ import (
 	"context"
 
 	"github.com/cccteam/deployment-tools/cmd/db/spanner/bootstrap"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/create"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/dataexport"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/dataimport"
 	"github.com/cccteam/deployment-tools/cmd/db/spanner/dropschema"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/generate"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/grant"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/history"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/instance"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/migrateall"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/optimizer"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/purge"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/rbac"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/rehearse"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/reseed"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/seed"
+	"github.com/cccteam/deployment-tools/cmd/db/spanner/verifyprod"
 	"github.com/spf13/cobra"
 )
 
@@ -25,7 +40,22 @@ func (command) Setup(ctx context.Context) *cobra.Command {
 	}
 
 	cmd.AddCommand(bootstrap.Command(ctx))
+	cmd.AddCommand(create.Command(ctx))
 	cmd.AddCommand(dropschema.Command(ctx))
+	cmd.AddCommand(dataexport.Command(ctx))
+	cmd.AddCommand(dataimport.Command(ctx))
+	cmd.AddCommand(generate.Command(ctx))
+	cmd.AddCommand(grant.Command(ctx))
+	cmd.AddCommand(history.Command(ctx))
+	cmd.AddCommand(instance.Command(ctx))
+	cmd.AddCommand(migrateall.Command(ctx))
+	cmd.AddCommand(optimizer.Command(ctx))
+	cmd.AddCommand(purge.Command(ctx))
+	cmd.AddCommand(rbac.Command(ctx))
+	cmd.AddCommand(rehearse.Command(ctx))
+	cmd.AddCommand(reseed.Command(ctx))
+	cmd.AddCommand(seed.Command(ctx))
+	cmd.AddCommand(verifyprod.Command(ctx))
 
 	return cmd
 }
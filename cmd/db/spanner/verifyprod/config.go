@@ -0,0 +1,50 @@
+package verifyprod
+
+import (
+	"context"
+	"log"
+
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+	"google.golang.org/api/option"
+)
+
+type envConfig struct {
+	SpannerProjectID    string `env:"GOOGLE_CLOUD_SPANNER_PROJECT"`
+	SpannerInstanceID   string `env:"GOOGLE_CLOUD_SPANNER_INSTANCE_ID"`
+	SpannerDatabaseName string `env:"GOOGLE_CLOUD_SPANNER_DATABASE_NAME"`
+}
+
+type config struct {
+	client *spannermigrate.ReadOnlyClient
+}
+
+func newConfig(ctx context.Context, databaseRole string) (*config, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return nil, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	client, err := spannermigrate.ConnectReadOnly(
+		ctx,
+		envVars.SpannerProjectID,
+		envVars.SpannerInstanceID,
+		envVars.SpannerDatabaseName,
+		databaseRole,
+		option.WithTelemetryDisabled(),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "spannermigrate.ConnectReadOnly()")
+	}
+
+	return &config{
+		client: client,
+	}, nil
+}
+
+func (c *config) close() {
+	if err := c.client.Close(); err != nil {
+		log.Printf("failed to close client: %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package verifyprod
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	schemaMigrationDirs []string
+	dataMigrationDirs   []string
+	minRows             map[string]int64
+	databaseRole        string
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-prod",
+		Short: "Run read-only health checks against a database",
+		Long: `Run read-only checks against a database: migration versions and dirty flags, migrations missing from or edited after
+deployment, schema drift, and minimum row counts of critical tables. Schema drift compares the tables, columns, indexes,
+views and change streams of the database, read with GetDatabaseDdl, with the ones the applied schema migrations create,
+so changes made outside the migrations are found. Only read-only transactions are used, the migrations tables are never
+created, and the schema is only read, so it is safe to run on a schedule against production. Exits non-zero if any
+check fails.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().
+		StringSliceVar(&c.schemaMigrationDirs, "schema-dir", []string{"file://schema/migrations"}, "Directories containing schema migration files, using the file URI syntax. Multiple directories should be comma-separated. Empty skips the schema checks.")
+	cmd.Flags().
+		StringSliceVar(&c.dataMigrationDirs, "data-dir", nil, "Directories containing data migration files, using the file URI syntax. Multiple directories should be comma-separated. Empty skips the data checks.")
+	cmd.Flags().
+		StringToInt64Var(&c.minRows, "min-rows", nil, "Minimum row counts of critical tables, e.g. Users=1,Roles=3")
+	cmd.Flags().
+		StringVar(&c.databaseRole, "database-role", "", "Connect as this fine-grained access control role, so the database itself enforces read-only access")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(_ *cobra.Command) error {
+	for table, n := range c.minRows {
+		if n < 0 {
+			return errors.Newf("--min-rows for %s must not be negative, got %d", table, n)
+		}
+	}
+
+	return nil
+}
+
+// result is the outcome of a single check
+type result struct {
+	check  string
+	detail string
+	err    error
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	conf, err := newConfig(ctx, c.databaseRole)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	defer conf.close()
+
+	sets, err := spannermigrate.ParseSchemaDirs(c.schemaMigrationDirs)
+	if err != nil {
+		return errors.Wrap(err, "spannermigrate.ParseSchemaDirs()")
+	}
+
+	var results []result
+	for _, set := range sets {
+		kind := "schema"
+		if set.Name != "" {
+			kind += " " + set.Name
+		}
+		results = append(results, checkMigrations(ctx, kind, func(ctx context.Context) (spannermigrate.MigrationState, error) {
+			return conf.client.SchemaState(ctx, set.Name)
+		}, set.Dirs))
+	}
+	if len(sets) > 0 {
+		results = append(results, checkSchemaDrift(ctx, conf.client, sets))
+	}
+	if len(c.dataMigrationDirs) > 0 {
+		results = append(results, checkMigrations(ctx, "data", conf.client.DataState, c.dataMigrationDirs))
+		results = append(results, c.checkDrift(ctx, conf.client))
+	}
+	results = append(results, c.checkRows(ctx, conf.client)...)
+
+	failed, err := writeResults(cmd.OutOrStdout(), results)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return errors.Newf("%d of %d checks failed", failed, len(results))
+	}
+
+	return nil
+}
+
+// checkMigrations checks the recorded migration version of kind against the migration files in dirs
+func checkMigrations(ctx context.Context, kind string, state func(context.Context) (spannermigrate.MigrationState, error), dirs []string) result {
+	check := kind + " version"
+
+	s, err := state(ctx)
+	if err != nil {
+		return result{check: check, err: err}
+	}
+
+	sourceURL, cleanup, err := spannermigrate.LinkDirs(dirs)
+	if err != nil {
+		return result{check: check, detail: s.String(), err: err}
+	}
+	defer cleanup()
+
+	return result{check: check, detail: s.String(), err: spannermigrate.CheckVersion(s, sourceURL)}
+}
+
+// checkSchemaDrift compares the schema of the database with the schema the applied migrations of every set create.
+// The sets are compared together, as each only creates part of the schema.
+func checkSchemaDrift(ctx context.Context, client *spannermigrate.ReadOnlyClient, sets []spannermigrate.SchemaSet) result {
+	const check = "schema drift"
+
+	expected := spannermigrate.NewSchema()
+	for _, set := range sets {
+		state, err := client.SchemaState(ctx, set.Name)
+		if err != nil {
+			return result{check: check, err: err}
+		}
+		if err := applyMigrations(expected, set.Dirs, state); err != nil {
+			return result{check: check, err: err}
+		}
+	}
+
+	schema, err := client.Schema(ctx)
+	if err != nil {
+		return result{check: check, err: err}
+	}
+	if drift := expected.Drift(schema); len(drift) > 0 {
+		return result{check: check, err: errors.Newf("changed outside the migrations: %s", strings.Join(drift, "; "))}
+	}
+
+	return result{check: check, detail: "schema matches the applied migrations"}
+}
+
+// applyMigrations applies the migrations in dirs up to the version recorded in state to schema
+func applyMigrations(schema *spannermigrate.Schema, dirs []string, state spannermigrate.MigrationState) error {
+	sourceURL, cleanup, err := spannermigrate.LinkDirs(dirs)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	return schema.ApplyMigrations(sourceURL, state)
+}
+
+// checkDrift reports data migrations edited after they were applied
+func (c *command) checkDrift(ctx context.Context, client *spannermigrate.ReadOnlyClient) result {
+	const check = "data checksums"
+
+	sourceURL, cleanup, err := spannermigrate.LinkDirs(c.dataMigrationDirs)
+	if err != nil {
+		return result{check: check, err: err}
+	}
+	defer cleanup()
+
+	drift, err := client.ChecksumDrift(ctx, sourceURL)
+	if err != nil {
+		return result{check: check, err: err}
+	}
+	if len(drift) > 0 {
+		return result{check: check, err: errors.Newf("edited after they were applied: %s", strings.Join(drift, ", "))}
+	}
+
+	return result{check: check, detail: "applied migrations match their files"}
+}
+
+// checkRows checks the row counts of critical tables, in table name order
+func (c *command) checkRows(ctx context.Context, client *spannermigrate.ReadOnlyClient) []result {
+	tables := make([]string, 0, len(c.minRows))
+	for table := range c.minRows {
+		tables = append(tables, table)
+	}
+	slices.Sort(tables)
+
+	results := make([]result, 0, len(tables))
+	for _, table := range tables {
+		check := "rows in " + table
+		count, err := client.CountRows(ctx, table)
+		switch {
+		case err != nil:
+			results = append(results, result{check: check, err: err})
+		case count < c.minRows[table]:
+			results = append(results, result{check: check, detail: fmt.Sprint(count), err: errors.Newf("expected at least %d rows", c.minRows[table])})
+		default:
+			results = append(results, result{check: check, detail: fmt.Sprint(count)})
+		}
+	}
+
+	return results
+}
+
+// writeResults writes a line per check and returns the number of failed checks
+func writeResults(w io.Writer, results []result) (int, error) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tRESULT\tDETAIL")
+
+	var failed int
+	for _, r := range results {
+		status, detail := "ok", r.detail
+		if r.err != nil {
+			failed++
+			status = "FAILED"
+			detail = strings.TrimSpace(strings.Join([]string{r.detail, errors.Cause(r.err).Error()}, " "))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.check, status, detail)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return 0, errors.Wrap(err, "tabwriter.Writer.Flush()")
+	}
+
+	return failed, nil
+}
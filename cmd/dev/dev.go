@@ -0,0 +1,29 @@
+package dev
+
+import (
+	"context"
+
+	"github.com/cccteam/deployment-tools/cmd/dev/up"
+	"github.com/spf13/cobra"
+)
+
+type command struct{}
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+func (command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Commands for local development",
+		Long:  "Commands for local development, such as running the database in the Spanner emulator",
+	}
+
+	cmd.AddCommand(up.Command(ctx))
+
+	return cmd
+}
@@ -0,0 +1,36 @@
+package up
+
+import (
+	"context"
+
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+)
+
+type envConfig struct {
+	SpannerProjectID    string `env:"GOOGLE_CLOUD_SPANNER_PROJECT, default=local"`
+	SpannerInstanceID   string `env:"GOOGLE_CLOUD_SPANNER_INSTANCE_ID, default=local"`
+	SpannerDatabaseName string `env:"GOOGLE_CLOUD_SPANNER_DATABASE_NAME, default=local"`
+	EmulatorHost        string `env:"SPANNER_EMULATOR_HOST"`
+}
+
+type config struct {
+	projectID    string
+	instanceID   string
+	databaseName string
+	emulatorHost string
+}
+
+func newConfig(ctx context.Context) (*config, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return nil, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	return &config{
+		projectID:    envVars.SpannerProjectID,
+		instanceID:   envVars.SpannerInstanceID,
+		databaseName: envVars.SpannerDatabaseName,
+		emulatorHost: envVars.EmulatorHost,
+	}, nil
+}
@@ -0,0 +1,161 @@
+package up
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/emulator"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/spannermigrate"
+	"github.com/go-playground/errors/v5"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/option"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	schemaMigrationDirs []string
+	dataMigrationDirs   []string
+	containerName       string
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "up",
+		Short: "Start the Spanner emulator and bootstrap a local database",
+		Long: `Start the Spanner emulator, create the instance and database, and run the schema and data migrations, mirroring what
+bootstrap does in CI. The emulator at SPANNER_EMULATOR_HOST is used when set. Otherwise an emulator container is started
+with docker, or reused when it exists, and keeps running after the command exits. A stopped container is started
+again, with an empty database as the emulator keeps its data in memory.
+
+The project, instance and database default to "local" and can be set with the usual GOOGLE_CLOUD_SPANNER_* variables.
+The environment variables needed to connect to the database are printed at the end.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().
+		StringSliceVar(&c.schemaMigrationDirs, "schema-dir", []string{"file://schema/migrations"}, "Directories containing schema migration files, using the file URI syntax. Multiple directories should be comma-separated.")
+	cmd.Flags().
+		StringSliceVar(&c.dataMigrationDirs, "data-dir", []string{"file://bootstrap/testdata"}, "Directories containing data migration files, using the file URI syntax. Multiple directories should be comma-separated.")
+	cmd.Flags().StringVar(&c.containerName, "container-name", "deployment-tools-spanner", "Name of the emulator container started with docker")
+
+	return cmd
+}
+
+// ValidateFlags validates and processes any input flags
+func (c *command) ValidateFlags(_ *cobra.Command) error {
+	if c.containerName == "" {
+		return errors.New("--container-name must not be empty")
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	interval, err := heartbeat.Interval(cmd)
+	if err != nil {
+		return err
+	}
+
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+
+	host := conf.emulatorHost
+	if host == "" {
+		if !emulator.Available() {
+			return errors.Newf("docker is required to start the Spanner emulator, or set %s to use a running emulator", emulator.HostEnv)
+		}
+
+		log.Printf("Starting Spanner emulator container %s\n", c.containerName)
+		if host, _, err = emulator.Start(ctx, c.containerName); err != nil {
+			return errors.Wrap(err, "emulator.Start()")
+		}
+
+		// the Spanner client libraries connect to the emulator when this is set
+		if err := os.Setenv(emulator.HostEnv, host); err != nil {
+			return errors.Wrap(err, "os.Setenv()")
+		}
+	}
+	log.Printf("Using Spanner emulator at %s\n", host)
+
+	if err := emulator.CreateInstance(ctx, conf.projectID, conf.instanceID); err != nil {
+		return errors.Wrap(err, "emulator.CreateInstance()")
+	}
+
+	created, err := emulator.CreateDatabase(ctx, conf.projectID, conf.instanceID, conf.databaseName)
+	if err != nil {
+		return errors.Wrap(err, "emulator.CreateDatabase()")
+	}
+	if created {
+		log.Printf("Created database %s\n", conf.databaseName)
+	} else {
+		log.Printf("Database %s already exists, applying new migrations only\n", conf.databaseName)
+	}
+
+	if err := c.migrate(ctx, conf, interval); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "export %s=%s\nexport GOOGLE_CLOUD_SPANNER_PROJECT=%s\nexport GOOGLE_CLOUD_SPANNER_INSTANCE_ID=%s\nexport GOOGLE_CLOUD_SPANNER_DATABASE_NAME=%s\n",
+		emulator.HostEnv, host, conf.projectID, conf.instanceID, conf.databaseName)
+
+	return nil
+}
+
+// migrate runs the schema and data migrations against the emulator database
+func (c *command) migrate(ctx context.Context, conf *config, interval time.Duration) error {
+	client, err := spannermigrate.Connect(ctx, conf.projectID, conf.instanceID, conf.databaseName, option.WithTelemetryDisabled())
+	if err != nil {
+		return errors.Wrap(err, "spannermigrate.Connect()")
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			log.Printf("failed to close migrateClient: %v", err)
+		}
+	}()
+
+	client.WithEnvironment("local").WithHeartbeat(interval)
+
+	if len(c.schemaMigrationDirs) > 0 {
+		log.Println("Running schema migrations")
+		if err := client.MigrateUpSchemaDirs(ctx, c.schemaMigrationDirs); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return errors.Wrap(err, "spannermigrate.Client.MigrateUpSchemaDirs()")
+		}
+	}
+
+	if len(c.dataMigrationDirs) > 0 {
+		log.Println("Running data migrations")
+		if err := client.MigrateUpDataDirs(ctx, c.dataMigrationDirs); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return errors.Wrap(err, "spannermigrate.Client.MigrateUpDataDirs()")
+		}
+	}
+
+	log.Println("Local database is up to date")
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"io"
+	"log"
+	"os"
+	"regexp"
+
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+const (
+	quietFlag   = "quiet"
+	noColorFlag = "no-color"
+)
+
+// applyOutputFlags applies --quiet and --no-color. Quiet drops progress lines and heartbeats from the log, keeping
+// warnings and failures. Command output, such as tables and reports, is not affected.
+func applyOutputFlags(cmd *cobra.Command) error {
+	noColor, err := cmd.Flags().GetBool(noColorFlag)
+	if err != nil {
+		return errors.Wrapf(err, "cobra.Command.Flags().GetBool(%q)", noColorFlag)
+	}
+	if noColor {
+		// deployment-tools writes no colors itself, but hooks, plugins and checkpointed commands inherit the
+		// environment and honor NO_COLOR
+		if err := os.Setenv("NO_COLOR", "1"); err != nil {
+			return errors.Wrap(err, "os.Setenv()")
+		}
+	}
+
+	quiet, err := cmd.Flags().GetBool(quietFlag)
+	if err != nil {
+		return errors.Wrapf(err, "cobra.Command.Flags().GetBool(%q)", quietFlag)
+	}
+	if !quiet {
+		return nil
+	}
+
+	if !cmd.Flags().Changed(heartbeat.FlagName) {
+		if err := cmd.Flags().Set(heartbeat.FlagName, "0"); err != nil {
+			return errors.Wrapf(err, "cobra.Command.Flags().Set(%q)", heartbeat.FlagName)
+		}
+	}
+	log.SetOutput(newQuietWriter(log.Writer()))
+
+	return nil
+}
+
+// quietWriter passes on the log lines reporting warnings and failures and drops the rest. A line is kept when its
+// message, after the date and time the log package adds, starts with WARNING:, ERROR: or "failed to ", the prefixes
+// used for them. The log package writes each line with a single call.
+type quietWriter struct {
+	w    io.Writer
+	keep *regexp.Regexp
+}
+
+func newQuietWriter(w io.Writer) *quietWriter {
+	return &quietWriter{w: w, keep: regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} )?(\d{2}:\d{2}:\d{2}(\.\d+)? )?(WARNING:|ERROR:|failed to )`)}
+}
+
+func (q *quietWriter) Write(p []byte) (int, error) {
+	if !q.keep.Match(p) {
+		return len(p), nil
+	}
+	if _, err := q.w.Write(p); err != nil {
+		return 0, errors.Wrap(err, "io.Writer.Write()")
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"testing"
+)
+
+var timestamp = regexp.MustCompile(`(?m)^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} `)
+
+func TestQuietWriter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := log.New(newQuietWriter(&buf), "", log.LstdFlags)
+	logger.Println("Running bootstrap schema migrations from: file://schema")
+	logger.Println("Applied schema migration 3 (0003_failover_config.up.sql)")
+	logger.Println("heartbeat: schema migrations still running, elapsed 30s")
+	logger.Printf("WARNING: %s", "applying schema migrations despite failed compatibility check")
+	logger.Printf("failed to close migrateClient: %v", "closed")
+	logger.Printf("ERROR: %s", "failed to write migration report")
+
+	want := "WARNING: applying schema migrations despite failed compatibility check\n" +
+		"failed to close migrateClient: closed\n" +
+		"ERROR: failed to write migration report\n"
+	if got := timestamp.ReplaceAllString(buf.String(), ""); got != want {
+		t.Errorf("quiet log = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/cccteam/deployment-tools/internal/plugin"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct{}
+
+// Setup returns the configured cli command
+func (c *command) Setup(_ context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugins",
+		Short: "List the deployment-tools-<name> plugins found on PATH",
+		Long: `List the plugins found on PATH. An executable named deployment-tools-<name> runs as "deployment-tools <name>",
+receiving the remaining arguments and, on stdin, a JSON object with the Spanner project, instance and database and the
+environment deployment-tools resolved. Built-in commands take precedence over plugins with the same name.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.Run(cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// Run executes the command
+func (c *command) Run(cmd *cobra.Command) error {
+	plugins := plugin.List()
+	if len(plugins) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "No %s* executables found on PATH\n", plugin.Prefix)
+
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPATH")
+	for _, p := range plugins {
+		fmt.Fprintf(w, "%s\t%s\n", p.Name, p.Path)
+	}
+
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "tabwriter.Writer.Flush()")
+	}
+
+	return nil
+}
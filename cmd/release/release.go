@@ -0,0 +1,29 @@
+package release
+
+import (
+	"context"
+
+	"github.com/cccteam/deployment-tools/cmd/release/train"
+	"github.com/spf13/cobra"
+)
+
+type command struct{}
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+func (command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "release",
+		Short: "Commands for release management",
+		Long:  "Commands for release management, such as the release train schedule",
+	}
+
+	cmd.AddCommand(train.Command(ctx))
+
+	return cmd
+}
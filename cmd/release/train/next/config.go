@@ -0,0 +1,27 @@
+package next
+
+import (
+	"context"
+
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+)
+
+type envConfig struct {
+	AppEnv string `env:"_APP_ENV"`
+}
+
+type config struct {
+	appEnv string
+}
+
+func newConfig(ctx context.Context) (*config, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return nil, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	return &config{
+		appEnv: envVars.AppEnv,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package next
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/appenv"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/releasetrain"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct {
+	scheduleFile string
+	check        bool
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "next",
+		Short: "Print the next release window",
+		Long: `Print the release window open now or, when none is, the next one to open, from the weekly schedule in the
+release train file:
+
+  timeZone: America/Denver
+  windows:
+    - day: Tuesday
+      start: "10:00"
+      duration: 2h
+
+With --check, the command fails when _APP_ENV is a production environment and no window is open, so a build step
+can hold production deploys to the release train. It also fails when _APP_ENV is not set, as it can not tell whether
+the deploy is to production.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.ValidateFlags(cmd); err != nil {
+				return deployerr.New(deployerr.Config, "invalid_flags", err)
+			}
+
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&c.scheduleFile, "schedule", "release-train.yaml", "Path to the YAML file of weekly release windows")
+	cmd.Flags().BoolVar(&c.check, "check", false, "Fail when _APP_ENV is a production environment and no release window is open, or is not set")
+
+	return cmd
+}
+
+func (c *command) ValidateFlags(_ *cobra.Command) error {
+	if c.scheduleFile == "" {
+		return errors.New("--schedule is required")
+	}
+
+	return nil
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+	if c.check && conf.appEnv == "" {
+		return deployerr.New(deployerr.Config, "missing_app_env", errors.New("_APP_ENV environment variable is not set, --check can not tell whether this is a production deploy"))
+	}
+
+	schedule, err := releasetrain.Load(c.scheduleFile)
+	if err != nil {
+		return errors.Wrap(err, "releasetrain.Load()")
+	}
+
+	now := time.Now()
+	slot := schedule.Next(now)
+	if slot.Contains(now) {
+		fmt.Fprintf(cmd.OutOrStdout(), "Release window open: %s, closes in %s\n", slot, slot.End.Sub(now).Round(time.Minute))
+
+		return nil
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Next release window: %s, opens in %s\n", slot, slot.Start.Sub(now).Round(time.Minute))
+
+	if c.check && appenv.IsProduction(conf.appEnv) {
+		return deployerr.New(deployerr.Policy, "outside_release_window", errors.Newf("production deploys are only allowed in a release window, the next one is %s", slot))
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package train
+
+import (
+	"context"
+
+	"github.com/cccteam/deployment-tools/cmd/release/train/next"
+	"github.com/spf13/cobra"
+)
+
+type command struct{}
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+func (command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "train",
+		Short: "Commands for the release train schedule",
+		Long:  "Commands for the release train, the weekly windows production releases are made in",
+	}
+
+	cmd.AddCommand(next.Command(ctx))
+
+	return cmd
+}
@@ -0,0 +1,36 @@
+package whoami
+
+import (
+	"context"
+
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+)
+
+type envConfig struct {
+	SpannerProjectID          string `env:"GOOGLE_CLOUD_SPANNER_PROJECT"`
+	ApplicationCredentials    string `env:"GOOGLE_APPLICATION_CREDENTIALS"`
+	CloudSDKConfigProject     string `env:"CLOUDSDK_CORE_PROJECT"`
+	ImpersonateServiceAccount string `env:"CLOUDSDK_AUTH_IMPERSONATE_SERVICE_ACCOUNT"`
+}
+
+type config struct {
+	spannerProjectID          string
+	applicationCredentials    string
+	cloudSDKProject           string
+	impersonateServiceAccount string
+}
+
+func newConfig(ctx context.Context) (*config, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return nil, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	return &config{
+		spannerProjectID:          envVars.SpannerProjectID,
+		applicationCredentials:    envVars.ApplicationCredentials,
+		cloudSDKProject:           envVars.CloudSDKConfigProject,
+		impersonateServiceAccount: envVars.ImpersonateServiceAccount,
+	}, nil
+}
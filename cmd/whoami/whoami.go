@@ -0,0 +1,230 @@
+package whoami
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials"
+	"cloud.google.com/go/compute/metadata"
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+const tokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+// Command returns the configured command
+func Command(ctx context.Context) *cobra.Command {
+	cli := command{}
+
+	return cli.Setup(ctx)
+}
+
+type command struct{}
+
+// clientType is a client used by the deployment commands and the scopes it requests
+type clientType struct {
+	name   string
+	scopes []string
+}
+
+// clientTypes returns the clients used by the deployment commands
+func clientTypes() []clientType {
+	return []clientType{
+		{name: "spanner", scopes: []string{spanner.Scope}},
+		{name: "spanner database admin", scopes: database.DefaultAuthScopes()},
+		{name: "spanner instance admin", scopes: instance.DefaultAuthScopes()},
+	}
+}
+
+// tokenInfo is the response of the OAuth2 tokeninfo endpoint
+type tokenInfo struct {
+	Email     string `json:"email"`
+	Scope     string `json:"scope"`
+	ExpiresIn string `json:"expires_in"`
+}
+
+// Setup returns the configured cli command
+func (c *command) Setup(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "whoami",
+		Short: "Print the resolved Google Cloud identity, project and token scopes",
+		Long: `Print the identity resolved from Application Default Credentials (user, service account, impersonated service account
+or metadata server), the active project, and the scopes granted to the token used by each client type.
+
+Use this to diagnose commands that work locally but fail with permission errors in Cloud Build. Tokens are never printed.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if err := c.Run(ctx, cmd); err != nil {
+				return errors.Wrap(err, "command.Run()")
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// Run executes the command
+func (c *command) Run(ctx context.Context, cmd *cobra.Command) error {
+	conf, err := newConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize config")
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+
+	clients := clientTypes()
+	creds, err := credentials.DetectDefault(&credentials.DetectOptions{Scopes: clients[0].scopes})
+	if err != nil {
+		return errors.Wrap(err, "credentials.DetectDefault()")
+	}
+
+	source, principal := describeCredentials(ctx, creds, conf)
+	fmt.Fprintf(w, "Credential source:\t%s\n", source)
+	fmt.Fprintf(w, "Principal:\t%s\n", principal)
+
+	projectID, err := creds.ProjectID(ctx)
+	if err != nil {
+		projectID = fmt.Sprintf("unknown (%v)", err)
+	}
+	fmt.Fprintf(w, "ADC project:\t%s\n", orNone(projectID))
+	fmt.Fprintf(w, "gcloud project (CLOUDSDK_CORE_PROJECT):\t%s\n", orNone(conf.cloudSDKProject))
+	fmt.Fprintf(w, "Spanner project (GOOGLE_CLOUD_SPANNER_PROJECT):\t%s\n", orNone(conf.spannerProjectID))
+	if conf.impersonateServiceAccount != "" {
+		fmt.Fprintf(w, "gcloud impersonation:\t%s (not used by this tool, only by gcloud)\n", conf.impersonateServiceAccount)
+	}
+
+	for _, client := range clients {
+		fmt.Fprintf(w, "\nClient:\t%s\n", client.name)
+		fmt.Fprintf(w, "  Requested scopes:\t%s\n", strings.Join(client.scopes, " "))
+
+		info, err := clientTokenInfo(ctx, client)
+		if err != nil {
+			fmt.Fprintf(w, "  Error:\t%v\n", err)
+
+			continue
+		}
+		fmt.Fprintf(w, "  Token identity:\t%s\n", orNone(info.Email))
+		fmt.Fprintf(w, "  Granted scopes:\t%s\n", orNone(info.Scope))
+		fmt.Fprintf(w, "  Expires in:\t%ss\n", info.ExpiresIn)
+	}
+
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "tabwriter.Writer.Flush()")
+	}
+
+	return nil
+}
+
+// describeCredentials returns where the credentials were found and the principal they resolve to
+func describeCredentials(ctx context.Context, creds *auth.Credentials, conf *config) (source, principal string) {
+	b := creds.JSON()
+	if len(b) == 0 {
+		email, err := metadata.EmailWithContext(ctx, "default")
+		if err != nil {
+			return "metadata server", fmt.Sprintf("unknown (%v)", err)
+		}
+
+		return "metadata server", email
+	}
+
+	var file struct {
+		Type                           string `json:"type"`
+		ClientEmail                    string `json:"client_email"`
+		ServiceAccountImpersonationURL string `json:"service_account_impersonation_url"`
+	}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return "credentials file", fmt.Sprintf("unknown (%v)", err)
+	}
+
+	source = file.Type + " credentials"
+	if conf.applicationCredentials != "" {
+		source += " from GOOGLE_APPLICATION_CREDENTIALS=" + conf.applicationCredentials
+	} else {
+		source += " from gcloud application-default login"
+	}
+
+	switch {
+	case file.ServiceAccountImpersonationURL != "":
+		return source, impersonatedServiceAccount(file.ServiceAccountImpersonationURL) + " (impersonated)"
+	case file.ClientEmail != "":
+		return source, file.ClientEmail
+	default:
+		return source, "see token identity below"
+	}
+}
+
+// impersonatedServiceAccount extracts the service account email from an impersonation URL of the form
+// https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/<email>:generateAccessToken
+func impersonatedServiceAccount(impersonationURL string) string {
+	_, account, ok := strings.Cut(impersonationURL, "/serviceAccounts/")
+	if !ok {
+		return impersonationURL
+	}
+	account, _, _ = strings.Cut(account, ":")
+
+	return account
+}
+
+// clientTokenInfo fetches a token with the scopes requested by the client and asks Google what it grants
+func clientTokenInfo(ctx context.Context, client clientType) (*tokenInfo, error) {
+	creds, err := credentials.DetectDefault(&credentials.DetectOptions{Scopes: client.scopes})
+	if err != nil {
+		return nil, errors.Wrap(err, "credentials.DetectDefault()")
+	}
+
+	token, err := creds.Token(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "auth.Credentials.Token()")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	form := url.Values{"access_token": {token.Value}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenInfoURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, "http.NewRequestWithContext()")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "http.Client.Do()")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "io.ReadAll()")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Newf("tokeninfo returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	info := &tokenInfo{}
+	if err := json.Unmarshal(body, info); err != nil {
+		return nil, errors.Wrap(err, "json.Unmarshal()")
+	}
+
+	return info, nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+
+	return s
+}
@@ -0,0 +1,200 @@
+// Package deploytest runs deployment-tools commands in-process against a Spanner emulator,
+// so repositories using the tool can test their migrations in CI.
+//
+// A test starts an emulator database with [StartSpanner] and runs commands with [Run]:
+//
+//	func TestMigrations(t *testing.T) {
+//		deploytest.StartSpanner(t)
+//
+//		if _, err := deploytest.Run(t, "db", "spanner", "bootstrap", "--schema-dir", "file://schema/migrations"); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+//
+// Both functions set environment variables with [testing.T.Setenv], so tests using them can not run in parallel.
+//
+// Without docker or SPANNER_EMULATOR_HOST, tests are skipped with a note on stderr. go test hides the output of
+// passing packages when testing several of them, so set DEPLOYTEST_REQUIRE_EMULATOR=true in CI to fail the tests
+// instead.
+package deploytest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cccteam/deployment-tools/cmd"
+	"github.com/cccteam/deployment-tools/internal/emulator"
+	"github.com/go-playground/errors/v5"
+)
+
+const (
+	// EmulatorImage is the Spanner emulator container started when SPANNER_EMULATOR_HOST is not set
+	EmulatorImage = emulator.Image
+
+	// ProjectID is the project of the emulator instance
+	ProjectID = "deploytest"
+
+	// InstanceID is the emulator instance databases are created in
+	InstanceID = "deploytest"
+
+	// RequireEmulatorEnv is the environment variable that, when true, fails tests that can not start an emulator
+	// instead of skipping them
+	RequireEmulatorEnv = "DEPLOYTEST_REQUIRE_EMULATOR"
+
+	startTimeout = time.Minute
+)
+
+// Spanner is an emulator database created for a test
+type Spanner struct {
+	// Host is the emulator gRPC address
+	Host string
+	// Database is the full database name, projects/<p>/instances/<i>/databases/<d>
+	Database string
+	// DatabaseID is the database ID within [InstanceID]
+	DatabaseID string
+}
+
+// StartSpanner creates an empty database for the test and points the environment variables read by the
+// db commands at it. The emulator at SPANNER_EMULATOR_HOST is used when set. Otherwise an emulator
+// container is started with docker and stopped when the test ends. If docker is not available, the test
+// is skipped, or fails when [RequireEmulatorEnv] is true.
+func StartSpanner(t testing.TB) *Spanner {
+	t.Helper()
+
+	host := os.Getenv(emulator.HostEnv)
+	if host == "" {
+		host = startEmulator(t)
+	}
+	t.Setenv(emulator.HostEnv, host)
+
+	ctx, cancel := context.WithTimeout(t.Context(), startTimeout)
+	defer cancel()
+
+	if err := emulator.CreateInstance(ctx, ProjectID, InstanceID); err != nil {
+		t.Fatalf("emulator.CreateInstance(): %v", err)
+	}
+
+	dbID := databaseID()
+	if _, err := emulator.CreateDatabase(ctx, ProjectID, InstanceID, dbID); err != nil {
+		t.Fatalf("emulator.CreateDatabase(): %v", err)
+	}
+
+	t.Setenv("GOOGLE_CLOUD_SPANNER_PROJECT", ProjectID)
+	t.Setenv("GOOGLE_CLOUD_SPANNER_INSTANCE_ID", InstanceID)
+	t.Setenv("GOOGLE_CLOUD_SPANNER_DATABASE_NAME", dbID)
+
+	return &Spanner{
+		Host:       host,
+		Database:   fmt.Sprintf("projects/%s/instances/%s/databases/%s", ProjectID, InstanceID, dbID),
+		DatabaseID: dbID,
+	}
+}
+
+// Run executes the deployment-tools command line with args, e.g. "db", "spanner", "bootstrap",
+// and returns what the command wrote to its output and log. _APP_ENV defaults to "tst" when unset.
+func Run(t testing.TB, args ...string) (string, error) {
+	t.Helper()
+
+	if _, ok := os.LookupEnv("_APP_ENV"); !ok {
+		t.Setenv("_APP_ENV", "tst")
+	}
+
+	var out syncBuffer
+	root := cmd.Command(t.Context())
+	root.SetArgs(args)
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SilenceUsage = true
+
+	logOutput := log.Writer()
+	log.SetOutput(&out)
+	defer log.SetOutput(logOutput)
+
+	if err := root.Execute(); err != nil {
+		return out.String(), errors.Wrapf(err, "deployment-tools %s", strings.Join(args, " "))
+	}
+
+	return out.String(), nil
+}
+
+// startEmulator starts an emulator container that is removed when the test ends and returns its gRPC address
+func startEmulator(t testing.TB) string {
+	t.Helper()
+
+	if !emulator.Available() {
+		required, err := requireEmulator()
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := fmt.Sprintf("docker not found and %s not set", emulator.HostEnv)
+		if required {
+			t.Fatalf("%s, and %s requires the Spanner emulator", msg, RequireEmulatorEnv)
+		}
+		// t.Skipf is only printed with -v
+		fmt.Fprintf(os.Stderr, "deploytest: skipping %s: %s\n", t.Name(), msg)
+		t.Skipf("%s, skipping Spanner emulator test", msg)
+	}
+
+	ctx := context.WithoutCancel(t.Context())
+	host, containerID, err := emulator.Start(ctx, "")
+	if containerID != "" {
+		t.Cleanup(func() {
+			if err := emulator.Stop(ctx, containerID); err != nil {
+				t.Logf("emulator.Stop(): %v", err)
+			}
+		})
+	}
+	if err != nil {
+		t.Fatalf("emulator.Start(): %v", err)
+	}
+
+	return host
+}
+
+// requireEmulator reports whether RequireEmulatorEnv is true
+func requireEmulator() (bool, error) {
+	v := os.Getenv(RequireEmulatorEnv)
+	if v == "" {
+		return false, nil
+	}
+	required, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.Wrapf(err, "%s must be true or false, got %q", RequireEmulatorEnv, v)
+	}
+
+	return required, nil
+}
+
+// syncBuffer is a bytes.Buffer that the command and the log, which heartbeats write to from another goroutine,
+// can share
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// databaseID returns a database ID unique to the test run. Database IDs are limited to 30 characters.
+func databaseID() string {
+	return fmt.Sprintf("t%d", time.Now().UnixNano()%1e15)
+}
@@ -0,0 +1,56 @@
+package deploytest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+)
+
+//nolint:paralleltest // uses t.Setenv
+func TestBootstrap(t *testing.T) {
+	db := StartSpanner(t)
+
+	schemaDir := t.TempDir()
+	dataDir := t.TempDir()
+	writeFile(t, schemaDir, "001_users.up.sql", "CREATE TABLE Users (Id INT64 NOT NULL, Name STRING(MAX)) PRIMARY KEY (Id);")
+	writeFile(t, dataDir, "001_users.up.sql", "INSERT INTO Users (Id, Name) VALUES (1, 'admin');")
+
+	if _, err := Run(t, "db", "spanner", "bootstrap", "--schema-dir", "file://"+schemaDir, "--data-dir", "file://"+dataDir); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	ctx := context.Background()
+	client, err := spanner.NewClient(ctx, db.Database)
+	if err != nil {
+		t.Fatalf("spanner.NewClient() error = %v", err)
+	}
+	defer client.Close()
+
+	row, err := client.Single().ReadRow(ctx, "Users", spanner.Key{1}, []string{"Name"})
+	if err != nil {
+		t.Fatalf("ReadRow() error = %v", err)
+	}
+	var name string
+	if err := row.Columns(&name); err != nil {
+		t.Fatalf("Columns() error = %v", err)
+	}
+	if name != "admin" {
+		t.Errorf("Name = %q, want %q", name, "admin")
+	}
+
+	// a second run has nothing to apply
+	if _, err := Run(t, "db", "spanner", "bootstrap", "--schema-dir", "file://"+schemaDir, "--data-dir", "file://"+dataDir); err != nil {
+		t.Fatalf("Run() second run error = %v", err)
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+}
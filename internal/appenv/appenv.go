@@ -0,0 +1,42 @@
+// Package appenv contains helpers for the application environment named by _APP_ENV.
+package appenv
+
+import (
+	"os"
+	"strings"
+
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/go-playground/errors/v5"
+)
+
+// IsProduction reports whether appEnv names a production environment
+func IsProduction(appEnv string) bool {
+	switch strings.ToLower(strings.TrimSpace(appEnv)) {
+	case "prd", "prod", "production":
+		return true
+	default:
+		return false
+	}
+}
+
+// CheckDropAllowed returns an error unless _APP_ENV is set and listed in the comma-separated
+// _DB_DROP_ENV_WHITELIST, which guards every command that drops database objects
+func CheckDropAllowed() error {
+	appEnv, ok := os.LookupEnv("_APP_ENV")
+	if !ok {
+		return deployerr.New(deployerr.Policy, "drop_not_allowed", errors.New("_APP_ENV environment variable is not set. This will not run if it is not set"))
+	}
+	allowedEnvsStr, ok := os.LookupEnv("_DB_DROP_ENV_WHITELIST")
+	if !ok {
+		return deployerr.New(deployerr.Policy, "drop_not_allowed", errors.New("_DB_DROP_ENV_WHITELIST environment variable is not set. This will not run if it is not set"))
+	}
+	allowedEnvs := make(map[string]bool)
+	for env := range strings.SplitSeq(allowedEnvsStr, ",") {
+		allowedEnvs[strings.TrimSpace(env)] = true
+	}
+	if !allowedEnvs[appEnv] {
+		return deployerr.New(deployerr.Policy, "drop_not_allowed", errors.Newf("dropping schema is only allowed in allowed environments (%s), current environment: %s", allowedEnvsStr, appEnv))
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+// Package checkpoint records completed deployment steps so a retried build can skip them. A Cloud Build retry
+// starts with a fresh workspace, so the state is kept in Cloud Storage or at a local path outside the workspace.
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-playground/errors/v5"
+)
+
+// Step is a completed step
+type Step struct {
+	// Key scopes the checkpoint, e.g. to a commit SHA. A checkpoint only counts for the same key.
+	Key         string        `json:"key"`
+	CompletedAt time.Time     `json:"completedAt"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// Store records completed steps
+type Store interface {
+	// Completed returns the checkpoint of step if it was completed with key
+	Completed(ctx context.Context, step, key string) (Step, bool, error)
+	// Complete records step as completed with key
+	Complete(ctx context.Context, step, key string, duration time.Duration) error
+}
+
+// Open returns the store at location, a gs://<bucket>/<prefix> URI or a local file path
+func Open(location string) (Store, error) {
+	if strings.HasPrefix(location, "gs://") {
+		s, err := NewGCSStore(location)
+		if err != nil {
+			return nil, errors.Wrap(err, "NewGCSStore()")
+		}
+
+		return s, nil
+	}
+
+	s, err := OpenFile(location)
+	if err != nil {
+		return nil, errors.Wrap(err, "OpenFile()")
+	}
+
+	return s, nil
+}
+
+// FileStore is a JSON file of completed steps
+type FileStore struct {
+	path  string
+	steps map[string]Step
+}
+
+// OpenFile reads the state file at path. A missing file is an empty store.
+func OpenFile(path string) (*FileStore, error) {
+	s := &FileStore{path: path, steps: make(map[string]Step)}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "os.ReadFile()")
+	}
+
+	if err := json.Unmarshal(b, &s.steps); err != nil {
+		return nil, errors.Wrapf(err, "json.Unmarshal(): %s", path)
+	}
+
+	return s, nil
+}
+
+// Completed returns the checkpoint of step if it was completed with key
+func (s *FileStore) Completed(_ context.Context, step, key string) (Step, bool, error) {
+	cp, ok := s.steps[step]
+	if !ok || cp.Key != key {
+		return Step{}, false, nil
+	}
+
+	return cp, true, nil
+}
+
+// Complete records step as completed with key and saves the store
+func (s *FileStore) Complete(_ context.Context, step, key string, duration time.Duration) error {
+	s.steps[step] = Step{Key: key, CompletedAt: time.Now().UTC(), Duration: duration}
+
+	return s.save()
+}
+
+// save writes the store to a temporary file and renames it, so an interrupted build never leaves a partial file
+func (s *FileStore) save() error {
+	b, err := json.MarshalIndent(s.steps, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "json.MarshalIndent()")
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrap(err, "os.MkdirAll()")
+	}
+
+	f, err := os.CreateTemp(dir, filepath.Base(s.path)+".*")
+	if err != nil {
+		return errors.Wrap(err, "os.CreateTemp()")
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+
+		return errors.Wrap(err, "os.File.Write()")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "os.File.Close()")
+	}
+
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		return errors.Wrap(err, "os.Rename()")
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package checkpoint
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state", "checkpoints.json")
+
+	s, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, ok, _ := s.Completed(context.Background(), "schema", "abc123"); ok {
+		t.Fatal("FileStore.Completed() = true for an empty store")
+	}
+
+	if err := s.Complete(context.Background(), "schema", "abc123", time.Minute); err != nil {
+		t.Fatalf("FileStore.Complete() error = %v", err)
+	}
+
+	reopened, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		step string
+		key  string
+		want bool
+	}{
+		{name: "completed step", step: "schema", key: "abc123", want: true},
+		{name: "different key", step: "schema", key: "def456", want: false},
+		{name: "other step", step: "data", key: "abc123", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cp, ok, err := reopened.Completed(context.Background(), tt.step, tt.key)
+			if err != nil {
+				t.Fatalf("FileStore.Completed() error = %v", err)
+			}
+			if ok != tt.want {
+				t.Fatalf("FileStore.Completed() = %v, want %v", ok, tt.want)
+			}
+			if ok && cp.Duration != time.Minute {
+				t.Errorf("Step.Duration = %s, want %s", cp.Duration, time.Minute)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+
+	if s, err := Open("gs://deploy-state/checkpoints"); err != nil {
+		t.Errorf("Open() error = %v", err)
+	} else if _, ok := s.(*GCSStore); !ok {
+		t.Errorf("Open() = %T, want *GCSStore for a gs:// URI", s)
+	}
+
+	if _, err := Open("gs://"); err == nil {
+		t.Error("Open() expected error for a URI without bucket")
+	}
+
+	if s, err := Open(filepath.Join(t.TempDir(), "checkpoints.json")); err != nil {
+		t.Errorf("Open() error = %v", err)
+	} else if _, ok := s.(*FileStore); !ok {
+		t.Errorf("Open() = %T, want *FileStore for a path", s)
+	}
+}
+
+func Test_objectName(t *testing.T) {
+	t.Parallel()
+
+	if got, want := objectName("schema", "abc123"), "abc123/schema.json"; got != want {
+		t.Errorf("objectName() = %q, want %q", got, want)
+	}
+	if got, want := objectName("data/seed", "refs/heads/main"), "refs%2Fheads%2Fmain/data%2Fseed.json"; got != want {
+		t.Errorf("objectName() = %q, want %q", got, want)
+	}
+}
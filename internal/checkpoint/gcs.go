@@ -0,0 +1,63 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/gcs"
+	"github.com/go-playground/errors/v5"
+)
+
+// GCSStore keeps each checkpoint in its own Cloud Storage object, <prefix>/<key>/<step>.json, so builds of
+// different commits never overwrite each other's state
+type GCSStore struct {
+	uri string
+}
+
+// NewGCSStore returns a store under uri, gs://<bucket> optionally followed by a path prefix
+func NewGCSStore(uri string) (*GCSStore, error) {
+	if _, _, err := gcs.ParseURI(uri); err != nil {
+		return nil, errors.Wrap(err, "gcs.ParseURI()")
+	}
+
+	return &GCSStore{uri: uri}, nil
+}
+
+// Completed returns the checkpoint of step if it was completed with key
+func (s *GCSStore) Completed(ctx context.Context, step, key string) (Step, bool, error) {
+	b, err := gcs.Download(ctx, s.uri, objectName(step, key))
+	if gcs.IsNotFound(err) {
+		return Step{}, false, nil
+	}
+	if err != nil {
+		return Step{}, false, errors.Wrap(err, "gcs.Download()")
+	}
+
+	var cp Step
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return Step{}, false, errors.Wrapf(err, "json.Unmarshal(): %s/%s", s.uri, objectName(step, key))
+	}
+
+	return cp, cp.Key == key, nil
+}
+
+// Complete records step as completed with key
+func (s *GCSStore) Complete(ctx context.Context, step, key string, duration time.Duration) error {
+	b, err := json.MarshalIndent(Step{Key: key, CompletedAt: time.Now().UTC(), Duration: duration}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "json.MarshalIndent()")
+	}
+
+	if _, err := gcs.Upload(ctx, s.uri, objectName(step, key), "application/json", b); err != nil {
+		return errors.Wrap(err, "gcs.Upload()")
+	}
+
+	return nil
+}
+
+// objectName returns the name of the object of step and key, relative to the store's prefix
+func objectName(step, key string) string {
+	return url.PathEscape(key) + "/" + url.PathEscape(step) + ".json"
+}
@@ -0,0 +1,198 @@
+// Package compat checks that pending schema migrations are compatible with the application version
+// that is running while they are applied.
+package compat
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cccteam/deployment-tools/internal/yamlfile"
+	"github.com/go-playground/errors/v5"
+)
+
+// Declaration lists the schema versions that the running application must be new enough to tolerate
+type Declaration struct {
+	Requirements []Requirement `yaml:"requirements"`
+}
+
+// Requirement declares that SchemaVersion may only be applied while at least MinAppVersion is running,
+// e.g. when a migration drops a column that older releases still read
+type Requirement struct {
+	// SchemaSet is the name of the schema set SchemaVersion belongs to, empty for the default set
+	SchemaSet     string `yaml:"schemaSet"`
+	SchemaVersion uint   `yaml:"schemaVersion"`
+	MinAppVersion string `yaml:"minAppVersion"`
+	Reason        string `yaml:"reason"`
+}
+
+// Load reads the compatibility declaration at path
+func Load(path string) (*Declaration, error) {
+	d := &Declaration{}
+	if _, err := yamlfile.Load(path, "compatibility declaration", d); err != nil {
+		return nil, errors.Wrap(err, "yamlfile.Load()")
+	}
+
+	return d, nil
+}
+
+// Validate checks that every requirement has a schema version and a valid minimum application
+// version
+func (d *Declaration) Validate() error {
+	var problems yamlfile.Problems
+	for i, r := range d.Requirements {
+		if r.SchemaVersion == 0 {
+			problems.Add("requirement %d has no schemaVersion", i+1)
+		}
+		if _, err := parseVersion(r.MinAppVersion); err != nil {
+			problems.Add("requirement %d has an invalid minAppVersion %q", i+1, r.MinAppVersion)
+		}
+	}
+
+	return problems.Err()
+}
+
+// SchemaSets returns the names of the schema sets the requirements refer to, in the order they first appear
+func (d *Declaration) SchemaSets() []string {
+	var sets []string
+	for _, r := range d.Requirements {
+		if !slices.Contains(sets, r.SchemaSet) {
+			sets = append(sets, r.SchemaSet)
+		}
+	}
+
+	return sets
+}
+
+// Check returns an error listing every pending schema version that requires a newer application than
+// runningVersion. pending holds the pending versions by schema set name, empty for the default set. An empty
+// runningVersion means nothing is running yet, which is always compatible.
+func (d *Declaration) Check(pending map[string][]uint, runningVersion string) error {
+	if runningVersion == "" {
+		return nil
+	}
+
+	running, err := parseVersion(runningVersion)
+	if err != nil {
+		return errors.Wrap(err, "running application version")
+	}
+
+	var problems []string
+	for _, r := range d.Requirements {
+		if !slices.Contains(pending[r.SchemaSet], r.SchemaVersion) {
+			continue
+		}
+
+		minVersion, err := parseVersion(r.MinAppVersion)
+		if err != nil {
+			return errors.Wrapf(err, "schema version %d", r.SchemaVersion)
+		}
+		if compare(running, minVersion) >= 0 {
+			continue
+		}
+
+		schema := "schema"
+		if r.SchemaSet != "" {
+			schema += " set " + r.SchemaSet
+		}
+		problem := fmt.Sprintf("%s version %d requires application %s or newer to be running", schema, r.SchemaVersion, r.MinAppVersion)
+		if r.Reason != "" {
+			problem += ": " + r.Reason
+		}
+		problems = append(problems, problem)
+	}
+
+	if len(problems) > 0 {
+		return errors.Newf("pending schema migrations are incompatible with running application %s:\n  %s", runningVersion, strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// version is a semantic version. Missing minor and patch numbers count as 0.
+type version struct {
+	numbers []int
+	// preRelease holds the dot-separated identifiers of a pre-release suffix, e.g. rc and 1 for v1.4.0-rc.1
+	preRelease []string
+}
+
+// parseVersion parses a dotted numeric version such as v1.4.2, with an optional pre-release suffix such as
+// -rc.1. A build suffix such as +build.5 is ignored, as it does not affect precedence.
+func parseVersion(s string) (version, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	trimmed, _, _ = strings.Cut(trimmed, "+")
+	trimmed, pre, hasPre := strings.Cut(trimmed, "-")
+	if trimmed == "" {
+		return version{}, errors.Newf("invalid version %q", s)
+	}
+
+	var v version
+	for p := range strings.SplitSeq(trimmed, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return version{}, errors.Newf("invalid version %q", s)
+		}
+		v.numbers = append(v.numbers, n)
+	}
+	if hasPre {
+		v.preRelease = strings.Split(pre, ".")
+		if slices.Contains(v.preRelease, "") {
+			return version{}, errors.Newf("invalid version %q", s)
+		}
+	}
+
+	return v, nil
+}
+
+// compare returns -1, 0 or 1 when a is older than, equal to or newer than b, following semantic version
+// precedence: a pre-release is older than its release, and pre-release identifiers are compared in order,
+// numerically when both are numbers
+func compare(a, b version) int {
+	for i := range max(len(a.numbers), len(b.numbers)) {
+		var x, y int
+		if i < len(a.numbers) {
+			x = a.numbers[i]
+		}
+		if i < len(b.numbers) {
+			y = b.numbers[i]
+		}
+		if c := cmp.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(a.preRelease) == 0 && len(b.preRelease) == 0:
+		return 0
+	case len(a.preRelease) == 0:
+		return 1
+	case len(b.preRelease) == 0:
+		return -1
+	}
+	for i := range min(len(a.preRelease), len(b.preRelease)) {
+		if c := compareIdentifier(a.preRelease[i], b.preRelease[i]); c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(len(a.preRelease), len(b.preRelease))
+}
+
+// compareIdentifier compares pre-release identifiers: numbers numerically and before alphanumeric identifiers,
+// which are compared as strings
+func compareIdentifier(a, b string) int {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Compare(x, y)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
@@ -0,0 +1,80 @@
+package compat
+
+import (
+	"testing"
+)
+
+func TestDeclaration_Check(t *testing.T) {
+	t.Parallel()
+
+	d := &Declaration{Requirements: []Requirement{
+		{SchemaVersion: 12, MinAppVersion: "v1.4.0", Reason: "drops Users.LegacyName"},
+		{SchemaVersion: 15, MinAppVersion: "2.0"},
+		{SchemaSet: "audit", SchemaVersion: 3, MinAppVersion: "v1.6.0"},
+	}}
+
+	tests := []struct {
+		name    string
+		pending map[string][]uint
+		running string
+		wantErr bool
+	}{
+		{name: "nothing running", pending: map[string][]uint{"": {12, 15}}},
+		{name: "no requirements pending", pending: map[string][]uint{"": {13, 14}}, running: "v1.0.0"},
+		{name: "running is new enough", pending: map[string][]uint{"": {12}}, running: "v1.4.0"},
+		{name: "running is newer", pending: map[string][]uint{"": {12, 15}}, running: "v2.0.1"},
+		{name: "pre-release is older than its release", pending: map[string][]uint{"": {12}}, running: "v1.4.0-rc.1", wantErr: true},
+		{name: "pre-release of a newer release", pending: map[string][]uint{"": {12}}, running: "v1.5.0-rc.1"},
+		{name: "running is too old", pending: map[string][]uint{"": {12}}, running: "v1.3.9", wantErr: true},
+		{name: "second requirement too old", pending: map[string][]uint{"": {12, 15}}, running: "v1.9.0", wantErr: true},
+		{name: "invalid running version", pending: map[string][]uint{"": {12}}, running: "latest", wantErr: true},
+		{name: "named set too old", pending: map[string][]uint{"audit": {3}}, running: "v1.5.0", wantErr: true},
+		{name: "version of another set", pending: map[string][]uint{"audit": {12}}, running: "v1.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := d.Check(tt.pending, tt.running); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_compare(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.2.3", b: "1.2.3", want: 0},
+		{a: "v1.2", b: "1.2.0", want: 0},
+		{a: "1.10.0", b: "1.9.9", want: 1},
+		{a: "1.2.3", b: "1.3", want: -1},
+		{a: "1.4.0-rc.1", b: "1.4.0", want: -1},
+		{a: "1.4.0-rc.2", b: "1.4.0-rc.10", want: -1},
+		{a: "1.4.0-rc.1", b: "1.4.0-rc", want: 1},
+		{a: "1.4.0-1", b: "1.4.0-alpha", want: -1},
+		{a: "1.4.0-beta", b: "1.4.0-alpha.5", want: 1},
+		{a: "1.4.0+build.5", b: "1.4.0", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			t.Parallel()
+
+			a, err := parseVersion(tt.a)
+			if err != nil {
+				t.Fatalf("parseVersion(%q) error = %v", tt.a, err)
+			}
+			b, err := parseVersion(tt.b)
+			if err != nil {
+				t.Fatalf("parseVersion(%q) error = %v", tt.b, err)
+			}
+			if got := compare(a, b); got != tt.want {
+				t.Errorf("compare() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package datagen
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	"github.com/cccteam/deployment-tools/internal/spannercsv"
+	"github.com/go-playground/errors/v5"
+)
+
+const (
+	// mutationLimit is the maximum number of mutations Spanner allows in a single transaction
+	mutationLimit = 80000
+
+	// maxGeneratedLength caps the length of generated STRING and BYTES values
+	maxGeneratedLength = 16
+)
+
+// Generator generates rows table by table. Tables must be generated after the tables they reference, and the
+// referenced column values of every generated row are kept in memory so later rows can reference them.
+type Generator struct {
+	spec  *Spec
+	rng   *rand.Rand
+	epoch time.Time
+	// referenced lists, by table, the columns other tables reference
+	referenced map[string][]string
+	// keys holds, by table, the referenced column values of each generated row
+	keys map[string][]map[string]any
+	// keyRefs holds, by table, the indexes of the references that make up a unique key of its rows, see keyReferences
+	keyRefs map[string][]int
+}
+
+// New returns a Generator for tables. The same spec, tables and seed always generate the same rows.
+func New(spec *Spec, tables []Table, seed uint64) *Generator {
+	referenced := make(map[string][]string)
+	for _, t := range tables {
+		for _, ref := range t.References {
+			for _, c := range ref.ReferencedColumns {
+				if !slices.Contains(referenced[ref.Table], c) {
+					referenced[ref.Table] = append(referenced[ref.Table], c)
+				}
+			}
+		}
+	}
+
+	return &Generator{
+		spec:       spec,
+		rng:        rand.New(rand.NewPCG(seed, seed)), //nolint:gosec // synthetic test data does not need a secure source
+		epoch:      time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		referenced: referenced,
+		keys:       make(map[string][]map[string]any),
+		keyRefs:    make(map[string][]int),
+	}
+}
+
+// Rows returns the number of rows generated for t: the spec's number, capped at the number of distinct
+// combinations of referenced rows when those make up the primary key or a unique index of t. The referenced
+// tables must have been generated.
+func (g *Generator) Rows(t Table) (int, error) {
+	rows := g.spec.TableRows(t.Name)
+
+	refs, err := g.keyReferences(t)
+	if err != nil {
+		return 0, err
+	}
+	if len(refs) == 0 {
+		return rows, nil
+	}
+
+	combinations := 1
+	for _, i := range refs {
+		combinations *= len(g.keys[t.References[i].Table])
+		if combinations >= rows {
+			return rows, nil
+		}
+	}
+
+	return combinations, nil
+}
+
+// keyReferences returns the indexes of the references that set every column of the primary key or of a unique
+// index of t. Rows of t take distinct combinations of the rows referenced through them, so the key is unique.
+// Keys with a column that is not referenced are unique already, as that column is derived from the row number.
+func (g *Generator) keyReferences(t Table) ([]int, error) {
+	if refs, ok := g.keyRefs[t.Name]; ok {
+		return refs, nil
+	}
+
+	// the reference setting each column, the first as in Row
+	setBy := make(map[string]int)
+	for i, ref := range t.References {
+		for _, c := range ref.Columns {
+			if _, ok := setBy[c]; !ok {
+				setBy[c] = i
+			}
+		}
+	}
+
+	var primaryKey []string
+	for _, c := range t.Columns {
+		if c.PrimaryKey {
+			primaryKey = append(primaryKey, c.Name)
+		}
+	}
+
+	var keyRefs []int
+	for _, key := range append([][]string{primaryKey}, t.UniqueIndexes...) {
+		var refs []int
+		referenced := len(key) > 0
+		for _, c := range key {
+			i, ok := setBy[c]
+			if _, overridden := g.spec.column(t.Name, c); !ok || overridden {
+				referenced = false
+
+				break
+			}
+			if !slices.Contains(refs, i) {
+				refs = append(refs, i)
+			}
+		}
+		if !referenced {
+			continue
+		}
+		slices.Sort(refs)
+
+		// distinct combinations of the references are only distinct keys when the references set no other column
+		for _, i := range refs {
+			for c, by := range setBy {
+				if by == i && !slices.Contains(key, c) {
+					return nil, errors.Newf("table %s: unique values can not be generated for key (%s), as its reference to %s also sets %s; set values or format for one of its columns in the spec", t.Name, strings.Join(key, ", "), t.References[i].Table, c)
+				}
+			}
+		}
+		switch {
+		case keyRefs == nil:
+			keyRefs = refs
+		case !slices.Equal(keyRefs, refs):
+			return nil, errors.Newf("table %s: unique values can not be generated for key (%s) and key (%s) at once; set values or format for one of their columns in the spec", t.Name, strings.Join(primaryKey, ", "), strings.Join(key, ", "))
+		}
+	}
+	g.keyRefs[t.Name] = keyRefs
+
+	return keyRefs, nil
+}
+
+// Generate inserts the spec's number of rows into t and returns the number of rows written. Rows are written
+// with insert mutations, so generating into a table that already has rows with the same keys fails.
+func (g *Generator) Generate(ctx context.Context, client *spanner.Client, t Table) (int64, error) {
+	columns := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		columns[i] = c.Name
+	}
+
+	// every written cell is a mutation, and secondary index entries count too, so leave half the limit for them
+	batchSize := max(mutationLimit/2/max(len(columns), 1), 1)
+	batch := make([]*spanner.Mutation, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := client.Apply(ctx, batch); err != nil {
+			return errors.Wrap(err, "spanner.Client.Apply()")
+		}
+		batch = batch[:0]
+
+		return nil
+	}
+
+	count, err := g.Rows(t)
+	if err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	for n := 1; n <= count; n++ {
+		values, err := g.Row(t, n)
+		if err != nil {
+			return rows, err
+		}
+		batch = append(batch, spanner.Insert(t.Name, columns, values))
+		rows++
+
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return rows, err
+			}
+		}
+	}
+
+	return rows, flush()
+}
+
+// Row generates row number n (starting at 1) of t, in column order. n must not exceed Rows.
+func (g *Generator) Row(t Table, n int) ([]any, error) {
+	keyRefs, err := g.keyReferences(t)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any, len(t.Columns))
+	// row n takes combination n-1 of the rows referenced through the key references, counting in mixed radix
+	combination := n - 1
+	for i, ref := range t.References {
+		if !slices.Contains(keyRefs, i) {
+			if err := g.reference(t, ref, values, -1); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		count := len(g.keys[ref.Table])
+		if count == 0 {
+			return nil, errors.Newf("table %s references %s, which has no generated rows", t.Name, ref.Table)
+		}
+		if err := g.reference(t, ref, values, combination%count); err != nil {
+			return nil, err
+		}
+		combination /= count
+	}
+	if len(keyRefs) > 0 && combination > 0 {
+		return nil, errors.Newf("table %s has no distinct combination of referenced rows left for row %d", t.Name, n)
+	}
+
+	row := make([]any, len(t.Columns))
+	for i, c := range t.Columns {
+		v, ok := values[c.Name]
+		if !ok {
+			var err error
+			v, err = g.value(t.Name, c, n)
+			if err != nil {
+				return nil, errors.Wrapf(err, "column %s.%s", t.Name, c.Name)
+			}
+			values[c.Name] = v
+		}
+		row[i] = v
+	}
+
+	if cols := g.referenced[t.Name]; len(cols) > 0 {
+		key := make(map[string]any, len(cols))
+		for _, c := range cols {
+			key[c] = values[c]
+		}
+		g.keys[t.Name] = append(g.keys[t.Name], key)
+	}
+
+	return row, nil
+}
+
+// reference copies the referenced columns of generated row number row of ref.Table, or of a random one when row is
+// negative, into values. Columns already set by an earlier reference are kept.
+func (g *Generator) reference(t Table, ref Reference, values map[string]any, row int) error {
+	rows := g.keys[ref.Table]
+	if len(rows) == 0 {
+		for _, name := range ref.Columns {
+			if i := slices.IndexFunc(t.Columns, func(c Column) bool { return c.Name == name }); i >= 0 && !t.Columns[i].Nullable {
+				return errors.Newf("table %s references %s, which has no generated rows", t.Name, ref.Table)
+			}
+		}
+		for _, name := range ref.Columns {
+			if _, ok := values[name]; !ok {
+				values[name] = nil
+			}
+		}
+
+		return nil
+	}
+
+	if row < 0 {
+		row = g.rng.IntN(len(rows))
+	}
+	for i, name := range ref.Columns {
+		if _, ok := values[name]; !ok {
+			values[name] = rows[row][ref.ReferencedColumns[i]]
+		}
+	}
+
+	return nil
+}
+
+// value returns the value of column c in row number n, from the spec if it overrides the column
+func (g *Generator) value(table string, c Column, n int) (any, error) {
+	if spec, ok := g.spec.column(table, c.Name); ok {
+		switch {
+		case spec.AlwaysNull:
+			return nil, nil
+		case len(spec.Values) > 0:
+			return spannercsv.ParseValue(c.Type, spec.Values[g.rng.IntN(len(spec.Values))])
+		case spec.Format != "":
+			return spannercsv.ParseValue(c.Type, fmt.Sprintf(spec.Format, n))
+		}
+	}
+
+	if c.PrimaryKey || c.Unique {
+		return g.keyValue(c, n)
+	}
+
+	return g.randomValue(c)
+}
+
+// keyValue returns a value derived from the row number, so primary keys and unique indexes are unique
+func (g *Generator) keyValue(c Column, n int) (any, error) {
+	switch base, length := baseType(c.Type); base {
+	case "STRING", "BYTES":
+		s := strconv.Itoa(n)
+		if length > 0 && len(s) > length {
+			return nil, errors.Newf("%s is too short to hold %d unique keys; set values or format in the spec", c.Type, n)
+		}
+		if base == "BYTES" {
+			return []byte(s), nil
+		}
+
+		return s, nil
+	case "INT64":
+		return int64(n), nil
+	case "FLOAT64", "FLOAT32":
+		return float64(n), nil
+	case "NUMERIC":
+		return big.NewRat(int64(n), 1), nil
+	case "DATE":
+		return civil.DateOf(g.epoch).AddDays(n - 1), nil
+	case "TIMESTAMP":
+		return g.epoch.Add(time.Duration(n) * time.Second), nil
+	default:
+		return nil, errors.Newf("unique values of type %s can not be generated for a key or unique index column; set values or format in the spec", c.Type)
+	}
+}
+
+// randomValue returns a random value of the column's type
+func (g *Generator) randomValue(c Column) (any, error) {
+	switch base, length := baseType(c.Type); base {
+	case "STRING":
+		b := make([]byte, g.length(length))
+		for i := range b {
+			b[i] = byte('a' + g.rng.IntN(26))
+		}
+
+		return string(b), nil
+	case "BYTES":
+		b := make([]byte, g.length(length))
+		for i := range b {
+			b[i] = byte(g.rng.IntN(256))
+		}
+
+		return b, nil
+	case "INT64":
+		return g.rng.Int64N(1_000_000), nil
+	case "FLOAT64", "FLOAT32":
+		return float64(g.rng.IntN(100_000)) / 100, nil
+	case "NUMERIC":
+		return big.NewRat(g.rng.Int64N(100_000_000), 100), nil
+	case "BOOL":
+		return g.rng.IntN(2) == 0, nil
+	case "DATE":
+		return civil.DateOf(g.epoch).AddDays(g.rng.IntN(3650)), nil
+	case "TIMESTAMP":
+		return g.epoch.Add(time.Duration(g.rng.Int64N(int64(3650 * 24 * time.Hour)))).Truncate(time.Microsecond), nil
+	case "JSON":
+		return spanner.NullJSON{Value: map[string]any{"value": g.rng.IntN(1000)}, Valid: true}, nil
+	default:
+		if c.Nullable {
+			return nil, nil
+		}
+
+		return nil, errors.Newf("values of type %s can not be generated; the column must be nullable", c.Type)
+	}
+}
+
+// length returns a random length from 1 up to the column length, capped at maxGeneratedLength
+func (g *Generator) length(columnLength int) int {
+	limit := maxGeneratedLength
+	if columnLength > 0 {
+		limit = min(limit, columnLength)
+	}
+
+	return 1 + g.rng.IntN(limit)
+}
+
+// baseType splits a Spanner type such as STRING(36) into its name and length. The length is 0 for MAX and for
+// types without one.
+func baseType(spannerType string) (base string, length int) {
+	base, rest, ok := strings.Cut(spannerType, "(")
+	if !ok || strings.HasPrefix(spannerType, "ARRAY") {
+		return spannerType, 0
+	}
+	length, _ = strconv.Atoi(strings.TrimSuffix(rest, ")"))
+
+	return base, length
+}
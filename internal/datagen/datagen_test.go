@@ -0,0 +1,242 @@
+package datagen
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func testTables() []Table {
+	return []Table{
+		{
+			Name: "Users",
+			Columns: []Column{
+				{Name: "UserId", Type: "INT64", PrimaryKey: true},
+				{Name: "Email", Type: "STRING(64)"},
+				{Name: "Status", Type: "STRING(16)"},
+			},
+		},
+		{
+			Name: "Orders",
+			Columns: []Column{
+				{Name: "UserId", Type: "INT64", PrimaryKey: true},
+				{Name: "OrderId", Type: "STRING(36)", PrimaryKey: true},
+				{Name: "Total", Type: "NUMERIC"},
+				{Name: "ReferrerId", Type: "INT64", Nullable: true},
+			},
+			References: []Reference{
+				{Table: "Users", Columns: []string{"UserId"}, ReferencedColumns: []string{"UserId"}},
+				{Table: "Referrers", Columns: []string{"ReferrerId"}, ReferencedColumns: []string{"ReferrerId"}},
+			},
+		},
+	}
+}
+
+func generate(t *testing.T, g *Generator, tables []Table) map[string][][]any {
+	t.Helper()
+
+	rows := make(map[string][][]any)
+	for _, table := range tables {
+		count, err := g.Rows(table)
+		if err != nil {
+			t.Fatalf("Generator.Rows(%s) error = %v", table.Name, err)
+		}
+		for n := 1; n <= count; n++ {
+			row, err := g.Row(table, n)
+			if err != nil {
+				t.Fatalf("Generator.Row(%s, %d) error = %v", table.Name, n, err)
+			}
+			rows[table.Name] = append(rows[table.Name], row)
+		}
+	}
+
+	return rows
+}
+
+func TestGenerator_Row(t *testing.T) {
+	t.Parallel()
+
+	users, orders := Count(5), Count(50)
+	spec := &Spec{Tables: map[string]TableSpec{
+		"Users": {Rows: &users, Columns: map[string]ColumnSpec{
+			"Email":  {Format: "user%d@example.com"},
+			"Status": {Values: []string{"active", "suspended"}},
+		}},
+		"Orders": {Rows: &orders},
+	}}
+	tables := testTables()
+	rows := generate(t, New(spec, tables, 1), tables)
+
+	if got := len(rows["Users"]); got != 5 {
+		t.Fatalf("generated %d users, want 5", got)
+	}
+	userIDs := make(map[any]bool)
+	for i, row := range rows["Users"] {
+		if row[0] != int64(i+1) {
+			t.Errorf("user %d: UserId = %v, want %d", i+1, row[0], i+1)
+		}
+		userIDs[row[0]] = true
+		if want := fmt.Sprintf("user%d@example.com", i+1); row[1] != want {
+			t.Errorf("user %d: Email = %v", i+1, row[1])
+		}
+		if row[2] != "active" && row[2] != "suspended" {
+			t.Errorf("user %d: Status = %v, want active or suspended", i+1, row[2])
+		}
+	}
+
+	orderIDs := make(map[any]bool)
+	for i, row := range rows["Orders"] {
+		if !userIDs[row[0]] {
+			t.Errorf("order %d: UserId = %v does not reference a generated user", i+1, row[0])
+		}
+		if orderIDs[row[1]] {
+			t.Errorf("order %d: OrderId %v is not unique", i+1, row[1])
+		}
+		orderIDs[row[1]] = true
+		if row[3] != nil {
+			t.Errorf("order %d: ReferrerId = %v, want NULL as Referrers has no rows", i+1, row[3])
+		}
+	}
+
+	// the same seed generates the same rows
+	if again := generate(t, New(spec, tables, 1), tables); !reflect.DeepEqual(rows, again) {
+		t.Error("Generator is not deterministic for the same seed")
+	}
+}
+
+func TestGenerator_Row_referencedKeys(t *testing.T) {
+	t.Parallel()
+
+	users, groups := Count(3), Count(4)
+	spec := &Spec{Rows: 100, Tables: map[string]TableSpec{"Users": {Rows: &users}, "Groups": {Rows: &groups}}}
+	tables := []Table{
+		{Name: "Users", Columns: []Column{
+			{Name: "UserId", Type: "INT64", PrimaryKey: true},
+			{Name: "Email", Type: "STRING(MAX)", Unique: true},
+			{Name: "Handle", Type: "STRING(8)", Unique: true},
+		}, UniqueIndexes: [][]string{{"Email"}, {"Handle"}}},
+		{Name: "Groups", Columns: []Column{{Name: "GroupId", Type: "INT64", PrimaryKey: true}}},
+		// a join table, whose key is made up of two foreign keys
+		{Name: "Memberships", Columns: []Column{
+			{Name: "GroupId", Type: "INT64", PrimaryKey: true},
+			{Name: "UserId", Type: "INT64", PrimaryKey: true},
+		}, References: []Reference{
+			{Table: "Groups", Columns: []string{"GroupId"}, ReferencedColumns: []string{"GroupId"}},
+			{Table: "Users", Columns: []string{"UserId"}, ReferencedColumns: []string{"UserId"}},
+		}},
+		// interleaved in Users with at most one row per user
+		{Name: "Profiles", Columns: []Column{
+			{Name: "UserId", Type: "INT64", PrimaryKey: true},
+			{Name: "Bio", Type: "STRING(MAX)"},
+		}, References: []Reference{{Table: "Users", Columns: []string{"UserId"}, ReferencedColumns: []string{"UserId"}}}},
+	}
+	rows := generate(t, New(spec, tables, 1), tables)
+
+	unique := func(table string, columns ...int) {
+		t.Helper()
+
+		seen := make(map[string]bool)
+		for _, row := range rows[table] {
+			var key string
+			for _, c := range columns {
+				key += fmt.Sprintf("%v/", row[c])
+			}
+			if seen[key] {
+				t.Errorf("%s: duplicate key %s", table, key)
+			}
+			seen[key] = true
+		}
+	}
+	unique("Users", 1)
+	unique("Users", 2)
+	unique("Memberships", 0, 1)
+	unique("Profiles", 0)
+
+	if got := len(rows["Memberships"]); got != 12 {
+		t.Errorf("generated %d memberships, want 12, the number of group and user combinations", got)
+	}
+	if got := len(rows["Profiles"]); got != 3 {
+		t.Errorf("generated %d profiles, want 3, one per user", got)
+	}
+}
+
+func TestGenerator_Rows_conflictingKeys(t *testing.T) {
+	t.Parallel()
+
+	// the foreign key sets both key columns, so distinct combinations of it do not make UserId unique
+	table := Table{Name: "Sessions", Columns: []Column{
+		{Name: "UserId", Type: "INT64", PrimaryKey: true},
+		{Name: "DeviceId", Type: "INT64"},
+	}, References: []Reference{{Table: "Devices", Columns: []string{"UserId", "DeviceId"}, ReferencedColumns: []string{"UserId", "DeviceId"}}}}
+
+	if _, err := New(&Spec{Rows: 1}, []Table{table}, 1).Rows(table); err == nil {
+		t.Error("Generator.Rows() error = nil, want an error for a key its reference can not keep unique")
+	}
+}
+
+func TestGenerator_Row_missingReference(t *testing.T) {
+	t.Parallel()
+
+	tables := testTables()
+	orders := tables[1]
+	if _, err := New(&Spec{Rows: 1}, tables, 1).Row(orders, 1); err == nil {
+		t.Error("Generator.Row() error = nil, want an error for a required reference without generated rows")
+	}
+}
+
+func TestGenerator_keyValue(t *testing.T) {
+	t.Parallel()
+
+	g := New(&Spec{}, nil, 1)
+	tests := []struct {
+		name    string
+		column  Column
+		n       int
+		want    any
+		wantErr bool
+	}{
+		{name: "int64", column: Column{Type: "INT64"}, n: 7, want: int64(7)},
+		{name: "string", column: Column{Type: "STRING(MAX)"}, n: 12, want: "12"},
+		{name: "bytes", column: Column{Type: "BYTES(8)"}, n: 3, want: []byte("3")},
+		{name: "string too short", column: Column{Type: "STRING(2)"}, n: 100, wantErr: true},
+		{name: "bool", column: Column{Type: "BOOL"}, n: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := g.keyValue(tt.column, tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generator.keyValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Generator.keyValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_baseType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		spannerType string
+		wantBase    string
+		wantLength  int
+	}{
+		{spannerType: "STRING(36)", wantBase: "STRING", wantLength: 36},
+		{spannerType: "BYTES(MAX)", wantBase: "BYTES"},
+		{spannerType: "INT64", wantBase: "INT64"},
+		{spannerType: "ARRAY<STRING(10)>", wantBase: "ARRAY<STRING(10)>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spannerType, func(t *testing.T) {
+			t.Parallel()
+
+			base, length := baseType(tt.spannerType)
+			if base != tt.wantBase || length != tt.wantLength {
+				t.Errorf("baseType() = (%q, %d), want (%q, %d)", base, length, tt.wantBase, tt.wantLength)
+			}
+		})
+	}
+}
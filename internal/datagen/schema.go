@@ -0,0 +1,175 @@
+package datagen
+
+import (
+	"context"
+	"slices"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cccteam/deployment-tools/internal/spannercopy"
+	"github.com/go-playground/errors/v5"
+)
+
+// Table is a table to generate rows for
+type Table struct {
+	Name    string
+	Columns []Column
+	// References are the interleave parent followed by the foreign keys. Generated rows take the values of these
+	// columns from a row already generated for the referenced table.
+	References []Reference
+	// UniqueIndexes lists the key columns of each UNIQUE secondary index
+	UniqueIndexes [][]string
+}
+
+// Column is a writable column of a table
+type Column struct {
+	Name string
+	// Type is the Spanner type, e.g. STRING(MAX)
+	Type       string
+	Nullable   bool
+	PrimaryKey bool
+	// Unique is set for the key columns of UNIQUE secondary indexes
+	Unique bool
+}
+
+// Reference maps columns of a table to the columns of a referenced table
+type Reference struct {
+	Table             string
+	Columns           []string
+	ReferencedColumns []string
+}
+
+// Describe reads the columns, primary keys, interleaving and foreign keys of tables, which are returned in the same
+// order. Only the columns listed in each spannercopy.Table are included.
+func Describe(ctx context.Context, client *spanner.Client, tables []spannercopy.Table) ([]Table, error) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	type column struct {
+		typ      string
+		nullable bool
+	}
+	columns := make(map[[2]string]column)
+	if err := txn.Query(ctx, spanner.Statement{SQL: `SELECT TABLE_NAME, COLUMN_NAME, SPANNER_TYPE, IS_NULLABLE = 'YES'
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = ''`}).Do(func(r *spanner.Row) error {
+		var table, name, typ string
+		var nullable bool
+		if err := r.Columns(&table, &name, &typ, &nullable); err != nil {
+			return errors.Wrap(err, "spanner.Row.Columns()")
+		}
+		columns[[2]string{table, name}] = column{typ: typ, nullable: nullable}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	primaryKeys := make(map[string][]string)
+	if err := txn.Query(ctx, spanner.Statement{SQL: `SELECT TABLE_NAME, COLUMN_NAME
+		FROM INFORMATION_SCHEMA.INDEX_COLUMNS
+		WHERE TABLE_SCHEMA = '' AND INDEX_TYPE = 'PRIMARY_KEY'
+		ORDER BY TABLE_NAME, ORDINAL_POSITION`}).Do(func(r *spanner.Row) error {
+		var table, name string
+		if err := r.Columns(&table, &name); err != nil {
+			return errors.Wrap(err, "spanner.Row.Columns()")
+		}
+		primaryKeys[table] = append(primaryKeys[table], name)
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	uniqueIndexes := make(map[string][][]string)
+	var index string
+	if err := txn.Query(ctx, spanner.Statement{SQL: `SELECT i.TABLE_NAME, i.INDEX_NAME, c.COLUMN_NAME
+		FROM INFORMATION_SCHEMA.INDEXES i
+		JOIN INFORMATION_SCHEMA.INDEX_COLUMNS c ON c.TABLE_SCHEMA = i.TABLE_SCHEMA AND c.TABLE_NAME = i.TABLE_NAME AND c.INDEX_NAME = i.INDEX_NAME
+		WHERE i.TABLE_SCHEMA = '' AND i.INDEX_TYPE = 'INDEX' AND i.IS_UNIQUE AND c.ORDINAL_POSITION IS NOT NULL
+		ORDER BY i.TABLE_NAME, i.INDEX_NAME, c.ORDINAL_POSITION`}).Do(func(r *spanner.Row) error {
+		var table, name, column string
+		if err := r.Columns(&table, &name, &column); err != nil {
+			return errors.Wrap(err, "spanner.Row.Columns()")
+		}
+		indexes := uniqueIndexes[table]
+		if name != index || len(indexes) == 0 {
+			indexes = append(indexes, nil)
+			index = name
+		}
+		indexes[len(indexes)-1] = append(indexes[len(indexes)-1], column)
+		uniqueIndexes[table] = indexes
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	foreignKeys := make(map[string][]Reference)
+	var constraint string
+	if err := txn.Query(ctx, spanner.Statement{SQL: `SELECT fk.TABLE_NAME, fk.CONSTRAINT_NAME, fk.COLUMN_NAME, pk.TABLE_NAME, pk.COLUMN_NAME
+		FROM INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS rc
+		JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE fk ON fk.CONSTRAINT_SCHEMA = rc.CONSTRAINT_SCHEMA AND fk.CONSTRAINT_NAME = rc.CONSTRAINT_NAME
+		JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE pk ON pk.CONSTRAINT_SCHEMA = rc.UNIQUE_CONSTRAINT_SCHEMA AND pk.CONSTRAINT_NAME = rc.UNIQUE_CONSTRAINT_NAME
+			AND pk.ORDINAL_POSITION = fk.POSITION_IN_UNIQUE_CONSTRAINT
+		WHERE rc.CONSTRAINT_SCHEMA = ''
+		ORDER BY fk.TABLE_NAME, fk.CONSTRAINT_NAME, fk.ORDINAL_POSITION`}).Do(func(r *spanner.Row) error {
+		var table, name, column, referenced, referencedColumn string
+		if err := r.Columns(&table, &name, &column, &referenced, &referencedColumn); err != nil {
+			return errors.Wrap(err, "spanner.Row.Columns()")
+		}
+		refs := foreignKeys[table]
+		if name != constraint || len(refs) == 0 {
+			refs = append(refs, Reference{Table: referenced})
+			constraint = name
+		}
+		ref := &refs[len(refs)-1]
+		ref.Columns = append(ref.Columns, column)
+		ref.ReferencedColumns = append(ref.ReferencedColumns, referencedColumn)
+		foreignKeys[table] = refs
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	described := make([]Table, 0, len(tables))
+	for _, t := range tables {
+		d := Table{Name: t.Name}
+		for _, name := range t.Columns {
+			c := columns[[2]string{t.Name, name}]
+			d.Columns = append(d.Columns, Column{Name: name, Type: c.typ, Nullable: c.nullable})
+		}
+		for _, name := range primaryKeys[t.Name] {
+			for i := range d.Columns {
+				if d.Columns[i].Name == name {
+					d.Columns[i].PrimaryKey = true
+				}
+			}
+		}
+		d.UniqueIndexes = uniqueIndexes[t.Name]
+		for _, index := range d.UniqueIndexes {
+			for i := range d.Columns {
+				if slices.Contains(index, d.Columns[i].Name) {
+					d.Columns[i].Unique = true
+				}
+			}
+		}
+
+		// an interleaved table's key starts with the key columns of its parent, which have the same names
+		if len(t.DependsOn) > 0 {
+			if parent, ok := primaryKeys[t.DependsOn[0]]; ok && isInterleaved(primaryKeys[t.Name], parent) {
+				d.References = append(d.References, Reference{Table: t.DependsOn[0], Columns: parent, ReferencedColumns: parent})
+			}
+		}
+		d.References = append(d.References, foreignKeys[t.Name]...)
+		described = append(described, d)
+	}
+
+	return described, nil
+}
+
+// isInterleaved reports whether key starts with the parent key, as it does for a table interleaved in the parent.
+// The key of a child with at most one row per parent is the parent key itself.
+func isInterleaved(key, parent []string) bool {
+	return len(parent) <= len(key) && slices.Equal(key[:len(parent)], parent)
+}
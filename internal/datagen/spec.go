@@ -0,0 +1,121 @@
+// Package datagen generates synthetic rows that honor the column types, keys, interleaving and foreign keys of a
+// live Spanner schema.
+package datagen
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cccteam/deployment-tools/internal/yamlfile"
+	"github.com/go-playground/errors/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// Spec controls how many rows are generated and how individual columns are filled
+type Spec struct {
+	// Rows is the number of rows generated for tables without their own count
+	Rows   Count                `yaml:"rows"`
+	Tables map[string]TableSpec `yaml:"tables"`
+}
+
+// TableSpec overrides the generation of one table
+type TableSpec struct {
+	// Rows is the number of rows generated. Zero generates no rows; unset uses the spec default.
+	Rows    *Count                `yaml:"rows"`
+	Columns map[string]ColumnSpec `yaml:"columns"`
+}
+
+// ColumnSpec overrides the values of one column. Values are written in the CSV format read by seed.
+type ColumnSpec struct {
+	// Values are chosen from at random
+	Values []string `yaml:"values"`
+	// Format is a fmt format applied to the 1-based row number, e.g. user%d@example.com
+	Format string `yaml:"format"`
+	// AlwaysNull writes NULL to every row
+	AlwaysNull bool `yaml:"alwaysNull"`
+}
+
+// Count is a row count that accepts k and m suffixes, e.g. 100k
+type Count int
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (c *Count) UnmarshalYAML(value *yaml.Node) error {
+	n, err := ParseCount(value.Value)
+	if err != nil {
+		return err
+	}
+	*c = n
+
+	return nil
+}
+
+// ParseCount parses a row count such as 500, 100k or 2m
+func ParseCount(s string) (Count, error) {
+	digits := strings.ToLower(strings.TrimSpace(s))
+	multiplier := 1
+	switch {
+	case strings.HasSuffix(digits, "k"):
+		multiplier = 1_000
+	case strings.HasSuffix(digits, "m"):
+		multiplier = 1_000_000
+	}
+	if multiplier > 1 {
+		digits = digits[:len(digits)-1]
+	}
+
+	n, err := strconv.Atoi(digits)
+	if err != nil || n < 0 {
+		return 0, errors.Newf("invalid row count %q: must be a non-negative number, optionally followed by k or m", s)
+	}
+
+	return Count(n * multiplier), nil
+}
+
+// LoadSpec reads the data generation spec at path
+func LoadSpec(path string) (*Spec, error) {
+	s := &Spec{}
+	if _, err := yamlfile.Load(path, "data generation spec", s); err != nil {
+		return nil, errors.Wrap(err, "yamlfile.Load()")
+	}
+
+	return s, nil
+}
+
+// Validate checks that each column uses at most one way of choosing its values
+func (s *Spec) Validate() error {
+	var problems yamlfile.Problems
+	for table, t := range s.Tables {
+		for column, c := range t.Columns {
+			set := 0
+			for _, ok := range []bool{len(c.Values) > 0, c.Format != "", c.AlwaysNull} {
+				if ok {
+					set++
+				}
+			}
+			if set > 1 {
+				problems.Add("column %s.%s must set only one of values, format and alwaysNull", table, column)
+			}
+		}
+	}
+
+	slices.Sort(problems)
+
+	return problems.Err()
+}
+
+// TableRows returns the number of rows the spec asks for in table
+func (s *Spec) TableRows(table string) int {
+	if t, ok := s.Tables[table]; ok && t.Rows != nil {
+		return int(*t.Rows)
+	}
+
+	return int(s.Rows)
+}
+
+// column returns the override for a column, if any
+func (s *Spec) column(table, column string) (ColumnSpec, bool) {
+	c, ok := s.Tables[table].Columns[column]
+
+	return c, ok
+}
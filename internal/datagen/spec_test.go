@@ -0,0 +1,99 @@
+package datagen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		s       string
+		want    Count
+		wantErr bool
+	}{
+		{s: "500", want: 500},
+		{s: "100k", want: 100_000},
+		{s: "2M", want: 2_000_000},
+		{s: " 0 ", want: 0},
+		{s: "1.5k", wantErr: true},
+		{s: "-1", wantErr: true},
+		{s: "k", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseCount(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSpec(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		content  string
+		wantRows map[string]int
+		wantErr  bool
+	}{
+		{
+			name: "row counts",
+			content: `
+rows: 10k
+tables:
+  Users:
+    rows: 500
+    columns:
+      Email:
+        format: user%d@example.com
+  AuditLog:
+    rows: 0
+`,
+			wantRows: map[string]int{"Users": 500, "AuditLog": 0, "Orders": 10_000},
+		},
+		{
+			name: "conflicting column settings",
+			content: `
+tables:
+  Users:
+    columns:
+      Status:
+        values: [active]
+        alwaysNull: true
+`,
+			wantErr: true,
+		},
+		{name: "unknown field", content: "row: 5\n", wantErr: true},
+		{name: "invalid count", content: "rows: lots\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "datagen.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("os.WriteFile() error = %v", err)
+			}
+
+			spec, err := LoadSpec(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for table, want := range tt.wantRows {
+				if got := spec.TableRows(table); got != want {
+					t.Errorf("Spec.TableRows(%s) = %d, want %d", table, got, want)
+				}
+			}
+		})
+	}
+}
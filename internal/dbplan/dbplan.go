@@ -0,0 +1,117 @@
+// Package dbplan describes the migrations of several databases and the order they must be applied in.
+package dbplan
+
+import (
+	"strings"
+
+	"github.com/cccteam/deployment-tools/internal/yamlfile"
+	"github.com/go-playground/errors/v5"
+)
+
+// Plan lists the databases to migrate
+type Plan struct {
+	Databases []Database `yaml:"databases"`
+}
+
+// Database is a database and the migrations applied to it
+type Database struct {
+	// Name identifies the database in dependsOn. Defaults to Database.
+	Name string `yaml:"name"`
+	// Database is the database ID
+	Database string `yaml:"database"`
+	// Instance is the instance ID. Defaults to GOOGLE_CLOUD_SPANNER_INSTANCE_ID.
+	Instance   string   `yaml:"instance"`
+	SchemaDirs []string `yaml:"schemaDirs"`
+	DataDirs   []string `yaml:"dataDirs"`
+	// DependsOn names the databases that must be migrated first
+	DependsOn []string `yaml:"dependsOn"`
+}
+
+// Load reads the database plan at path
+func Load(path string) (*Plan, error) {
+	p := &Plan{}
+	if _, err := yamlfile.Load(path, "database plan", p); err != nil {
+		return nil, errors.Wrap(err, "yamlfile.Load()")
+	}
+
+	return p, nil
+}
+
+// Validate checks that every database has an ID and a unique name and only depends on defined databases. A
+// database without a name is named after its ID.
+func (p *Plan) Validate() error {
+	for i := range p.Databases {
+		if p.Databases[i].Name == "" {
+			p.Databases[i].Name = p.Databases[i].Database
+		}
+	}
+
+	var problems yamlfile.Problems
+
+	names := make(map[string]bool, len(p.Databases))
+	for _, d := range p.Databases {
+		switch {
+		case d.Database == "":
+			problems.Add("database %q has no database ID", d.Name)
+		case names[d.Name]:
+			problems.Add("database %q is defined more than once", d.Name)
+		}
+		names[d.Name] = true
+	}
+
+	for _, d := range p.Databases {
+		for _, dep := range d.DependsOn {
+			if !names[dep] {
+				problems.Add("database %q depends on undefined database %q", d.Name, dep)
+			}
+		}
+	}
+
+	return problems.Err()
+}
+
+// Order returns the databases in the order they must be migrated, every database after the databases it
+// depends on. Databases that do not depend on each other keep the order they are defined in.
+func (p *Plan) Order() ([]Database, error) {
+	remaining := make(map[string]int, len(p.Databases))
+	dependents := make(map[string][]string, len(p.Databases))
+	for _, d := range p.Databases {
+		remaining[d.Name] = len(d.DependsOn)
+		for _, dep := range d.DependsOn {
+			dependents[dep] = append(dependents[dep], d.Name)
+		}
+	}
+
+	done := make(map[string]bool, len(p.Databases))
+	ordered := make([]Database, 0, len(p.Databases))
+	for len(ordered) < len(p.Databases) {
+		progressed := false
+		for _, d := range p.Databases {
+			if done[d.Name] || remaining[d.Name] > 0 {
+				continue
+			}
+			done[d.Name] = true
+			ordered = append(ordered, d)
+			for _, dependent := range dependents[d.Name] {
+				remaining[dependent]--
+			}
+			progressed = true
+
+			// restart so databases unblocked by d keep their defined order relative to earlier ones
+			break
+		}
+
+		if !progressed {
+			var cycle []string
+			for _, d := range p.Databases {
+				if !done[d.Name] {
+					cycle = append(cycle, d.Name)
+				}
+			}
+
+			return nil, errors.Newf("databases have circular dependencies: %s", strings.Join(cycle, ", "))
+		}
+	}
+
+	return ordered, nil
+}
@@ -0,0 +1,87 @@
+package dbplan
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPlan_Order(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		databases []Database
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "no dependencies keep defined order",
+			databases: []Database{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			want:      []string{"a", "b", "c"},
+		},
+		{
+			name:      "reporting after primary",
+			databases: []Database{{Name: "reporting", DependsOn: []string{"primary"}}, {Name: "primary"}},
+			want:      []string{"primary", "reporting"},
+		},
+		{
+			name: "chain and independent",
+			databases: []Database{
+				{Name: "c", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "a"},
+				{Name: "audit"},
+			},
+			want: []string{"a", "b", "c", "audit"},
+		},
+		{
+			name:      "cycle",
+			databases: []Database{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}, {Name: "c"}},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &Plan{Databases: tt.databases}
+			got, err := p.Order()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Order() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var names []string
+			for _, d := range got {
+				names = append(names, d.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("Order() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlan_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		databases []Database
+		wantErr   bool
+	}{
+		{name: "valid", databases: []Database{{Name: "primary", Database: "app"}, {Name: "reporting", Database: "rpt", DependsOn: []string{"primary"}}}},
+		{name: "missing database ID", databases: []Database{{Name: "primary"}}, wantErr: true},
+		{name: "duplicate name", databases: []Database{{Name: "app", Database: "app"}, {Name: "app", Database: "app2"}}, wantErr: true},
+		{name: "undefined dependency", databases: []Database{{Name: "reporting", Database: "rpt", DependsOn: []string{"primary"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &Plan{Databases: tt.databases}
+			if err := p.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
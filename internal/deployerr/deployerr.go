@@ -0,0 +1,238 @@
+// Package deployerr categorizes the errors of deployment-tools and reports them as JSON, so the build wrappers
+// and the developer portal can show an actionable message and route a failure to the team that can fix it.
+package deployerr
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Category is the kind of failure, which decides who has to act on it
+type Category string
+
+const (
+	// Config is an invalid flag, environment variable or configuration file
+	Config Category = "config"
+
+	// Auth is missing credentials or permissions
+	Auth Category = "auth"
+
+	// Spanner is a failure returned by Spanner
+	Spanner Category = "spanner"
+
+	// Policy is an operation refused by a safety policy, such as dropping a production database
+	Policy Category = "policy"
+
+	// Internal is any other failure
+	Internal Category = "internal"
+)
+
+// Codes of the errors not created with New. Codes are stable: wrappers match on them.
+const (
+	CodeInvalidEnv       = "config.invalid_env"
+	CodeUnauthenticated  = "auth.unauthenticated"
+	CodePermissionDenied = "auth.permission_denied"
+	CodeNoCredentials    = "auth.no_credentials"
+	CodeUnknown          = "internal.unknown"
+)
+
+type envConfig struct {
+	Format string `env:"DEPLOYMENT_TOOLS_ERROR_FORMAT, default=text"`
+}
+
+// JSONOutput reports whether DEPLOYMENT_TOOLS_ERROR_FORMAT asks for errors to be written as JSON. It is text
+// by default.
+func JSONOutput(ctx context.Context) (bool, error) {
+	var envVars envConfig
+	if err := envconfig.Process(ctx, &envVars); err != nil {
+		return false, errors.Wrap(err, "envconfig.Process()")
+	}
+
+	switch envVars.Format {
+	case "text":
+		return false, nil
+	case "json":
+		return true, nil
+	default:
+		return false, New(Config, "invalid_env", errors.Newf("DEPLOYMENT_TOOLS_ERROR_FORMAT must be text or json, got %q", envVars.Format))
+	}
+}
+
+// Error is an error with its category and a stable code of the form <category>.<name>
+type Error struct {
+	Category Category
+	Code     string
+	Err      error
+}
+
+// New returns err categorized, with the code <category>.<name>
+func New(category Category, name string, err error) error {
+	return &Error{Category: category, Code: string(category) + "." + name, Err: err}
+}
+
+// Error returns the message of the underlying error
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// FromSpanner categorizes err, returned by a Spanner or Spanner admin client call, as a Spanner failure with the
+// code of its gRPC status, e.g. spanner.failed_precondition. Missing credentials and permissions stay Auth failures.
+// It returns nil for nil, and err as is when it is already categorized.
+func FromSpanner(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	if category, code := Classify(err); category != Internal {
+		return &Error{Category: category, Code: code, Err: err}
+	}
+
+	return &Error{Category: Spanner, Code: spannerCode(err), Err: err}
+}
+
+// Classify returns the category and code of err. Errors not created with New are classified by their origin:
+// errors of the Spanner client are Spanner failures, and other errors by their gRPC or Google API status, missing
+// credentials, and the configuration errors of envconfig. Any other gRPC status is an Internal failure with the
+// code of the status, e.g. internal.unavailable, as it can not tell which service returned it.
+func Classify(err error) (Category, string) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Category, e.Code
+	}
+
+	for _, target := range []error{envconfig.ErrMissingRequired, envconfig.ErrMissingKey, envconfig.ErrInvalidMapItem} {
+		if errors.Is(err, target) {
+			return Config, CodeInvalidEnv
+		}
+	}
+
+	// Application Default Credentials detection has no error type of its own
+	if strings.HasPrefix(errors.Cause(err).Error(), "credentials: could not find default credentials") {
+		return Auth, CodeNoCredentials
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusUnauthorized:
+			return Auth, CodeUnauthenticated
+		case http.StatusForbidden:
+			return Auth, CodePermissionDenied
+		}
+	}
+
+	switch code := status.Code(err); code {
+	case codes.Unauthenticated:
+		return Auth, CodeUnauthenticated
+	case codes.PermissionDenied:
+		return Auth, CodePermissionDenied
+	}
+
+	var spannerErr *spanner.Error
+	if errors.As(err, &spannerErr) {
+		return Spanner, spannerCode(err)
+	}
+
+	switch code := status.Code(err); code {
+	case codes.OK, codes.Unknown:
+		return Internal, CodeUnknown
+	default:
+		return Internal, string(Internal) + "." + snakeCase(code.String())
+	}
+}
+
+// spannerCode returns the code of a Spanner failure, from the gRPC status of err
+func spannerCode(err error) string {
+	code := status.Code(err)
+	if code == codes.OK {
+		code = codes.Unknown
+	}
+
+	return string(Spanner) + "." + snakeCase(code.String())
+}
+
+// Report is the JSON form of an error
+type Report struct {
+	Category Category `json:"category"`
+	Code     string   `json:"code"`
+	// Message is the root cause, meant for people
+	Message string `json:"message"`
+	// Detail is the full error with the call sites that wrapped it
+	Detail string `json:"detail"`
+}
+
+// NewReport returns the report of err
+func NewReport(err error) Report {
+	category, code := Classify(err)
+
+	cause := errors.Cause(err)
+	var e *Error
+	if errors.As(err, &e) {
+		cause = errors.Cause(e.Err)
+	}
+
+	return Report{Category: category, Code: code, Message: cause.Error(), Detail: err.Error()}
+}
+
+// WriteJSON writes the report of err to w as a single line of JSON
+func WriteJSON(w io.Writer, err error) error {
+	b, merr := json.Marshal(map[string]Report{"error": NewReport(err)})
+	if merr != nil {
+		return errors.Wrap(merr, "json.Marshal()")
+	}
+
+	if _, werr := w.Write(append(b, '\n')); werr != nil {
+		return errors.Wrap(werr, "io.Writer.Write()")
+	}
+
+	return nil
+}
+
+// Reported marks err as already written to the output, so it is not printed again
+func Reported(err error) error {
+	return &reportedError{err: err}
+}
+
+// IsReported reports whether err was marked with Reported
+func IsReported(err error) bool {
+	var r *reportedError
+
+	return errors.As(err, &r)
+}
+
+type reportedError struct {
+	err error
+}
+
+func (r *reportedError) Error() string {
+	return r.err.Error()
+}
+
+func (r *reportedError) Unwrap() error {
+	return r.err
+}
+
+// snakeCase converts a gRPC code name, e.g. FailedPrecondition, to failed_precondition
+func snakeCase(s string) string {
+	return strings.ToLower(regexp.MustCompile(`([a-z])([A-Z])`).ReplaceAllString(s, "${1}_${2}"))
+}
@@ -0,0 +1,132 @@
+package deployerr
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		err          error
+		wantCategory Category
+		wantCode     string
+	}{
+		{
+			name:         "categorized",
+			err:          errors.Wrap(New(Policy, "drop_not_allowed", errors.New("not allowed")), "appenv.CheckDropAllowed()"),
+			wantCategory: Policy,
+			wantCode:     "policy.drop_not_allowed",
+		},
+		{
+			name:         "envconfig",
+			err:          errors.Wrap(envconfig.ErrMissingRequired, "envconfig.Process()"),
+			wantCategory: Config,
+			wantCode:     CodeInvalidEnv,
+		},
+		{
+			name:         "no credentials",
+			err:          errors.Wrap(errors.New("credentials: could not find default credentials. See https://example.com"), "spanner.NewClient()"),
+			wantCategory: Auth,
+			wantCode:     CodeNoCredentials,
+		},
+		{
+			name:         "googleapi forbidden",
+			err:          errors.Wrap(&googleapi.Error{Code: http.StatusForbidden}, "storage.Objects.Get()"),
+			wantCategory: Auth,
+			wantCode:     CodePermissionDenied,
+		},
+		{
+			name:         "grpc permission denied",
+			err:          errors.Wrap(status.Error(codes.PermissionDenied, "denied"), "spanner.Client.Apply()"),
+			wantCategory: Auth,
+			wantCode:     CodePermissionDenied,
+		},
+		{
+			name:         "spanner error",
+			err:          errors.Wrap(spanner.ToSpannerError(status.Error(codes.FailedPrecondition, "index in use")), "spannermigrate.Run()"),
+			wantCategory: Spanner,
+			wantCode:     "spanner.failed_precondition",
+		},
+		{
+			name:         "spanner admin error",
+			err:          errors.Wrap(FromSpanner(status.Error(codes.FailedPrecondition, "index in use")), "database.DatabaseAdminClient.UpdateDatabaseDdl()"),
+			wantCategory: Spanner,
+			wantCode:     "spanner.failed_precondition",
+		},
+		{
+			name:         "spanner admin permission denied",
+			err:          errors.Wrap(FromSpanner(status.Error(codes.PermissionDenied, "denied")), "database.DatabaseAdminClient.UpdateDatabaseDdl()"),
+			wantCategory: Auth,
+			wantCode:     CodePermissionDenied,
+		},
+		{
+			name:         "grpc error of unknown origin",
+			err:          errors.Wrap(status.Error(codes.Unavailable, "connection reset"), "storage.Writer.Close()"),
+			wantCategory: Internal,
+			wantCode:     "internal.unavailable",
+		},
+		{
+			name:         "unknown",
+			err:          errors.New("something broke"),
+			wantCategory: Internal,
+			wantCode:     CodeUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			category, code := Classify(tt.err)
+			if category != tt.wantCategory || code != tt.wantCode {
+				t.Errorf("Classify() = %s, %s, want %s, %s", category, code, tt.wantCategory, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	t.Parallel()
+
+	err := errors.Wrap(New(Config, "invalid_flags", errors.New("--schema-dir is required")), "command.Run()")
+
+	var buf bytes.Buffer
+	if werr := WriteJSON(&buf, err); werr != nil {
+		t.Fatalf("WriteJSON() error = %v", werr)
+	}
+
+	var got map[string]Report
+	if uerr := json.Unmarshal(buf.Bytes(), &got); uerr != nil {
+		t.Fatalf("json.Unmarshal() error = %v", uerr)
+	}
+	report := got["error"]
+	if report.Category != Config || report.Code != "config.invalid_flags" || report.Message != "--schema-dir is required" {
+		t.Errorf("WriteJSON() report = %+v", report)
+	}
+	if report.Detail != err.Error() {
+		t.Errorf("WriteJSON() detail = %q, want %q", report.Detail, err.Error())
+	}
+}
+
+func TestIsReported(t *testing.T) {
+	t.Parallel()
+
+	err := errors.New("failed")
+	if IsReported(err) {
+		t.Error("IsReported() = true for unmarked error")
+	}
+	if !IsReported(errors.Wrap(Reported(err), "execute()")) {
+		t.Error("IsReported() = false for marked error")
+	}
+}
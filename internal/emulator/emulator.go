@@ -0,0 +1,184 @@
+// Package emulator starts the Spanner emulator and creates instances and databases in it.
+package emulator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	adminpb "cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/go-playground/errors/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Image is the Spanner emulator container image
+	Image = "gcr.io/cloud-spanner-emulator/emulator"
+
+	// HostEnv is the environment variable the Spanner client libraries read the emulator address from
+	HostEnv = "SPANNER_EMULATOR_HOST"
+
+	grpcPort     = "9010/tcp"
+	startTimeout = time.Minute
+)
+
+// Available reports whether docker, which is used to start the emulator, is installed
+func Available() bool {
+	_, err := exec.LookPath("docker")
+
+	return err == nil
+}
+
+// Start starts an emulator container and returns its gRPC address and container ID. A non-empty name names the
+// container and reuses it when it exists, starting it again if it was stopped, so the emulator outlives the command
+// that started it. Unnamed containers are removed when stopped.
+func Start(ctx context.Context, name string) (host, containerID string, err error) {
+	if name != "" {
+		out, err := docker(ctx, "ps", "--all", "--filter", "name=^"+name+"$", "--format", "{{.ID}} {{.State}}")
+		if err != nil {
+			return "", "", err
+		}
+		if id, state, ok := strings.Cut(out, " "); ok {
+			if state != "running" {
+				if _, err := docker(ctx, "start", id); err != nil {
+					return "", id, err
+				}
+			}
+			containerID = id
+		}
+	}
+
+	if containerID == "" {
+		args := []string{"run", "--detach", "--publish", "127.0.0.1::9010"}
+		if name != "" {
+			args = append(args, "--name", name)
+		} else {
+			args = append(args, "--rm")
+		}
+		if containerID, err = docker(ctx, append(args, Image)...); err != nil {
+			return "", "", err
+		}
+	}
+
+	out, err := docker(ctx, "port", containerID, grpcPort)
+	if err != nil {
+		return "", containerID, err
+	}
+	host = strings.SplitN(out, "\n", 2)[0]
+
+	if err := waitForPort(ctx, host); err != nil {
+		return "", containerID, err
+	}
+
+	return host, containerID, nil
+}
+
+// Stop stops the emulator container
+func Stop(ctx context.Context, containerID string) error {
+	_, err := docker(ctx, "stop", containerID)
+
+	return err
+}
+
+// CreateInstance creates the instance in the emulator unless it already exists. The emulator is selected by the
+// SPANNER_EMULATOR_HOST environment variable.
+func CreateInstance(ctx context.Context, projectID, instanceID string) error {
+	admin, err := instance.NewInstanceAdminClient(ctx)
+	if err != nil {
+		return errors.Wrap(err, "instance.NewInstanceAdminClient()")
+	}
+	defer admin.Close()
+
+	op, err := admin.CreateInstance(ctx, &instancepb.CreateInstanceRequest{
+		Parent:     "projects/" + projectID,
+		InstanceId: instanceID,
+		Instance: &instancepb.Instance{
+			Config:      fmt.Sprintf("projects/%s/instanceConfigs/emulator-config", projectID),
+			DisplayName: instanceID,
+			NodeCount:   1,
+		},
+	})
+	if status.Code(err) == codes.AlreadyExists {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "instance.InstanceAdminClient.CreateInstance()")
+	}
+	if _, err := op.Wait(ctx); err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "instance.CreateInstanceOperation.Wait()")
+	}
+
+	return nil
+}
+
+// CreateDatabase creates an empty database in the emulator instance. It reports false when the database already
+// existed. The emulator is selected by the SPANNER_EMULATOR_HOST environment variable.
+func CreateDatabase(ctx context.Context, projectID, instanceID, dbID string) (bool, error) {
+	admin, err := database.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "database.NewDatabaseAdminClient()")
+	}
+	defer admin.Close()
+
+	op, err := admin.CreateDatabase(ctx, &adminpb.CreateDatabaseRequest{
+		Parent:          fmt.Sprintf("projects/%s/instances/%s", projectID, instanceID),
+		CreateStatement: fmt.Sprintf("CREATE DATABASE `%s`", dbID),
+	})
+	if status.Code(err) == codes.AlreadyExists {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.CreateDatabase()")
+	}
+	if _, err := op.Wait(ctx); err != nil {
+		return false, errors.Wrap(deployerr.FromSpanner(err), "database.CreateDatabaseOperation.Wait()")
+	}
+
+	return true, nil
+}
+
+// waitForPort waits until the emulator accepts connections
+func waitForPort(ctx context.Context, host string) error {
+	deadline := time.Now().Add(startTimeout)
+	for {
+		conn, err := (&net.Dialer{Timeout: time.Second}).DialContext(ctx, "tcp", host)
+		if err == nil {
+			_ = conn.Close()
+
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Wrapf(err, "Spanner emulator at %s did not start within %s", host, startTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "waiting for Spanner emulator")
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// docker runs a docker command and returns its trimmed output
+func docker(ctx context.Context, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "docker", args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", errors.Newf("docker %s: %v: %s", args[0], err, bytes.TrimSpace(exitErr.Stderr))
+		}
+
+		return "", errors.Wrapf(err, "docker %s", args[0])
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,119 @@
+// Package environments reads environments.yaml, the repository-level description of the environments a
+// repository deploys to, so their Spanner project, instance and database are not scattered across build triggers.
+package environments
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/yamlfile"
+	"github.com/go-playground/errors/v5"
+)
+
+// DefaultFile is the environments file read when no other is named
+const DefaultFile = "environments.yaml"
+
+// File lists the environments by name. The name is used as _APP_ENV, so production environments must be named
+// prd, prod or production for the production guards to apply.
+type File struct {
+	Environments map[string]Environment `yaml:"environments"`
+}
+
+// Environment is where an environment's database lives and what may be done to it
+type Environment struct {
+	Project  string `yaml:"project"`
+	Instance string `yaml:"instance"`
+	Database string `yaml:"database"`
+	// AllowDrop adds the environment to _DB_DROP_ENV_WHITELIST, allowing commands that drop database objects
+	AllowDrop bool `yaml:"allowDrop"`
+}
+
+// Load reads and validates an environments file
+func Load(path string) (*File, error) {
+	f := &File{}
+	if _, err := yamlfile.Load(path, "environments file", f); err != nil {
+		return nil, errors.Wrap(err, "yamlfile.Load()")
+	}
+
+	return f, nil
+}
+
+// Validate checks that every environment names its project, instance and database
+func (f *File) Validate() error {
+	var problems yamlfile.Problems
+	if len(f.Environments) == 0 {
+		problems.Add("no environments defined")
+	}
+	for _, name := range slices.Sorted(maps.Keys(f.Environments)) {
+		env := f.Environments[name]
+		if strings.ContainsAny(name, ", ") {
+			problems.Add("environment name %q must not contain commas or spaces", name)
+		}
+		if env.Project == "" {
+			problems.Add("environment %s has no project", name)
+		}
+		if env.Instance == "" {
+			problems.Add("environment %s has no instance", name)
+		}
+		if env.Database == "" {
+			problems.Add("environment %s has no database", name)
+		}
+	}
+
+	return problems.Err()
+}
+
+// Vars returns the environment variables the commands read for the named environment
+func (f *File) Vars(name string) (map[string]string, error) {
+	env, ok := f.Environments[name]
+	if !ok {
+		return nil, errors.Newf("unknown environment %q, must be one of %s", name, strings.Join(slices.Sorted(maps.Keys(f.Environments)), ", "))
+	}
+
+	var dropAllowed []string
+	for _, n := range slices.Sorted(maps.Keys(f.Environments)) {
+		if f.Environments[n].AllowDrop {
+			dropAllowed = append(dropAllowed, n)
+		}
+	}
+
+	return map[string]string{
+		"GOOGLE_CLOUD_SPANNER_PROJECT":       env.Project,
+		"GOOGLE_CLOUD_SPANNER_INSTANCE_ID":   env.Instance,
+		"GOOGLE_CLOUD_SPANNER_DATABASE_NAME": env.Database,
+		"_APP_ENV":                           name,
+		"_DB_DROP_ENV_WHITELIST":             strings.Join(dropAllowed, ","),
+	}, nil
+}
+
+// Apply sets the environment variables of the named environment for the commands to read. The environment is
+// authoritative: a variable already set to a different value is an error rather than silently mixing two
+// environments, e.g. a prd database left in the shell with the tst _APP_ENV and drop whitelist.
+func (f *File) Apply(name string) error {
+	vars, err := f.Vars(name)
+	if err != nil {
+		return errors.Wrap(err, "File.Vars()")
+	}
+
+	var conflicts []string
+	for _, key := range slices.Sorted(maps.Keys(vars)) {
+		if current, ok := os.LookupEnv(key); ok && current != vars[key] {
+			conflicts = append(conflicts, fmt.Sprintf("%s=%q is set, environment %s has %q", key, current, name, vars[key]))
+		}
+	}
+	if len(conflicts) > 0 {
+		return deployerr.New(deployerr.Config, "environment_conflict", errors.Newf("variables set in the environment conflict with --environment, unset them: %s", strings.Join(conflicts, "; ")))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(vars)) {
+		if err := os.Setenv(key, vars[key]); err != nil {
+			return errors.Wrapf(err, "os.Setenv(%q)", key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package environments
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+)
+
+const validFile = `
+environments:
+  tst:
+    project: app-tst
+    instance: tst-instance
+    database: app
+    allowDrop: true
+  stg:
+    project: app-stg
+    instance: shared-instance
+    database: app
+    allowDrop: true
+  prd:
+    project: app-prd
+    instance: prd-instance
+    database: app
+`
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), DefaultFile)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "valid", content: validFile},
+		{name: "empty", content: "environments: {}\n", wantErr: true},
+		{name: "missing database", content: "environments:\n  tst:\n    project: p\n    instance: i\n", wantErr: true},
+		{name: "unknown field", content: "environments:\n  tst:\n    project: p\n    instance: i\n    database: d\n    region: us\n", wantErr: true},
+		{name: "comma in name", content: "environments:\n  tst,stg:\n    project: p\n    instance: i\n    database: d\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Load(writeFile(t, tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFile_Vars(t *testing.T) {
+	t.Parallel()
+
+	f, err := Load(writeFile(t, validFile))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	got, err := f.Vars("prd")
+	if err != nil {
+		t.Fatalf("Vars() error = %v", err)
+	}
+	want := map[string]string{
+		"GOOGLE_CLOUD_SPANNER_PROJECT":       "app-prd",
+		"GOOGLE_CLOUD_SPANNER_INSTANCE_ID":   "prd-instance",
+		"GOOGLE_CLOUD_SPANNER_DATABASE_NAME": "app",
+		"_APP_ENV":                           "prd",
+		"_DB_DROP_ENV_WHITELIST":             "stg,tst",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Vars() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Vars()[%s] = %q, want %q", k, got[k], v)
+		}
+	}
+
+	if _, err := f.Vars("dev"); err == nil {
+		t.Error("Vars() expected error for unknown environment")
+	}
+}
+
+func TestFile_Apply(t *testing.T) { //nolint:paralleltest // uses t.Setenv
+	f, err := Load(writeFile(t, validFile))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for _, key := range []string{"GOOGLE_CLOUD_SPANNER_PROJECT", "GOOGLE_CLOUD_SPANNER_INSTANCE_ID", "_APP_ENV", "_DB_DROP_ENV_WHITELIST"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	// already set to the environment's value, which is not a conflict
+	t.Setenv("GOOGLE_CLOUD_SPANNER_DATABASE_NAME", "app")
+
+	if err := f.Apply("stg"); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	for key, want := range map[string]string{
+		"GOOGLE_CLOUD_SPANNER_PROJECT":       "app-stg",
+		"GOOGLE_CLOUD_SPANNER_INSTANCE_ID":   "shared-instance",
+		"GOOGLE_CLOUD_SPANNER_DATABASE_NAME": "app",
+		"_APP_ENV":                           "stg",
+		"_DB_DROP_ENV_WHITELIST":             "stg,tst",
+	} {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestFile_Apply_conflict(t *testing.T) { //nolint:paralleltest // uses t.Setenv
+	f, err := Load(writeFile(t, validFile))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for _, key := range []string{"GOOGLE_CLOUD_SPANNER_INSTANCE_ID", "GOOGLE_CLOUD_SPANNER_DATABASE_NAME", "_APP_ENV", "_DB_DROP_ENV_WHITELIST"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	// left over from a prd shell
+	t.Setenv("GOOGLE_CLOUD_SPANNER_PROJECT", "app-prd")
+
+	err = f.Apply("tst")
+	if err == nil {
+		t.Fatal("Apply() expected error for a conflicting variable")
+	}
+	if category, _ := deployerr.Classify(err); category != deployerr.Config {
+		t.Errorf("Classify() category = %s, want %s", category, deployerr.Config)
+	}
+	for _, key := range []string{"_APP_ENV", "_DB_DROP_ENV_WHITELIST", "GOOGLE_CLOUD_SPANNER_DATABASE_NAME"} {
+		if v, ok := os.LookupEnv(key); ok {
+			t.Errorf("%s = %q, want unset after a conflict", key, v)
+		}
+	}
+}
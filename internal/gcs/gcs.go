@@ -0,0 +1,128 @@
+// Package gcs uploads and downloads artifacts in Cloud Storage.
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/errors/v5"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+)
+
+// Upload writes data to the object name under uri, which is gs://<bucket> optionally followed by
+// a path prefix, and returns the gs:// URI of the object
+func Upload(ctx context.Context, uri, name, contentType string, data []byte) (string, error) {
+	bucket, prefix, err := ParseURI(uri)
+	if err != nil {
+		return "", err
+	}
+
+	svc, err := storage.NewService(ctx, option.WithTelemetryDisabled())
+	if err != nil {
+		return "", errors.Wrap(err, "storage.NewService()")
+	}
+
+	object := name
+	if prefix != "" {
+		object = prefix + "/" + name
+	}
+
+	if _, err := svc.Objects.Insert(bucket, &storage.Object{Name: object, ContentType: contentType}).
+		Media(bytes.NewReader(data)).
+		Context(ctx).
+		Do(); err != nil {
+		return "", errors.Wrapf(err, "storage.ObjectsInsertCall.Do(): gs://%s/%s", bucket, object)
+	}
+
+	return "gs://" + bucket + "/" + object, nil
+}
+
+// List returns the names, relative to the prefix, of the objects under uri, which is gs://<bucket>
+// optionally followed by a path prefix. Objects in nested prefixes are not included.
+func List(ctx context.Context, uri string) ([]string, error) {
+	bucket, prefix, err := ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	svc, err := storage.NewService(ctx, option.WithTelemetryDisabled())
+	if err != nil {
+		return nil, errors.Wrap(err, "storage.NewService()")
+	}
+
+	var names []string
+	if err := svc.Objects.List(bucket).Prefix(prefix).Delimiter("/").Pages(ctx, func(objects *storage.Objects) error {
+		for _, o := range objects.Items {
+			if name := strings.TrimPrefix(o.Name, prefix); name != "" {
+				names = append(names, name)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "storage.ObjectsListCall.Pages(): %s", uri)
+	}
+
+	return names, nil
+}
+
+// Download returns the contents of the object name under uri
+func Download(ctx context.Context, uri, name string) ([]byte, error) {
+	bucket, prefix, err := ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	svc, err := storage.NewService(ctx, option.WithTelemetryDisabled())
+	if err != nil {
+		return nil, errors.Wrap(err, "storage.NewService()")
+	}
+
+	object := name
+	if prefix != "" {
+		object = prefix + "/" + name
+	}
+
+	resp, err := svc.Objects.Get(bucket, object).Context(ctx).Download()
+	if err != nil {
+		return nil, errors.Wrapf(err, "storage.ObjectsGetCall.Download(): gs://%s/%s", bucket, object)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "io.ReadAll(): gs://%s/%s", bucket, object)
+	}
+
+	return data, nil
+}
+
+// IsNotFound reports whether err is the error of a missing object or bucket
+func IsNotFound(err error) bool {
+	var apiErr *googleapi.Error
+
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// ParseURI splits gs://<bucket>/<prefix> into the bucket and the prefix without surrounding slashes
+func ParseURI(uri string) (bucket, prefix string, err error) {
+	rest, ok := strings.CutPrefix(uri, "gs://")
+	if !ok {
+		return "", "", errors.Newf("invalid Cloud Storage URI %q: must start with gs://", uri)
+	}
+
+	bucket, prefix, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", errors.Newf("invalid Cloud Storage URI %q: missing bucket", uri)
+	}
+
+	return bucket, strings.Trim(prefix, "/"), nil
+}
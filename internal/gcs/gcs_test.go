@@ -0,0 +1,33 @@
+package gcs
+
+import "testing"
+
+func TestParseURI(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		uri        string
+		wantBucket string
+		wantPrefix string
+		wantErr    bool
+	}{
+		{uri: "gs://releases", wantBucket: "releases"},
+		{uri: "gs://releases/", wantBucket: "releases"},
+		{uri: "gs://releases/db/reports/", wantBucket: "releases", wantPrefix: "db/reports"},
+		{uri: "releases/db", wantErr: true},
+		{uri: "gs:///db", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			t.Parallel()
+
+			bucket, prefix, err := ParseURI(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseURI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if bucket != tt.wantBucket || prefix != tt.wantPrefix {
+				t.Errorf("ParseURI() = (%q, %q), want (%q, %q)", bucket, prefix, tt.wantBucket, tt.wantPrefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Package heartbeat logs periodic progress lines during long-running operations so that
+// build systems watching for output do not consider the process hung.
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// FlagName is the name of the persistent flag holding the heartbeat interval
+const FlagName = "heartbeat-interval"
+
+// DefaultInterval is the default time between heartbeat lines
+const DefaultInterval = 30 * time.Second
+
+// Interval returns the heartbeat interval configured on the command
+func Interval(cmd *cobra.Command) (time.Duration, error) {
+	interval, err := cmd.Flags().GetDuration(FlagName)
+	if err != nil {
+		return 0, errors.Wrapf(err, "cobra.Command.Flags().GetDuration(%q)", FlagName)
+	}
+
+	return interval, nil
+}
+
+// Start logs a heartbeat line with the elapsed time and operation metadata every interval until
+// the returned stop function is called. keyvals are alternating keys and values describing the
+// operation. Values are formatted on every line, so a [*Status] reports the current step of the
+// operation. An interval of zero or less disables the heartbeat.
+func Start(ctx context.Context, interval time.Duration, operation string, keyvals ...any) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				log.Printf("heartbeat: %s still running, elapsed %s%s\n", operation, time.Since(start).Round(time.Second), formatKeyvals(keyvals))
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// Status is a heartbeat value describing the current step of an operation. It is safe for concurrent use.
+type Status struct {
+	mu     sync.Mutex
+	status string
+}
+
+// Set sets the current step
+func (s *Status) Set(status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = status
+}
+
+// String implements fmt.Stringer
+func (s *Status) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.status
+}
+
+func formatKeyvals(keyvals []any) string {
+	if len(keyvals) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 < len(keyvals) {
+			pairs = append(pairs, fmt.Sprintf("%v=%v", keyvals[i], keyvals[i+1]))
+		} else {
+			pairs = append(pairs, fmt.Sprint(keyvals[i]))
+		}
+	}
+
+	return " (" + strings.Join(pairs, ", ") + ")"
+}
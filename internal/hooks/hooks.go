@@ -0,0 +1,278 @@
+// Package hooks runs the commands and HTTP calls a repository declares for points in a deployment, such as before
+// migrations, so teams can extend the pipeline without changing deployment-tools.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/yamlfile"
+	"github.com/go-playground/errors/v5"
+)
+
+// Event names a point where hooks run
+type Event string
+
+const (
+	// PreMigrate runs before bootstrap applies any migration. A failure aborts the bootstrap.
+	PreMigrate Event = "preMigrate"
+
+	// PostMigrate runs after bootstrap applied the migrations successfully
+	PostMigrate Event = "postMigrate"
+
+	// MigrateFailed runs after bootstrap failed, with the error in DEPLOY_HOOK_ERROR
+	MigrateFailed Event = "migrateFailed"
+
+	// PreSeed runs before seed loads any file. A failure aborts the seed.
+	PreSeed Event = "preSeed"
+
+	// PostSeed runs after seed loaded every file successfully
+	PostSeed Event = "postSeed"
+)
+
+// defaultTimeout bounds a hook that does not set its own timeout
+const defaultTimeout = 5 * time.Minute
+
+// Config lists the hooks of each event
+type Config struct {
+	Hooks map[Event][]Hook `yaml:"hooks"`
+
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Hook runs a command or makes an HTTP call. The context of the event is passed to commands as DEPLOY_HOOK_*
+// environment variables and to HTTP calls as a JSON object with those variables. ${VAR} references in the
+// command, URL and headers are expanded from the environment and the event context.
+type Hook struct {
+	Name string `yaml:"name"`
+	// Command is the program and its arguments. It is not run through a shell.
+	Command []string `yaml:"command"`
+	HTTP    *HTTP    `yaml:"http"`
+	// Timeout defaults to 5m
+	Timeout time.Duration `yaml:"timeout"`
+	// ContinueOnError logs a failure of the hook instead of failing the command
+	ContinueOnError bool `yaml:"continueOnError"`
+}
+
+// HTTP is a call to a URL
+type HTTP struct {
+	URL string `yaml:"url"`
+	// Method defaults to POST
+	Method  string            `yaml:"method"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// Load reads the hooks configured in the file at path
+func Load(path string) (*Config, error) {
+	c := &Config{}
+	if _, err := yamlfile.Load(path, "hooks file", c); err != nil {
+		return nil, errors.Wrap(err, "yamlfile.Load()")
+	}
+
+	return c, nil
+}
+
+// Validate checks that every hook belongs to a known event and either runs a command or calls a URL
+func (c *Config) Validate() error {
+	var problems yamlfile.Problems
+	for _, event := range slices.Sorted(maps.Keys(c.Hooks)) {
+		switch event {
+		case PreMigrate, PostMigrate, MigrateFailed, PreSeed, PostSeed:
+		default:
+			problems.Add("unknown event %q, must be one of %s, %s, %s, %s or %s", event, PreMigrate, PostMigrate, MigrateFailed, PreSeed, PostSeed)
+		}
+
+		for i, h := range c.Hooks[event] {
+			name := fmt.Sprintf("%s hook %d", event, i+1)
+			switch {
+			case len(h.Command) > 0 && h.HTTP != nil:
+				problems.Add("%s must set only one of command and http", name)
+			case len(h.Command) == 0 && h.HTTP == nil:
+				problems.Add("%s must set command or http", name)
+			case h.HTTP != nil && h.HTTP.URL == "":
+				problems.Add("%s has no url", name)
+			}
+			if h.Timeout < 0 {
+				problems.Add("%s has a negative timeout", name)
+			}
+		}
+	}
+
+	return problems.Err()
+}
+
+// SetOutput sets where the output of hook commands is written, os.Stdout and os.Stderr by default. A nil Config
+// is left unchanged.
+func (c *Config) SetOutput(stdout, stderr io.Writer) {
+	if c == nil {
+		return
+	}
+
+	c.stdout, c.stderr = stdout, stderr
+}
+
+// Run runs the hooks of event in order, passing them vars and DEPLOY_HOOK_EVENT as the event context. It stops at
+// the first hook that fails unless the hook continues on error. A nil Config runs nothing.
+func (c *Config) Run(ctx context.Context, event Event, vars map[string]string) error {
+	if c == nil {
+		return nil
+	}
+
+	env := map[string]string{"DEPLOY_HOOK_EVENT": string(event)}
+	maps.Copy(env, vars)
+
+	for i, h := range c.Hooks[event] {
+		name := h.Name
+		if name == "" {
+			name = fmt.Sprintf("%d", i+1)
+		}
+		log.Printf("Running %s hook %s\n", event, name)
+
+		if err := h.run(ctx, env, c.stdout, c.stderr); err != nil {
+			if h.ContinueOnError {
+				log.Printf("WARNING: %s hook %s failed, continuing: %v", event, name, err)
+
+				continue
+			}
+
+			return errors.Wrapf(err, "%s hook %s failed", event, name)
+		}
+	}
+
+	return nil
+}
+
+func (h *Hook) run(ctx context.Context, env map[string]string, stdout, stderr io.Writer) error {
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if h.HTTP != nil {
+		return h.HTTP.call(ctx, env)
+	}
+
+	args := make([]string, len(h.Command))
+	for i, arg := range h.Command {
+		var err error
+		if args[i], err = expand(arg, env); err != nil {
+			return err
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...) //nolint:gosec // hooks are declared by the repository being deployed
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	if stderr != nil {
+		cmd.Stderr = stderr
+	}
+	cmd.Env = os.Environ()
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		cmd.Env = append(cmd.Env, k+"="+env[k])
+	}
+
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "exec.Cmd.Run(): %s", args[0])
+	}
+
+	return nil
+}
+
+func (h *HTTP) call(ctx context.Context, env map[string]string) error {
+	rawURL, err := expand(h.URL, env)
+	if err != nil {
+		return err
+	}
+	// errors report the configured URL, as the expanded one may contain secrets
+	if _, err := url.ParseRequestURI(rawURL); err != nil {
+		return errors.Wrapf(withoutURL(err), "url.ParseRequestURI(): %s", h.URL)
+	}
+
+	body, err := json.Marshal(env)
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal()")
+	}
+
+	method := h.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(withoutURL(err), "http.NewRequestWithContext(): %s", h.URL)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.Headers {
+		expanded, err := expand(v, env)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(k, expanded)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(withoutURL(err), "http.Client.Do(): %s %s", method, h.URL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return errors.Newf("%s %s returned %s: %s", method, h.URL, resp.Status, bytes.TrimSpace(b))
+	}
+
+	return nil
+}
+
+// withoutURL returns the underlying error of a *url.Error, whose message includes the expanded URL
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Unwrap()
+	}
+
+	return err
+}
+
+// expand replaces ${VAR} references in s with the event context or the environment. Other uses of $ are kept,
+// so commands can pass shell scripts. Referencing an unset variable is an error, so a missing secret never
+// produces a malformed request.
+func expand(s string, env map[string]string) (string, error) {
+	var unset []string
+	expanded := regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`).ReplaceAllStringFunc(s, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		if v, ok := env[name]; ok {
+			return v
+		}
+		v, ok := os.LookupEnv(name)
+		if !ok && !slices.Contains(unset, name) {
+			unset = append(unset, name)
+		}
+
+		return v
+	})
+	if len(unset) > 0 {
+		return "", errors.Newf("environment variables referenced by the hook are not set: %s", strings.Join(unset, ", "))
+	}
+
+	return expanded, nil
+}
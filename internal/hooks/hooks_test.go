@@ -0,0 +1,185 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			content: `
+hooks:
+  preMigrate:
+    - name: pause workers
+      command: ["./scripts/pause-workers.sh"]
+      timeout: 2m
+  postMigrate:
+    - http:
+        url: https://hooks.example.com/migrated
+        headers:
+          Authorization: Bearer ${HOOK_TOKEN}
+      continueOnError: true
+`,
+		},
+		{name: "unknown event", content: "hooks:\n  postDeploy:\n    - command: [true]\n", wantErr: true},
+		{name: "command and http", content: "hooks:\n  preSeed:\n    - command: [true]\n      http:\n        url: https://example.com\n", wantErr: true},
+		{name: "neither", content: "hooks:\n  preSeed:\n    - name: empty\n", wantErr: true},
+		{name: "http without url", content: "hooks:\n  preSeed:\n    - http:\n        method: GET\n", wantErr: true},
+		{name: "unknown field", content: "hooks:\n  preSeed:\n    - cmd: [true]\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "hooks.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("os.WriteFile() error = %v", err)
+			}
+
+			c, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && c.Hooks[PreMigrate][0].Timeout != 2*time.Minute {
+				t.Errorf("Hook.Timeout = %s, want 2m", c.Hooks[PreMigrate][0].Timeout)
+			}
+		})
+	}
+}
+
+func TestConfig_Run_command(t *testing.T) {
+	t.Parallel()
+
+	out := filepath.Join(t.TempDir(), "out")
+	c := &Config{Hooks: map[Event][]Hook{
+		PreMigrate: {{Command: []string{"sh", "-c", `echo "$DEPLOY_HOOK_EVENT $DEPLOY_HOOK_DATABASE" > "$1"`, "sh", out}}},
+	}}
+
+	if err := c.Run(context.Background(), PreMigrate, map[string]string{"DEPLOY_HOOK_DATABASE": "orders"}); err != nil {
+		t.Fatalf("Config.Run() error = %v", err)
+	}
+
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if got, want := strings.TrimSpace(string(b)), "preMigrate orders"; got != want {
+		t.Errorf("hook wrote %q, want %q", got, want)
+	}
+}
+
+func TestConfig_SetOutput(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{Hooks: map[Event][]Hook{
+		PostSeed: {{Command: []string{"sh", "-c", "echo out; echo err >&2"}}},
+	}}
+	var stdout, stderr bytes.Buffer
+	c.SetOutput(&stdout, &stderr)
+
+	if err := c.Run(context.Background(), PostSeed, nil); err != nil {
+		t.Fatalf("Config.Run() error = %v", err)
+	}
+	if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Errorf("hook output = %q, %q, want %q, %q", stdout.String(), stderr.String(), "out\n", "err\n")
+	}
+}
+
+func TestConfig_Run_failure(t *testing.T) {
+	t.Parallel()
+
+	failing := Hook{Name: "fails", Command: []string{"false"}}
+	tests := []struct {
+		name    string
+		hook    Hook
+		wantErr bool
+	}{
+		{name: "fails", hook: failing, wantErr: true},
+		{name: "continue on error", hook: Hook{Command: failing.Command, ContinueOnError: true}},
+		{name: "unset variable", hook: Hook{Command: []string{"echo", "${DEPLOY_HOOK_TEST_UNSET}"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &Config{Hooks: map[Event][]Hook{PostSeed: {tt.hook}}}
+			if err := c.Run(context.Background(), PostSeed, nil); (err != nil) != tt.wantErr {
+				t.Errorf("Config.Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Run_http(t *testing.T) {
+	t.Parallel()
+
+	var got map[string]string
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Config{Hooks: map[Event][]Hook{
+		MigrateFailed: {{HTTP: &HTTP{URL: srv.URL + "/failed", Headers: map[string]string{"Authorization": "Bearer ${DEPLOY_HOOK_DATABASE}"}}}},
+	}}
+	if err := c.Run(context.Background(), MigrateFailed, map[string]string{"DEPLOY_HOOK_DATABASE": "orders", "DEPLOY_HOOK_ERROR": "boom"}); err != nil {
+		t.Fatalf("Config.Run() error = %v", err)
+	}
+
+	if got["DEPLOY_HOOK_EVENT"] != "migrateFailed" || got["DEPLOY_HOOK_ERROR"] != "boom" {
+		t.Errorf("hook body = %v, want the event context", got)
+	}
+	if auth != "Bearer orders" {
+		t.Errorf("Authorization = %q, want %q", auth, "Bearer orders")
+	}
+}
+
+func TestConfig_Run_httpErrorHidesSecrets(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	closedURL := srv.URL
+	srv.Close()
+
+	vars := map[string]string{"DEPLOY_HOOK_TOKEN": "s3cr3t-token"}
+	for _, hookURL := range []string{closedURL + "/hook?token=${DEPLOY_HOOK_TOKEN}", "http://[::1/${DEPLOY_HOOK_TOKEN}"} {
+		c := &Config{Hooks: map[Event][]Hook{PostSeed: {{HTTP: &HTTP{URL: hookURL}}}}}
+
+		err := c.Run(context.Background(), PostSeed, vars)
+		if err == nil {
+			t.Fatalf("Config.Run() expected error for %s", hookURL)
+		}
+		if strings.Contains(err.Error(), "s3cr3t-token") {
+			t.Errorf("Config.Run() error = %v, must not contain the expanded URL", err)
+		}
+	}
+}
+
+func TestConfig_Run_nil(t *testing.T) {
+	t.Parallel()
+
+	var c *Config
+	if err := c.Run(context.Background(), PreMigrate, nil); err != nil {
+		t.Errorf("Config.Run() error = %v", err)
+	}
+}
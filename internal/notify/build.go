@@ -0,0 +1,17 @@
+package notify
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// BuildLogURL returns the Cloud Console page of a Cloud Build build and its logs. Builds without a location run
+// in the global region.
+func BuildLogURL(projectID, location, buildID string) string {
+	if location == "" {
+		location = "global"
+	}
+
+	return fmt.Sprintf("https://console.cloud.google.com/cloud-build/builds;region=%s/%s?project=%s",
+		url.PathEscape(location), url.PathEscape(buildID), url.QueryEscape(projectID))
+}
@@ -0,0 +1,25 @@
+package notify
+
+import "testing"
+
+func TestBuildLogURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		location string
+		want     string
+	}{
+		{name: "regional", location: "us-central1", want: "https://console.cloud.google.com/cloud-build/builds;region=us-central1/b-123?project=my-project"},
+		{name: "global", want: "https://console.cloud.google.com/cloud-build/builds;region=global/b-123?project=my-project"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := BuildLogURL("my-project", tt.location, "b-123"); got != tt.want {
+				t.Errorf("BuildLogURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
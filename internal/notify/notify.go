@@ -0,0 +1,58 @@
+// Package notify posts short messages to a chat incoming webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/errors/v5"
+)
+
+// Post sends text to an incoming webhook URL as {"text": "..."}, the payload accepted by both
+// Slack and Google Chat incoming webhooks
+func Post(ctx context.Context, url, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal()")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "http.NewRequestWithContext()")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "http.Client.Do()")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return errors.Newf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+
+	return nil
+}
+
+// Send posts text to url if it is set, logging instead of failing when the post fails,
+// so a broken webhook never fails the operation being reported
+func Send(ctx context.Context, url, text string) {
+	if url == "" {
+		return
+	}
+
+	if err := Post(ctx, url, text); err != nil {
+		log.Printf("failed to send notification: %v", err)
+	}
+}
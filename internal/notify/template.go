@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-playground/errors/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// Event names the point in an operation a notification is sent at
+type Event string
+
+const (
+	// EventStarted is sent when an operation starts
+	EventStarted Event = "started"
+	// EventSucceeded is sent when an operation succeeds
+	EventSucceeded Event = "succeeded"
+	// EventFailed is sent when an operation fails
+	EventFailed Event = "failed"
+)
+
+// Message is the data available to notification templates
+type Message struct {
+	Event       Event
+	Command     string
+	Environment string
+	Database    string
+	BuildID     string
+	Duration    time.Duration
+	Error       string
+}
+
+// Templates holds the notification templates of each event. Events without a template use the default message.
+type Templates map[Event]*template.Template
+
+// LoadTemplates reads Go text templates keyed by event from a YAML file, e.g.
+//
+//	failed: ":rotating_light: {{.Command}} of {{.Database}} failed after {{.Duration}}: {{.Error}}"
+//
+// Each template is rendered with an empty [Message], so a misspelled field fails here rather than when the
+// notification is sent.
+func LoadTemplates(path string) (Templates, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "os.ReadFile()")
+	}
+
+	var raw map[Event]string
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, errors.Wrapf(err, "yaml.Unmarshal(): %s", path)
+	}
+
+	templates := make(Templates, len(raw))
+	for event, text := range raw {
+		switch event {
+		case EventStarted, EventSucceeded, EventFailed:
+		default:
+			return nil, errors.Newf("unknown notification event %q in %s, expected one of %s, %s or %s", event, path, EventStarted, EventSucceeded, EventFailed)
+		}
+
+		t, err := template.New(string(event)).Parse(text)
+		if err != nil {
+			return nil, errors.Wrapf(err, "template.Template.Parse(): %s template in %s", event, path)
+		}
+		if err := t.Execute(io.Discard, Message{Event: event}); err != nil {
+			return nil, errors.Wrapf(err, "template.Template.Execute(): %s template in %s", event, path)
+		}
+		templates[event] = t
+	}
+
+	return templates, nil
+}
+
+// Render returns the notification text of m. The default message is used when there is no template for the
+// event or the template fails to render.
+func (t Templates) Render(m Message) string {
+	tmpl, ok := t[m.Event]
+	if !ok {
+		return defaultText(m)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, m); err != nil {
+		log.Printf("failed to render %s notification template, using the default message: %v", m.Event, err)
+
+		return defaultText(m)
+	}
+
+	return strings.TrimSpace(buf.String())
+}
+
+func defaultText(m Message) string {
+	target := strings.TrimSpace(fmt.Sprintf("%s database %s", m.Environment, m.Database))
+	name := m.Command
+	if name == "" {
+		name = "operation"
+	}
+	capitalized := strings.ToUpper(name[:1]) + name[1:]
+
+	switch m.Event {
+	case EventStarted:
+		return fmt.Sprintf("%s of %s started", capitalized, target)
+	case EventSucceeded:
+		return fmt.Sprintf("%s of %s succeeded in %s", capitalized, target, m.Duration)
+	case EventFailed:
+		return fmt.Sprintf("%s of %s failed after %s: %s", capitalized, target, m.Duration, m.Error)
+	default:
+		return fmt.Sprintf("%s of %s: %s", capitalized, target, m.Event)
+	}
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestTemplates_Render(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "templates.yaml")
+	content := `failed: ":rotating_light: {{.Command}} of {{.Database}} in {{.Environment}} failed: {{.Error}}"
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	templates, err := LoadTemplates(path)
+	if err != nil {
+		t.Fatalf("LoadTemplates() error = %v", err)
+	}
+
+	// LoadTemplates rejects this template, but Render must still fall back if one fails
+	broken := Templates{EventSucceeded: template.Must(template.New("succeeded").Parse("{{.Missing}}"))}
+
+	msg := Message{Command: "reseed", Environment: "tst", Database: "app", Duration: 90 * time.Second, Error: "boom"}
+	tests := []struct {
+		name      string
+		templates Templates
+		event     Event
+		want      string
+	}{
+		{name: "default started", event: EventStarted, want: "Reseed of tst database app started"},
+		{name: "default succeeded", event: EventSucceeded, want: "Reseed of tst database app succeeded in 1m30s"},
+		{name: "custom failed", templates: templates, event: EventFailed, want: ":rotating_light: reseed of app in tst failed: boom"},
+		{name: "broken template falls back to default", templates: broken, event: EventSucceeded, want: "Reseed of tst database app succeeded in 1m30s"},
+		{name: "event without template", templates: templates, event: EventStarted, want: "Reseed of tst database app started"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := msg
+			m.Event = tt.event
+			if got := tt.templates.Render(m); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTemplates_unknownEvent(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "templates.yaml")
+	if err := os.WriteFile(path, []byte(`finished: "done"`), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	if _, err := LoadTemplates(path); err == nil {
+		t.Error("LoadTemplates() error = nil, want error for unknown event")
+	}
+}
+
+func TestLoadTemplates_unknownField(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "templates.yaml")
+	if err := os.WriteFile(path, []byte(`failed: "{{.Databse}} failed"`), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	if _, err := LoadTemplates(path); err == nil {
+		t.Error("LoadTemplates() error = nil, want error for a misspelled field")
+	}
+}
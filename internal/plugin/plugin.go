@@ -0,0 +1,107 @@
+// Package plugin finds and runs deployment-tools-<name> executables on PATH, so team-specific subcommands can live
+// in their own repositories.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/errors/v5"
+	"github.com/sethvargo/go-envconfig"
+)
+
+// Prefix is the file name prefix of plugin executables
+const Prefix = "deployment-tools-"
+
+// Plugin is an executable found on PATH
+type Plugin struct {
+	Name string
+	Path string
+}
+
+// Context is the configuration deployment-tools resolved from its environment, written to the plugin's stdin
+// as JSON so plugins do not have to repeat the lookup
+type Context struct {
+	Plugin          string `json:"plugin"`
+	SpannerProject  string `env:"GOOGLE_CLOUD_SPANNER_PROJECT"       json:"spannerProject"`
+	SpannerInstance string `env:"GOOGLE_CLOUD_SPANNER_INSTANCE_ID"   json:"spannerInstance"`
+	SpannerDatabase string `env:"GOOGLE_CLOUD_SPANNER_DATABASE_NAME" json:"spannerDatabase"`
+	AppEnv          string `env:"_APP_ENV"                           json:"appEnv"`
+	CommitSHA       string `env:"COMMIT_SHA"                         json:"commitSha"`
+	EmulatorHost    string `env:"SPANNER_EMULATOR_HOST"              json:"emulatorHost"`
+}
+
+// Find returns the plugin executable for name, if one is on PATH
+func Find(name string) (Plugin, bool) {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsRune(name, filepath.Separator) {
+		return Plugin{}, false
+	}
+
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		return Plugin{}, false
+	}
+
+	return Plugin{Name: name, Path: path}, true
+}
+
+// List returns the plugins on PATH, sorted by name. When several directories contain a plugin with the same
+// name, the first one on PATH is returned, as it is the one Find runs.
+func List() []Plugin {
+	var plugins []Plugin
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), Prefix)
+			if !ok || name == "" || seen[name] || entry.IsDir() {
+				continue
+			}
+			if info, err := entry.Info(); err != nil || info.Mode()&0o111 == 0 {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, Plugin{Name: name, Path: filepath.Join(dir, entry.Name())})
+		}
+	}
+
+	slices.SortFunc(plugins, func(a, b Plugin) int { return strings.Compare(a.Name, b.Name) })
+
+	return plugins
+}
+
+// Run executes the plugin with args, writing the resolved Context to its stdin. The plugin inherits the
+// environment, including credentials, and its output is written to stdout and stderr.
+func (p Plugin) Run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	var pctx Context
+	if err := envconfig.Process(ctx, &pctx); err != nil {
+		return errors.Wrap(err, "envconfig.Process()")
+	}
+	pctx.Plugin = p.Name
+
+	input, err := json.Marshal(pctx)
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal()")
+	}
+
+	cmd := exec.CommandContext(ctx, p.Path, args...) //nolint:gosec // plugins are executables the user installed on PATH
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "plugin %s", p.Name)
+	}
+
+	return nil
+}
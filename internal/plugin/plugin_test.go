@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePlugin(t *testing.T, dir, name, script string, mode os.FileMode) string {
+	t.Helper()
+
+	path := filepath.Join(dir, Prefix+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), mode); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	return path
+}
+
+func TestFind(t *testing.T) { //nolint:paralleltest // uses t.Setenv
+	dir := t.TempDir()
+	path := writePlugin(t, dir, "hello", "true", 0o755)
+	t.Setenv("PATH", dir)
+
+	tests := []struct {
+		name   string
+		arg    string
+		wantOK bool
+	}{
+		{name: "found", arg: "hello", wantOK: true},
+		{name: "missing", arg: "goodbye"},
+		{name: "empty", arg: ""},
+		{name: "flag", arg: "--hello"},
+		{name: "path", arg: "../hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Find(tt.arg)
+			if ok != tt.wantOK {
+				t.Fatalf("Find() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (got.Name != tt.arg || got.Path != path) {
+				t.Errorf("Find() = %+v, want name %q path %q", got, tt.arg, path)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) { //nolint:paralleltest // uses t.Setenv
+	first, second := t.TempDir(), t.TempDir()
+	zeta := writePlugin(t, first, "zeta", "true", 0o755)
+	alpha := writePlugin(t, first, "alpha", "true", 0o755)
+	writePlugin(t, first, "notexec", "true", 0o644)
+	writePlugin(t, second, "alpha", "true", 0o755)
+	if err := os.WriteFile(filepath.Join(second, "unrelated"), nil, 0o755); err != nil { //nolint:gosec // test fixture
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	t.Setenv("PATH", first+string(filepath.ListSeparator)+second)
+
+	got := List()
+	want := []Plugin{{Name: "alpha", Path: alpha}, {Name: "zeta", Path: zeta}}
+	if len(got) != len(want) {
+		t.Fatalf("List() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("List()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPlugin_Run(t *testing.T) { //nolint:paralleltest // uses t.Setenv
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	t.Setenv("OUT", out)
+	t.Setenv("_APP_ENV", "dev")
+	t.Setenv("GOOGLE_CLOUD_SPANNER_DATABASE_NAME", "app")
+
+	p := Plugin{Name: "hello", Path: writePlugin(t, dir, "hello", `cat > "$OUT.json"; echo "$@" > "$OUT.args"; echo done`, 0o755)}
+	var stdout bytes.Buffer
+	if err := p.Run(context.Background(), []string{"a", "--b"}, &stdout, io.Discard); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got, want := stdout.String(), "done\n"; got != want {
+		t.Errorf("plugin stdout = %q, want %q", got, want)
+	}
+
+	args, err := os.ReadFile(out + ".args")
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if got, want := string(args), "a --b\n"; got != want {
+		t.Errorf("plugin args = %q, want %q", got, want)
+	}
+
+	input, err := os.ReadFile(out + ".json")
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	var got Context
+	if err := json.Unmarshal(input, &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if got.Plugin != "hello" || got.AppEnv != "dev" || got.SpannerDatabase != "app" {
+		t.Errorf("plugin context = %+v", got)
+	}
+
+	failing := Plugin{Name: "fail", Path: writePlugin(t, dir, "fail", "exit 3", 0o755)}
+	if err := failing.Run(context.Background(), nil, io.Discard, io.Discard); err == nil {
+		t.Error("Run() expected error for failing plugin")
+	}
+}
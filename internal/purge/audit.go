@@ -0,0 +1,91 @@
+package purge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	adminpb "cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/go-playground/errors/v5"
+)
+
+// AuditTable records every purge that changed data
+const AuditTable = "PurgeAudit"
+
+// EnsureAuditTable creates the audit table in the database dbStr if it does not exist
+func EnsureAuditTable(ctx context.Context, client *spanner.Client, admin *database.DatabaseAdminClient, dbStr string) error {
+	stmt := spanner.Statement{
+		SQL:    `SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = '' AND TABLE_NAME = @table`,
+		Params: map[string]any{"table": AuditTable},
+	}
+
+	var count int64
+	if err := client.Single().Query(ctx, stmt).Do(func(r *spanner.Row) error {
+		return r.Column(0, &count)
+	}); err != nil {
+		return errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+	if count > 0 {
+		return nil
+	}
+
+	op, err := admin.UpdateDatabaseDdl(ctx, &adminpb.UpdateDatabaseDdlRequest{
+		Database: dbStr,
+		Statements: []string{
+			`CREATE TABLE ` + AuditTable + ` (
+				SubjectHash STRING(64) NOT NULL,
+				PurgedAt TIMESTAMP NOT NULL OPTIONS (allow_commit_timestamp=true),
+				PlanChecksum STRING(64) NOT NULL,
+				Steps JSON NOT NULL,
+				Error STRING(MAX),
+				Environment STRING(MAX) NOT NULL,
+			) PRIMARY KEY (SubjectHash, PurgedAt)`,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.UpdateDatabaseDdl()")
+	}
+	if err := op.Wait(ctx); err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "database.UpdateDatabaseDdlOperation.Wait()")
+	}
+
+	return nil
+}
+
+// Record writes the audit row of a purge, including the error it failed with, if any. The subject is only recorded
+// by its [SubjectHash].
+func Record(ctx context.Context, client *spanner.Client, p *Plan, subjectHash, environment string, results []StepResult, purgeErr error) error {
+	steps, err := json.Marshal(results)
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal()")
+	}
+
+	var errMsg spanner.NullString
+	if purgeErr != nil {
+		errMsg = spanner.NullString{StringVal: errors.Cause(purgeErr).Error(), Valid: true}
+	}
+
+	if _, err := client.Apply(ctx, []*spanner.Mutation{spanner.Insert(AuditTable,
+		[]string{"SubjectHash", "PurgedAt", "PlanChecksum", "Steps", "Error", "Environment"},
+		[]any{subjectHash, spanner.CommitTimestamp, p.Checksum, spanner.NullJSON{Value: json.RawMessage(steps), Valid: true}, errMsg, environment},
+	)}); err != nil {
+		return errors.Wrapf(err, "purge could not be recorded in %s", AuditTable)
+	}
+
+	return nil
+}
+
+// SubjectHash returns the hex encoded HMAC-SHA256 of a subject ID, which identifies the subject in the audit table.
+// Without the secret key, subject IDs can not be recovered from the audit table by hashing every possible ID, but
+// with it a later request for the same subject can be matched to its audit rows.
+func SubjectHash(key []byte, subjectID string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(subjectID))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
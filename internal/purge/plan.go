@@ -0,0 +1,165 @@
+// Package purge deletes or anonymizes the rows of one data subject across tables, following a declarative plan.
+package purge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/cccteam/deployment-tools/internal/yamlfile"
+	"github.com/cloudspannerecosystem/memefish"
+	"github.com/cloudspannerecosystem/memefish/ast"
+	"github.com/go-playground/errors/v5"
+)
+
+// Action is what a step does to the rows it matches
+type Action string
+
+const (
+	// Delete deletes the matching rows. Rows interleaved with ON DELETE CASCADE are deleted with them.
+	Delete Action = "delete"
+
+	// Anonymize overwrites columns of the matching rows
+	Anonymize Action = "anonymize"
+)
+
+// subjectParam is the query parameter holding the subject ID in every step's where clause
+const subjectParam = "@subjectId"
+
+// Plan lists the steps of a purge, executed in order. Steps for child tables must come before the steps that
+// remove the rows they reference.
+type Plan struct {
+	// SubjectType is the Spanner type of @subjectId, e.g. INT64. Defaults to STRING.
+	SubjectType string `yaml:"subjectType"`
+	Steps       []Step `yaml:"steps"`
+	// Checksum is the SHA-256 of the plan file, recorded in the audit table
+	Checksum string `yaml:"-"`
+}
+
+// Step deletes or anonymizes the rows of a table matching a where clause
+type Step struct {
+	Table string `yaml:"table"`
+	// Where is a SQL condition that references @subjectId, e.g. UserId = @subjectId
+	Where  string `yaml:"where"`
+	Action Action `yaml:"action"`
+	// Set holds the values anonymize writes, in the CSV format read by seed. A null value writes NULL.
+	Set map[string]*string `yaml:"set"`
+}
+
+// Load reads the purge plan at path and records its checksum for the audit trail
+func Load(path string) (*Plan, error) {
+	p := &Plan{}
+	b, err := yamlfile.Load(path, "purge plan", p)
+	if err != nil {
+		return nil, errors.Wrap(err, "yamlfile.Load()")
+	}
+	sum := sha256.Sum256(b)
+	p.Checksum = hex.EncodeToString(sum[:])
+
+	return p, nil
+}
+
+// Validate checks that every step names a table, is limited to the subject by its where clause and has a valid action.
+// A plan without a subject type purges STRING subjects.
+func (p *Plan) Validate() error {
+	if p.SubjectType == "" {
+		p.SubjectType = "STRING"
+	}
+
+	var problems yamlfile.Problems
+	if len(p.Steps) == 0 {
+		problems.Add("plan has no steps")
+	}
+
+	for i, s := range p.Steps {
+		step := fmt.Sprintf("step %d", i+1)
+		if s.Table != "" {
+			step += " (" + s.Table + ")"
+		}
+
+		if s.Table == "" {
+			problems.Add("%s has no table", step)
+		}
+		if problem := whereProblem(s.Where); problem != "" {
+			problems.Add("%s %s", step, problem)
+		}
+		switch s.Action {
+		case Delete:
+			if len(s.Set) > 0 {
+				problems.Add("%s deletes rows and must not set columns", step)
+			}
+		case Anonymize:
+			if len(s.Set) == 0 {
+				problems.Add("%s anonymizes rows and must set at least one column", step)
+			}
+		default:
+			problems.Add("%s has action %q, must be %s or %s", step, s.Action, Delete, Anonymize)
+		}
+	}
+
+	return problems.Err()
+}
+
+// whereProblem parses a step's where clause and describes why it may match rows of other subjects, or returns an
+// empty string. The clause must compare a column with @subjectId, or take a column from a subquery that does, and
+// may only narrow that down with AND.
+func whereProblem(where string) string {
+	if strings.TrimSpace(where) == "" {
+		return "must have a where clause that references " + subjectParam
+	}
+
+	expr, err := memefish.ParseExpr("where", where)
+	if err != nil {
+		return "has an invalid where clause: " + strings.TrimSpace(err.Error())
+	}
+	if !limitedToSubject(expr) {
+		return fmt.Sprintf("must have a where clause that compares a column with %s, e.g. UserId = %s, and only combines it with AND", subjectParam, subjectParam)
+	}
+
+	return ""
+}
+
+// limitedToSubject reports whether expr only holds for rows of the subject
+func limitedToSubject(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return limitedToSubject(e.Expr)
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case ast.OpAnd:
+			return limitedToSubject(e.Left) || limitedToSubject(e.Right)
+		case ast.OpEqual:
+			return (isColumn(e.Left) && isSubject(e.Right)) || (isSubject(e.Left) && isColumn(e.Right))
+		}
+	case *ast.InExpr:
+		cond, ok := e.Right.(*ast.SubQueryInCondition)
+		if !ok || e.Not || !isColumn(e.Left) {
+			return false
+		}
+		query := cond.Query
+		if q, ok := query.(*ast.Query); ok && q.With == nil && len(q.PipeOperators) == 0 {
+			query = q.Query
+		}
+		if s, ok := query.(*ast.Select); ok && s.Where != nil {
+			return limitedToSubject(s.Where.Expr)
+		}
+	}
+
+	return false
+}
+
+func isColumn(expr ast.Expr) bool {
+	switch expr.(type) {
+	case *ast.Ident, *ast.Path:
+		return true
+	default:
+		return false
+	}
+}
+
+func isSubject(expr ast.Expr) bool {
+	p, ok := expr.(*ast.Param)
+
+	return ok && "@"+p.Name == subjectParam
+}
@@ -0,0 +1,152 @@
+package purge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		content         string
+		wantSubjectType string
+		wantSteps       int
+		wantErr         bool
+	}{
+		{
+			name: "valid",
+			content: `
+subjectType: INT64
+steps:
+  - table: Orders
+    where: UserId = @subjectId
+    action: anonymize
+    set:
+      Address: redacted
+      Phone: null
+  - table: Users
+    where: UserId = @subjectId
+    action: delete
+`,
+			wantSubjectType: "INT64",
+			wantSteps:       2,
+		},
+		{
+			name: "default subject type",
+			content: `
+steps:
+  - table: Users
+    where: Email = @subjectId
+    action: delete
+`,
+			wantSubjectType: "STRING",
+			wantSteps:       1,
+		},
+		{name: "no steps", content: "subjectType: STRING\n", wantErr: true},
+		{
+			name: "where without subject",
+			content: `
+steps:
+  - table: Users
+    where: "TRUE"
+    action: delete
+`,
+			wantErr: true,
+		},
+		{
+			name: "anonymize without set",
+			content: `
+steps:
+  - table: Users
+    where: UserId = @subjectId
+    action: anonymize
+`,
+			wantErr: true,
+		},
+		{
+			name: "delete with set",
+			content: `
+steps:
+  - table: Users
+    where: UserId = @subjectId
+    action: delete
+    set:
+      Name: x
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown action",
+			content: `
+steps:
+  - table: Users
+    where: UserId = @subjectId
+    action: truncate
+`,
+			wantErr: true,
+		},
+		{name: "unknown field", content: "stepz: []\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "purge.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("os.WriteFile() error = %v", err)
+			}
+
+			p, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if p.SubjectType != tt.wantSubjectType {
+				t.Errorf("Plan.SubjectType = %q, want %q", p.SubjectType, tt.wantSubjectType)
+			}
+			if len(p.Steps) != tt.wantSteps {
+				t.Errorf("len(Plan.Steps) = %d, want %d", len(p.Steps), tt.wantSteps)
+			}
+			if len(p.Checksum) != 64 {
+				t.Errorf("Plan.Checksum = %q, want a SHA-256 hex digest", p.Checksum)
+			}
+		})
+	}
+}
+
+func Test_whereProblem(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		where   string
+		wantErr bool
+	}{
+		{where: "UserId = @subjectId"},
+		{where: "@subjectId = u.UserId"},
+		{where: "(UserId = @subjectId) AND Deleted = FALSE"},
+		{where: "OrderId IN (SELECT OrderId FROM Orders WHERE UserId = @subjectId)"},
+		{where: "", wantErr: true},
+		{where: "TRUE", wantErr: true},
+		{where: "UserId = @subjectId OR TRUE", wantErr: true},
+		{where: "UserId != @subjectId", wantErr: true},
+		{where: "UserId = @subjectId2", wantErr: true},
+		{where: "'@subjectId' = Name", wantErr: true},
+		{where: "OrderId NOT IN (SELECT OrderId FROM Orders WHERE UserId = @subjectId)", wantErr: true},
+		{where: "OrderId IN (SELECT OrderId FROM Orders)", wantErr: true},
+		{where: "UserId = @subjectId AND (", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			t.Parallel()
+
+			if got := whereProblem(tt.where); (got != "") != tt.wantErr {
+				t.Errorf("whereProblem() = %q, wantErr %v", got, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package purge
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/cccteam/deployment-tools/internal/spannercsv"
+	"github.com/go-playground/errors/v5"
+)
+
+// StepResult is the number of rows a step matched and deleted or anonymized
+type StepResult struct {
+	Table  string `json:"table"`
+	Action Action `json:"action"`
+	Rows   int64  `json:"rows"`
+}
+
+// Run executes the steps of the plan for subjectID in order and returns the rows changed by each step, including
+// the step that failed. Each step reads the keys of the rows it matches, then deletes or anonymizes them in
+// transactions of at most batchSize rows. With dryRun the matching rows are only counted.
+func Run(ctx context.Context, client *spanner.Client, p *Plan, subjectID string, batchSize int, dryRun bool) ([]StepResult, error) {
+	subject, err := spannercsv.ParseValue(p.SubjectType, subjectID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "subject ID is not a valid %s", p.SubjectType)
+	}
+
+	results := make([]StepResult, 0, len(p.Steps))
+	for i, s := range p.Steps {
+		rows, err := runStep(ctx, client, s, subject, batchSize, dryRun)
+		results = append(results, StepResult{Table: s.Table, Action: s.Action, Rows: rows})
+		if err != nil {
+			return results, errors.Wrapf(err, "step %d (%s)", i+1, s.Table)
+		}
+	}
+
+	return results, nil
+}
+
+func runStep(ctx context.Context, client *spanner.Client, s Step, subject any, batchSize int, dryRun bool) (int64, error) {
+	keyColumns, err := primaryKey(ctx, client, s.Table)
+	if err != nil {
+		return 0, err
+	}
+
+	keys, err := matchingKeys(ctx, client, s, keyColumns, subject)
+	if err != nil {
+		return 0, err
+	}
+	if dryRun {
+		return int64(len(keys)), nil
+	}
+
+	var columns []string
+	var values []any
+	if s.Action == Anonymize {
+		columns, values, err = setValues(ctx, client, s)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var rows int64
+	for batch := range slices.Chunk(keys, batchSize) {
+		mutations := make([]*spanner.Mutation, 0, len(batch))
+		for _, key := range batch {
+			switch s.Action {
+			case Delete:
+				mutations = append(mutations, spanner.Delete(s.Table, key))
+			case Anonymize:
+				mutations = append(mutations, spanner.Update(s.Table, slices.Concat(keyColumns, columns), slices.Concat([]any(key), values)))
+			}
+		}
+		if _, err := client.Apply(ctx, mutations); err != nil {
+			return rows, errors.Wrap(err, "spanner.Client.Apply()")
+		}
+		rows += int64(len(batch))
+	}
+
+	return rows, nil
+}
+
+// primaryKey returns the primary key columns of table, in key order
+func primaryKey(ctx context.Context, client *spanner.Client, table string) ([]string, error) {
+	stmt := spanner.Statement{
+		SQL: `SELECT COLUMN_NAME
+			FROM INFORMATION_SCHEMA.INDEX_COLUMNS
+			WHERE TABLE_SCHEMA = '' AND TABLE_NAME = @table AND INDEX_TYPE = 'PRIMARY_KEY'
+			ORDER BY ORDINAL_POSITION`,
+		Params: map[string]any{"table": table},
+	}
+
+	var columns []string
+	if err := client.Single().Query(ctx, stmt).Do(func(r *spanner.Row) error {
+		var name string
+		if err := r.Column(0, &name); err != nil {
+			return errors.Wrap(err, "spanner.Row.Column()")
+		}
+		columns = append(columns, name)
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	if len(columns) == 0 {
+		return nil, errors.Newf("table %s does not exist", table)
+	}
+
+	return columns, nil
+}
+
+// matchingKeys returns the keys of the rows matching the step's where clause
+func matchingKeys(ctx context.Context, client *spanner.Client, s Step, keyColumns []string, subject any) ([]spanner.Key, error) {
+	stmt := spanner.Statement{
+		SQL:    "SELECT `" + strings.Join(keyColumns, "`, `") + "` FROM `" + s.Table + "` WHERE " + s.Where,
+		Params: map[string]any{strings.TrimPrefix(subjectParam, "@"): subject},
+	}
+
+	var keys []spanner.Key
+	if err := client.Single().Query(ctx, stmt).Do(func(r *spanner.Row) error {
+		key := make(spanner.Key, r.Size())
+		for i := range key {
+			var v spanner.GenericColumnValue
+			if err := r.Column(i, &v); err != nil {
+				return errors.Wrap(err, "spanner.Row.Column()")
+			}
+			part, err := keyPart(v)
+			if err != nil {
+				return errors.Wrapf(err, "key column %s", keyColumns[i])
+			}
+			key[i] = part
+		}
+		keys = append(keys, key)
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	return keys, nil
+}
+
+// keyPart decodes a key column value into a type spanner.Key accepts. Key columns may be NULL.
+func keyPart(v spanner.GenericColumnValue) (any, error) {
+	switch code := v.Type.GetCode(); code {
+	case sppb.TypeCode_INT64:
+		return decode[spanner.NullInt64](v)
+	case sppb.TypeCode_FLOAT64:
+		return decode[spanner.NullFloat64](v)
+	case sppb.TypeCode_FLOAT32:
+		return decode[spanner.NullFloat32](v)
+	case sppb.TypeCode_BOOL:
+		return decode[spanner.NullBool](v)
+	case sppb.TypeCode_STRING:
+		return decode[spanner.NullString](v)
+	case sppb.TypeCode_BYTES:
+		return decode[[]byte](v)
+	case sppb.TypeCode_DATE:
+		return decode[spanner.NullDate](v)
+	case sppb.TypeCode_TIMESTAMP:
+		return decode[spanner.NullTime](v)
+	case sppb.TypeCode_NUMERIC:
+		return decode[spanner.NullNumeric](v)
+	default:
+		return nil, errors.Newf("unsupported key type %s", code)
+	}
+}
+
+func decode[T any](v spanner.GenericColumnValue) (any, error) {
+	var part T
+	if err := v.Decode(&part); err != nil {
+		return nil, errors.Wrap(err, "spanner.GenericColumnValue.Decode()")
+	}
+
+	return part, nil
+}
+
+// setValues returns the columns an anonymize step writes and their values, in column name order
+func setValues(ctx context.Context, client *spanner.Client, s Step) ([]string, []any, error) {
+	columns := make([]string, 0, len(s.Set))
+	for column := range s.Set {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+
+	types, err := spannercsv.ColumnTypes(ctx, client, s.Table, columns)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "spannercsv.ColumnTypes()")
+	}
+
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		v := s.Set[column]
+		if v == nil {
+			continue
+		}
+		values[i], err = spannercsv.ParseValue(types[i], *v)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "set %s", column)
+		}
+	}
+
+	return columns, values, nil
+}
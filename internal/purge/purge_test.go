@@ -0,0 +1,66 @@
+package purge
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func Test_keyPart(t *testing.T) {
+	t.Parallel()
+
+	value := func(code sppb.TypeCode, v *structpb.Value) spanner.GenericColumnValue {
+		return spanner.GenericColumnValue{Type: &sppb.Type{Code: code}, Value: v}
+	}
+
+	tests := []struct {
+		name    string
+		v       spanner.GenericColumnValue
+		want    any
+		wantErr bool
+	}{
+		{name: "int64", v: value(sppb.TypeCode_INT64, structpb.NewStringValue("42")), want: spanner.NullInt64{Int64: 42, Valid: true}},
+		{name: "null int64", v: value(sppb.TypeCode_INT64, structpb.NewNullValue()), want: spanner.NullInt64{}},
+		{name: "string", v: value(sppb.TypeCode_STRING, structpb.NewStringValue("u-1")), want: spanner.NullString{StringVal: "u-1", Valid: true}},
+		{name: "bytes", v: value(sppb.TypeCode_BYTES, structpb.NewStringValue("aGk=")), want: []byte("hi")},
+		{name: "bool", v: value(sppb.TypeCode_BOOL, structpb.NewBoolValue(true)), want: spanner.NullBool{Bool: true, Valid: true}},
+		{name: "date", v: value(sppb.TypeCode_DATE, structpb.NewStringValue("2024-02-29")), want: spanner.NullDate{Date: civil.Date{Year: 2024, Month: time.February, Day: 29}, Valid: true}},
+		{
+			name: "timestamp",
+			v:    value(sppb.TypeCode_TIMESTAMP, structpb.NewStringValue("2024-01-02T03:04:05Z")),
+			want: spanner.NullTime{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true},
+		},
+		{name: "json", v: value(sppb.TypeCode_JSON, structpb.NewStringValue("{}")), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := keyPart(tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("keyPart() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keyPart() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubjectHash(t *testing.T) {
+	t.Parallel()
+
+	// HMAC-SHA256 of "42" keyed with "key"
+	want := "f2991b7ce981d0b5adc5e6a0f31acaeb407bfc21354bbcc31a0c43eaffa83d65"
+	if got := SubjectHash([]byte("key"), "42"); got != want {
+		t.Errorf("SubjectHash() = %q, want %q", got, want)
+	}
+	if SubjectHash([]byte("other key"), "42") == want {
+		t.Error("SubjectHash() is the same for a different key")
+	}
+}
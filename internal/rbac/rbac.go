@@ -0,0 +1,202 @@
+// Package rbac loads role and permission fixture data from a canonical YAML definition and
+// reconciles it with the RBAC tables of a Spanner database.
+package rbac
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/cccteam/deployment-tools/internal/yamlfile"
+	"github.com/go-playground/errors/v5"
+)
+
+// Definition is the canonical definition of roles and the permissions granted to them
+type Definition struct {
+	Permissions []Permission `yaml:"permissions"`
+	Roles       []Role       `yaml:"roles"`
+}
+
+// Permission is a permission that can be granted to roles
+type Permission struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+}
+
+// Role is a named set of permissions
+type Role struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Permissions []string `yaml:"permissions"`
+}
+
+// State is the set of roles, permissions and grants, either defined or stored in the database
+type State struct {
+	// Permissions maps permission names to descriptions
+	Permissions map[string]string
+	// Roles maps role names to descriptions
+	Roles map[string]string
+	// Grants maps role names to the names of the permissions granted to them
+	Grants map[string][]string
+}
+
+// Load reads the RBAC definition at path
+func Load(path string) (*Definition, error) {
+	d := &Definition{}
+	if _, err := yamlfile.Load(path, "RBAC definition", d); err != nil {
+		return nil, errors.Wrap(err, "yamlfile.Load()")
+	}
+
+	return d, nil
+}
+
+// Validate checks that names are unique and that roles only reference defined permissions
+func (d *Definition) Validate() error {
+	var problems yamlfile.Problems
+
+	permissions := make(map[string]bool, len(d.Permissions))
+	for _, p := range d.Permissions {
+		switch {
+		case p.Name == "":
+			problems.Add("permission with an empty name")
+		case permissions[p.Name]:
+			problems.Add("permission %q is defined more than once", p.Name)
+		}
+		permissions[p.Name] = true
+	}
+
+	roles := make(map[string]bool, len(d.Roles))
+	for _, r := range d.Roles {
+		switch {
+		case r.Name == "":
+			problems.Add("role with an empty name")
+		case roles[r.Name]:
+			problems.Add("role %q is defined more than once", r.Name)
+		}
+		roles[r.Name] = true
+
+		granted := make(map[string]bool, len(r.Permissions))
+		for _, p := range r.Permissions {
+			switch {
+			case !permissions[p]:
+				problems.Add("role %q references undefined permission %q", r.Name, p)
+			case granted[p]:
+				problems.Add("role %q lists permission %q more than once", r.Name, p)
+			}
+			granted[p] = true
+		}
+	}
+
+	return problems.Err()
+}
+
+// State returns the state described by the definition
+func (d *Definition) State() State {
+	s := State{
+		Permissions: make(map[string]string, len(d.Permissions)),
+		Roles:       make(map[string]string, len(d.Roles)),
+		Grants:      make(map[string][]string, len(d.Roles)),
+	}
+	for _, p := range d.Permissions {
+		s.Permissions[p.Name] = p.Description
+	}
+	for _, r := range d.Roles {
+		s.Roles[r.Name] = r.Description
+		s.Grants[r.Name] = slices.Sorted(slices.Values(r.Permissions))
+	}
+
+	return s
+}
+
+// Op is the kind of change made to a row
+type Op string
+
+// Ops
+const (
+	Add    Op = "+"
+	Remove Op = "-"
+	Update Op = "~"
+)
+
+// Kind is the kind of object changed
+type Kind string
+
+// Kinds
+const (
+	KindPermission Kind = "permission"
+	KindRole       Kind = "role"
+	KindGrant      Kind = "grant"
+)
+
+// Change is a single difference between two states
+type Change struct {
+	Op   Op
+	Kind Kind
+	// Name is the permission or role name. For grants it is the permission granted to Role.
+	Name string
+	// Role is the role a grant belongs to
+	Role string
+	// Description is the new description of an added or updated permission or role
+	Description string
+}
+
+// String implements fmt.Stringer
+func (c Change) String() string {
+	if c.Kind == KindGrant {
+		return fmt.Sprintf("%s role %s: permission %s", c.Op, c.Role, c.Name)
+	}
+
+	return fmt.Sprintf("%s %s %s", c.Op, c.Kind, c.Name)
+}
+
+// Diff returns the changes that turn current into desired, in a stable order:
+// permissions, then roles, then grants grouped by role
+func Diff(current, desired State) []Change {
+	var changes []Change
+	changes = append(changes, diffNamed(KindPermission, current.Permissions, desired.Permissions)...)
+	changes = append(changes, diffNamed(KindRole, current.Roles, desired.Roles)...)
+
+	roles := slices.Sorted(maps.Keys(current.Grants))
+	for role := range desired.Grants {
+		if _, ok := current.Grants[role]; !ok {
+			roles = append(roles, role)
+		}
+	}
+	slices.Sort(roles)
+
+	for _, role := range roles {
+		have, want := current.Grants[role], desired.Grants[role]
+		for _, p := range want {
+			if !slices.Contains(have, p) {
+				changes = append(changes, Change{Op: Add, Kind: KindGrant, Name: p, Role: role})
+			}
+		}
+		for _, p := range have {
+			if !slices.Contains(want, p) {
+				changes = append(changes, Change{Op: Remove, Kind: KindGrant, Name: p, Role: role})
+			}
+		}
+	}
+
+	return changes
+}
+
+func diffNamed(kind Kind, current, desired map[string]string) []Change {
+	var changes []Change
+	for _, name := range slices.Sorted(maps.Keys(desired)) {
+		description, ok := current[name]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Op: Add, Kind: kind, Name: name, Description: desired[name]})
+		case description != desired[name]:
+			changes = append(changes, Change{Op: Update, Kind: kind, Name: name, Description: desired[name]})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(current)) {
+		if _, ok := desired[name]; !ok {
+			changes = append(changes, Change{Op: Remove, Kind: kind, Name: name})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,121 @@
+package rbac
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDefinition_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		def      Definition
+		wantErrs []string
+	}{
+		{
+			name: "valid",
+			def: Definition{
+				Permissions: []Permission{{Name: "users.read"}, {Name: "users.write"}},
+				Roles:       []Role{{Name: "admin", Permissions: []string{"users.read", "users.write"}}, {Name: "viewer"}},
+			},
+		},
+		{
+			name: "all problems reported",
+			def: Definition{
+				Permissions: []Permission{{Name: "users.read"}, {Name: "users.read"}, {}},
+				Roles: []Role{
+					{Name: "admin", Permissions: []string{"users.read", "users.delete", "users.read"}},
+					{Name: "admin"},
+				},
+			},
+			wantErrs: []string{
+				`permission "users.read" is defined more than once`,
+				"permission with an empty name",
+				`role "admin" references undefined permission "users.delete"`,
+				`role "admin" lists permission "users.read" more than once`,
+				`role "admin" is defined more than once`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.def.Validate()
+			if (err != nil) != (len(tt.wantErrs) > 0) {
+				t.Fatalf("Definition.Validate() error = %v, want errors %q", err, tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Definition.Validate() error = %v, missing %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	empty := State{Permissions: map[string]string{}, Roles: map[string]string{}, Grants: map[string][]string{}}
+
+	tests := []struct {
+		name    string
+		current State
+		desired State
+		want    []string
+	}{
+		{
+			name:    "no changes",
+			current: State{Permissions: map[string]string{"a": "A"}, Roles: map[string]string{"r": "R"}, Grants: map[string][]string{"r": {"a"}}},
+			desired: State{Permissions: map[string]string{"a": "A"}, Roles: map[string]string{"r": "R"}, Grants: map[string][]string{"r": {"a"}}},
+		},
+		{
+			name:    "seed empty database",
+			current: empty,
+			desired: State{
+				Permissions: map[string]string{"b": "B", "a": "A"},
+				Roles:       map[string]string{"r": "R"},
+				Grants:      map[string][]string{"r": {"a", "b"}},
+			},
+			want: []string{"+ permission a", "+ permission b", "+ role r", "+ role r: permission a", "+ role r: permission b"},
+		},
+		{
+			name: "updates and removals",
+			current: State{
+				Permissions: map[string]string{"a": "A", "old": "Old"},
+				Roles:       map[string]string{"r": "R", "gone": "Gone"},
+				Grants:      map[string][]string{"r": {"a", "old"}, "gone": {"a"}},
+			},
+			desired: State{
+				Permissions: map[string]string{"a": "A changed"},
+				Roles:       map[string]string{"r": "R", "new": "New"},
+				Grants:      map[string][]string{"r": {"a"}, "new": {"a"}},
+			},
+			want: []string{
+				"~ permission a",
+				"- permission old",
+				"+ role new",
+				"- role gone",
+				"- role gone: permission a",
+				"+ role new: permission a",
+				"- role r: permission old",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+			for _, c := range Diff(tt.current, tt.desired) {
+				got = append(got, c.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
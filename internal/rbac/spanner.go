@@ -0,0 +1,119 @@
+package rbac
+
+import (
+	"context"
+	"slices"
+
+	"cloud.google.com/go/spanner"
+	"github.com/go-playground/errors/v5"
+)
+
+// Tables names the RBAC tables. The tables must have the following columns, with the primary key listed first:
+//   - Permissions: Name STRING, Description STRING
+//   - Roles: Name STRING, Description STRING
+//   - RolePermissions: RoleName STRING, PermissionName STRING (primary key of both columns)
+type Tables struct {
+	Permissions     string
+	Roles           string
+	RolePermissions string
+}
+
+// Reader is implemented by spanner read-only and read-write transactions
+type Reader interface {
+	Read(ctx context.Context, table string, keys spanner.KeySet, columns []string) *spanner.RowIterator
+}
+
+// Read returns the state stored in the database
+func Read(ctx context.Context, txn Reader, tables Tables) (State, error) {
+	s := State{
+		Permissions: make(map[string]string),
+		Roles:       make(map[string]string),
+		Grants:      make(map[string][]string),
+	}
+
+	if err := readPairs(ctx, txn, tables.Permissions, []string{"Name", "Description"}, func(name, description string) {
+		s.Permissions[name] = description
+	}); err != nil {
+		return State{}, err
+	}
+	if err := readPairs(ctx, txn, tables.Roles, []string{"Name", "Description"}, func(name, description string) {
+		s.Roles[name] = description
+	}); err != nil {
+		return State{}, err
+	}
+	if err := readPairs(ctx, txn, tables.RolePermissions, []string{"RoleName", "PermissionName"}, func(role, permission string) {
+		s.Grants[role] = append(s.Grants[role], permission)
+	}); err != nil {
+		return State{}, err
+	}
+	for role := range s.Grants {
+		slices.Sort(s.Grants[role])
+	}
+
+	return s, nil
+}
+
+func readPairs(ctx context.Context, txn Reader, table string, columns []string, fn func(a, b string)) error {
+	if err := txn.Read(ctx, table, spanner.AllKeys(), columns).Do(func(r *spanner.Row) error {
+		var a, b spanner.NullString
+		if err := r.Columns(&a, &b); err != nil {
+			return errors.Wrap(err, "spanner.Row.Columns()")
+		}
+		fn(a.StringVal, b.StringVal)
+
+		return nil
+	}); err != nil {
+		return errors.Wrapf(err, "spanner.RowIterator.Do(): %s", table)
+	}
+
+	return nil
+}
+
+// Mutations returns the mutations that apply the changes
+func Mutations(changes []Change, tables Tables) []*spanner.Mutation {
+	mutations := make([]*spanner.Mutation, 0, len(changes))
+	for _, c := range changes {
+		switch c.Kind {
+		case KindPermission, KindRole:
+			table := tables.Permissions
+			if c.Kind == KindRole {
+				table = tables.Roles
+			}
+			if c.Op == Remove {
+				mutations = append(mutations, spanner.Delete(table, spanner.Key{c.Name}))
+			} else {
+				mutations = append(mutations, spanner.InsertOrUpdate(table, []string{"Name", "Description"}, []any{c.Name, c.Description}))
+			}
+		case KindGrant:
+			if c.Op == Remove {
+				mutations = append(mutations, spanner.Delete(tables.RolePermissions, spanner.Key{c.Role, c.Name}))
+			} else {
+				mutations = append(mutations, spanner.InsertOrUpdate(tables.RolePermissions, []string{"RoleName", "PermissionName"}, []any{c.Role, c.Name}))
+			}
+		}
+	}
+
+	return mutations
+}
+
+// Apply reconciles the database with the definition in a single read-write transaction and returns the changes made
+func Apply(ctx context.Context, client *spanner.Client, tables Tables, d *Definition) ([]Change, error) {
+	var changes []Change
+	if _, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		current, err := Read(ctx, txn, tables)
+		if err != nil {
+			return err
+		}
+
+		changes = Diff(current, d.State())
+		if err := txn.BufferWrite(Mutations(changes, tables)); err != nil {
+			return errors.Wrap(err, "spanner.ReadWriteTransaction.BufferWrite()")
+		}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "spanner.Client.ReadWriteTransaction()")
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,101 @@
+package redact
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	t.Parallel()
+
+	env := []string{
+		"GITHUB_TOKEN=s3cr3t-t0ken-value",
+		"DB_PASSWORD=hunter22hunter22",
+		"SHORT_TOKEN=abc",
+		"GOOGLE_CLOUD_SPANNER_DATABASE_NAME=app-database",
+		"MALFORMED",
+	}
+	r, err := New(env, []string{`sk-[0-9a-z]{6}`, ""})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "env value", in: "cloning with s3cr3t-t0ken-value", want: "cloning with [REDACTED]"},
+		{name: "env value twice", in: "hunter22hunter22 hunter22hunter22", want: "[REDACTED] [REDACTED]"},
+		{name: "short value", in: "abc", want: "abc"},
+		{name: "not a secret name", in: "database app-database", want: "database app-database"},
+		{name: "oauth token", in: "token ya29.a0AfH6SMB-x_y", want: "token [REDACTED]"},
+		{name: "bearer", in: "Authorization: Bearer abc.def-ghi", want: "Authorization: [REDACTED]"},
+		{name: "url password", in: "postgres://user:pw@host/db", want: "postgres[REDACTED]host/db"},
+		{name: "custom pattern", in: "key sk-abc123 used", want: "key [REDACTED] used"},
+		{name: "nothing to redact", in: "Applied migration 42", want: "Applied migration 42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := r.Redact(tt.in); got != tt.want {
+				t.Errorf("Redact() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_invalidPattern(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(nil, []string{"("}); err == nil {
+		t.Error("New() expected error for invalid pattern")
+	}
+}
+
+func TestRedactor_Writer(t *testing.T) {
+	t.Parallel()
+
+	r, err := New([]string{"API_KEY=0123456789abcdef"}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	logger := log.New(r.Writer(&buf), "", 0)
+	logger.Printf("calling with key %s", "0123456789abcdef")
+
+	if got, want := buf.String(), "calling with key [REDACTED]\n"; got != want {
+		t.Errorf("log output = %q, want %q", got, want)
+	}
+}
+
+func TestRedactor_LineWriter(t *testing.T) {
+	t.Parallel()
+
+	r, err := New([]string{"API_KEY=0123456789abcdef"}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	w := r.LineWriter(&buf)
+	// the secret is split across writes, as it can be when a pipe is copied
+	for _, chunk := range []string{"key 01234", "56789abcdef\nsecond ", "line 0123456789", "abcdef"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if got, want := buf.String(), "key [REDACTED]\n"; got != want {
+		t.Errorf("output before Flush() = %q, want %q", got, want)
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got, want := buf.String(), "key [REDACTED]\nsecond line [REDACTED]"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,130 @@
+// Package releasetrain reads the release cadence of a repository, e.g. Tuesdays at 10:00, and computes its
+// release windows, so production deploys can be held to them.
+package releasetrain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cccteam/deployment-tools/internal/yamlfile"
+	"github.com/go-playground/errors/v5"
+)
+
+// Schedule lists the weekly release windows in a time zone
+type Schedule struct {
+	// TimeZone is an IANA time zone name, e.g. America/Denver. Defaults to UTC.
+	TimeZone string   `yaml:"timeZone"`
+	Windows  []Window `yaml:"windows"`
+
+	location *time.Location
+}
+
+// Window is a weekly release window
+type Window struct {
+	Day string `yaml:"day"`
+	// Start is the local time the window opens, e.g. 10:00
+	Start    string        `yaml:"start"`
+	Duration time.Duration `yaml:"duration"`
+}
+
+// Slot is a release window at a point in time
+type Slot struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains reports whether t is inside the slot
+func (s Slot) Contains(t time.Time) bool {
+	return !t.Before(s.Start) && t.Before(s.End)
+}
+
+func (s Slot) String() string {
+	return fmt.Sprintf("%s to %s", s.Start.Format("Mon 2006-01-02 15:04 MST"), s.End.Format("Mon 2006-01-02 15:04 MST"))
+}
+
+// Load reads the release train schedule at path
+func Load(path string) (*Schedule, error) {
+	s := &Schedule{}
+	if _, err := yamlfile.Load(path, "release train schedule", s); err != nil {
+		return nil, errors.Wrap(err, "yamlfile.Load()")
+	}
+
+	return s, nil
+}
+
+// Validate checks the time zone and every window
+func (s *Schedule) Validate() error {
+	var problems yamlfile.Problems
+
+	location, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		problems.Add("unknown time zone %q", s.TimeZone)
+	}
+	s.location = location
+
+	if len(s.Windows) == 0 {
+		problems.Add("no windows defined")
+	}
+	for i, w := range s.Windows {
+		name := fmt.Sprintf("window %d", i+1)
+		if _, ok := weekday(w.Day); !ok {
+			problems.Add("%s: unknown day %q", name, w.Day)
+		}
+		if _, err := time.Parse("15:04", w.Start); err != nil {
+			problems.Add("%s: start %q is not a time of day like 10:00", name, w.Start)
+		}
+		if w.Duration <= 0 || w.Duration > 7*24*time.Hour {
+			problems.Add("%s: duration must be between 0 and 168h, got %s", name, w.Duration)
+		}
+	}
+
+	return problems.Err()
+}
+
+// Next returns the window open at now or, when none is, the next one to open
+func (s *Schedule) Next(now time.Time) Slot {
+	now = now.In(s.location)
+
+	var next Slot
+	// Windows may last up to a week, so the window open now may have started a week ago
+	for _, w := range s.Windows {
+		day, _ := weekday(w.Day)
+		start, _ := time.Parse("15:04", w.Start)
+		for offset := -7; offset <= 7; offset++ {
+			d := now.AddDate(0, 0, offset)
+			if d.Weekday() != day {
+				continue
+			}
+			opens := time.Date(d.Year(), d.Month(), d.Day(), start.Hour(), start.Minute(), 0, 0, s.location)
+			slot := Slot{Start: opens, End: opens.Add(w.Duration)}
+			if !slot.End.After(now) {
+				continue
+			}
+			// An open window wins over one that has not opened yet, otherwise the earliest start wins
+			switch {
+			case next.Start.IsZero():
+			case slot.Contains(now) != next.Contains(now):
+				if !slot.Contains(now) {
+					continue
+				}
+			case !slot.Start.Before(next.Start):
+				continue
+			}
+			next = slot
+		}
+	}
+
+	return next
+}
+
+// weekday parses a day name such as Tuesday or tue
+func weekday(name string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(name, d.String()) || strings.EqualFold(name, d.String()[:3]) {
+			return d, true
+		}
+	}
+
+	return 0, false
+}
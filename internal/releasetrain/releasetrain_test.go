@@ -0,0 +1,85 @@
+package releasetrain
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "valid", content: "timeZone: America/Denver\nwindows:\n  - day: Tuesday\n    start: \"10:00\"\n    duration: 2h\n"},
+		{name: "utc by default", content: "windows:\n  - day: thu\n    start: \"09:30\"\n    duration: 30m\n"},
+		{name: "no windows", content: "timeZone: UTC\n", wantErr: true},
+		{name: "unknown time zone", content: "timeZone: Mars/Olympus\nwindows:\n  - day: Tuesday\n    start: \"10:00\"\n    duration: 2h\n", wantErr: true},
+		{name: "unknown day", content: "windows:\n  - day: Funday\n    start: \"10:00\"\n    duration: 2h\n", wantErr: true},
+		{name: "bad start", content: "windows:\n  - day: Tuesday\n    start: 10am\n    duration: 2h\n", wantErr: true},
+		{name: "no duration", content: "windows:\n  - day: Tuesday\n    start: \"10:00\"\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "release-train.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("os.WriteFile() error = %v", err)
+			}
+
+			_, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	t.Parallel()
+
+	s := &Schedule{
+		TimeZone: "UTC",
+		Windows: []Window{
+			{Day: "Tuesday", Start: "10:00", Duration: 2 * time.Hour},
+			{Day: "thu", Start: "14:00", Duration: time.Hour},
+		},
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	// 2026-10-13 is a Tuesday
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantStart time.Time
+		wantOpen  bool
+	}{
+		{name: "before tuesday window", now: at(13, 9, 0), wantStart: at(13, 10, 0)},
+		{name: "inside tuesday window", now: at(13, 11, 30), wantStart: at(13, 10, 0), wantOpen: true},
+		{name: "window end is exclusive", now: at(13, 12, 0), wantStart: at(15, 14, 0)},
+		{name: "after thursday window", now: at(15, 16, 0), wantStart: at(20, 10, 0)},
+		{name: "sunday", now: at(18, 12, 0), wantStart: at(20, 10, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := s.Next(tt.now)
+			if !got.Start.Equal(tt.wantStart) {
+				t.Errorf("Next().Start = %s, want %s", got.Start, tt.wantStart)
+			}
+			if got.Contains(tt.now) != tt.wantOpen {
+				t.Errorf("Next().Contains(now) = %v, want %v", got.Contains(tt.now), tt.wantOpen)
+			}
+		})
+	}
+}
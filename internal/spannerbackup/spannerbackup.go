@@ -0,0 +1,77 @@
+// Package spannerbackup restores Spanner database backups.
+package spannerbackup
+
+import (
+	"context"
+	"fmt"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	adminpb "cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/go-playground/errors/v5"
+	"google.golang.org/api/iterator"
+)
+
+// Latest returns the most recent ready backup of the database dbStr (projects/<p>/instances/<i>/databases/<d>)
+// stored in the instance instanceStr (projects/<p>/instances/<i>)
+func Latest(ctx context.Context, admin *database.DatabaseAdminClient, instanceStr, dbStr string) (*adminpb.Backup, error) {
+	iter := admin.ListBackups(ctx, &adminpb.ListBackupsRequest{
+		Parent: instanceStr,
+		Filter: fmt.Sprintf("database:%q AND state:READY", dbStr),
+	})
+
+	var backups []*adminpb.Backup
+	for {
+		backup, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(deployerr.FromSpanner(err), "database.ListBackupsIterator.Next()")
+		}
+		backups = append(backups, backup)
+	}
+
+	backup := latest(backups)
+	if backup == nil {
+		return nil, errors.Newf("no ready backups found for %s", dbStr)
+	}
+
+	return backup, nil
+}
+
+// latest returns the backup with the most recent version time, which is the point in time the backup holds
+func latest(backups []*adminpb.Backup) *adminpb.Backup {
+	var newest *adminpb.Backup
+	for _, backup := range backups {
+		if newest == nil || backup.GetVersionTime().AsTime().After(newest.GetVersionTime().AsTime()) {
+			newest = backup
+		}
+	}
+
+	return newest
+}
+
+// StartRestore starts restoring backupName into the new database dbID in the instance instanceStr. The database
+// exists once the restore has started, so it must be dropped even if waiting for the returned operation fails.
+func StartRestore(ctx context.Context, admin *database.DatabaseAdminClient, instanceStr, dbID, backupName string) (*database.RestoreDatabaseOperation, error) {
+	op, err := admin.RestoreDatabase(ctx, &adminpb.RestoreDatabaseRequest{
+		Parent:     instanceStr,
+		DatabaseId: dbID,
+		Source:     &adminpb.RestoreDatabaseRequest_Backup{Backup: backupName},
+	})
+	if err != nil {
+		return nil, errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.RestoreDatabase()")
+	}
+
+	return op, nil
+}
+
+// Drop deletes the database dbStr
+func Drop(ctx context.Context, admin *database.DatabaseAdminClient, dbStr string) error {
+	if err := admin.DropDatabase(ctx, &adminpb.DropDatabaseRequest{Database: dbStr}); err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.DropDatabase()")
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package spannerbackup
+
+import (
+	"testing"
+	"time"
+
+	adminpb "cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func Test_latest(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	backup := func(name string, age time.Duration) *adminpb.Backup {
+		return &adminpb.Backup{Name: name, VersionTime: timestamppb.New(now.Add(-age))}
+	}
+
+	tests := []struct {
+		name    string
+		backups []*adminpb.Backup
+		want    string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:    "single",
+			backups: []*adminpb.Backup{backup("daily", time.Hour)},
+			want:    "daily",
+		},
+		{
+			name:    "newest version time wins regardless of order",
+			backups: []*adminpb.Backup{backup("old", 48*time.Hour), backup("new", time.Hour), backup("older", 72*time.Hour)},
+			want:    "new",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := latest(tt.backups).GetName(); got != tt.want {
+				t.Errorf("latest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
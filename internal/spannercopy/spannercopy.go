@@ -0,0 +1,241 @@
+// Package spannercopy creates Spanner databases from the schema and rows of a template database.
+package spannercopy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	adminpb "cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/go-playground/errors/v5"
+)
+
+// mutationLimit is the maximum number of mutations Spanner allows in a single transaction
+const mutationLimit = 80000
+
+// Table is a table to copy and the tables that must be copied before it
+type Table struct {
+	Name    string
+	Columns []string
+	// DependsOn lists the interleave parent and the tables referenced by foreign keys
+	DependsOn []string
+}
+
+// alterDatabase matches the ALTER DATABASE statements of a schema, capturing the clause after the database name
+var alterDatabase = regexp.MustCompile("(?is)^\\s*ALTER\\s+DATABASE\\s+(?:`[^`]+`|\\S+)\\s+(.*)$")
+
+// CreateDatabase creates the database dbID in the instance instanceStr (projects/<p>/instances/<i>) with the
+// schema of the database templateStr, including database options such as pinned optimizer options. An empty
+// templateStr creates an empty database.
+func CreateDatabase(ctx context.Context, admin *database.DatabaseAdminClient, instanceStr, dbID, templateStr string) error {
+	var ddl, options []string
+	if templateStr != "" {
+		resp, err := admin.GetDatabaseDdl(ctx, &adminpb.GetDatabaseDdlRequest{Database: templateStr})
+		if err != nil {
+			return errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.GetDatabaseDdl()")
+		}
+		ddl, options = splitDatabaseOptions(resp.GetStatements(), dbID)
+	}
+
+	op, err := admin.CreateDatabase(ctx, &adminpb.CreateDatabaseRequest{
+		Parent:          instanceStr,
+		CreateStatement: fmt.Sprintf("CREATE DATABASE `%s`", dbID),
+		ExtraStatements: ddl,
+	})
+	if err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.CreateDatabase()")
+	}
+	if _, err := op.Wait(ctx); err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "database.CreateDatabaseOperation.Wait()")
+	}
+
+	if len(options) > 0 {
+		ddlOp, err := admin.UpdateDatabaseDdl(ctx, &adminpb.UpdateDatabaseDdlRequest{
+			Database:   instanceStr + "/databases/" + dbID,
+			Statements: options,
+		})
+		if err != nil {
+			return errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.UpdateDatabaseDdl()")
+		}
+		if err := ddlOp.Wait(ctx); err != nil {
+			return errors.Wrap(deployerr.FromSpanner(err), "database.UpdateDatabaseDdlOperation.Wait()")
+		}
+	}
+
+	return nil
+}
+
+// splitDatabaseOptions separates the ALTER DATABASE statements of a template's schema, which name the template
+// and are rejected for any other database, from the rest. They are returned rewritten for the database dbID.
+func splitDatabaseOptions(ddl []string, dbID string) (schema, options []string) {
+	for _, stmt := range ddl {
+		m := alterDatabase.FindStringSubmatch(stmt)
+		if m == nil {
+			schema = append(schema, stmt)
+
+			continue
+		}
+		options = append(options, fmt.Sprintf("ALTER DATABASE `%s` %s", dbID, m[1]))
+	}
+
+	return schema, options
+}
+
+// Tables returns the user tables of the database in the order they can be written: every table after its
+// interleave parent and the tables its foreign keys reference. Generated columns are not included.
+func Tables(ctx context.Context, client *spanner.Client) ([]Table, error) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	tables := make(map[string]*Table)
+	var names []string
+	if err := txn.Query(ctx, spanner.Statement{SQL: `SELECT t.TABLE_NAME, IFNULL(t.PARENT_TABLE_NAME, ''), c.COLUMN_NAME
+		FROM INFORMATION_SCHEMA.TABLES t
+		JOIN INFORMATION_SCHEMA.COLUMNS c ON c.TABLE_SCHEMA = t.TABLE_SCHEMA AND c.TABLE_NAME = t.TABLE_NAME
+		WHERE t.TABLE_SCHEMA = '' AND t.TABLE_TYPE = 'BASE TABLE' AND c.IS_GENERATED = 'NEVER'
+		ORDER BY t.TABLE_NAME, c.ORDINAL_POSITION`}).Do(func(r *spanner.Row) error {
+		var name, parent, column string
+		if err := r.Columns(&name, &parent, &column); err != nil {
+			return errors.Wrap(err, "spanner.Row.Columns()")
+		}
+		t, ok := tables[name]
+		if !ok {
+			t = &Table{Name: name}
+			if parent != "" {
+				t.DependsOn = append(t.DependsOn, parent)
+			}
+			tables[name] = t
+			names = append(names, name)
+		}
+		t.Columns = append(t.Columns, column)
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	if err := txn.Query(ctx, spanner.Statement{SQL: `SELECT fk.TABLE_NAME, pk.TABLE_NAME
+		FROM INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS rc
+		JOIN INFORMATION_SCHEMA.TABLE_CONSTRAINTS fk ON fk.CONSTRAINT_SCHEMA = rc.CONSTRAINT_SCHEMA AND fk.CONSTRAINT_NAME = rc.CONSTRAINT_NAME
+		JOIN INFORMATION_SCHEMA.TABLE_CONSTRAINTS pk ON pk.CONSTRAINT_SCHEMA = rc.UNIQUE_CONSTRAINT_SCHEMA AND pk.CONSTRAINT_NAME = rc.UNIQUE_CONSTRAINT_NAME
+		WHERE rc.CONSTRAINT_SCHEMA = ''`}).Do(func(r *spanner.Row) error {
+		var table, referenced string
+		if err := r.Columns(&table, &referenced); err != nil {
+			return errors.Wrap(err, "spanner.Row.Columns()")
+		}
+		if t, ok := tables[table]; ok && table != referenced && !slices.Contains(t.DependsOn, referenced) {
+			t.DependsOn = append(t.DependsOn, referenced)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	list := make([]Table, 0, len(names))
+	for _, name := range names {
+		list = append(list, *tables[name])
+	}
+
+	return order(list)
+}
+
+// order sorts tables so every table follows the tables it depends on, keeping name order otherwise
+func order(tables []Table) ([]Table, error) {
+	done := make(map[string]bool, len(tables))
+	ordered := make([]Table, 0, len(tables))
+	for len(ordered) < len(tables) {
+		progressed := false
+		for _, t := range tables {
+			if done[t.Name] || slices.ContainsFunc(t.DependsOn, func(dep string) bool { return !done[dep] }) {
+				continue
+			}
+			done[t.Name] = true
+			ordered = append(ordered, t)
+			progressed = true
+		}
+
+		if !progressed {
+			var cycle []string
+			for _, t := range tables {
+				if !done[t.Name] {
+					cycle = append(cycle, t.Name)
+				}
+			}
+
+			return nil, errors.Newf("tables have circular foreign keys and can not be copied in order: %s", strings.Join(cycle, ", "))
+		}
+	}
+
+	return ordered, nil
+}
+
+// CopyRows copies every row of tables from src to dst, in order, and returns the number of rows copied.
+// All rows are read at the same timestamp, so the copy is consistent. Rows are written with
+// insert-or-update mutations in transactions sized to stay below the 80,000 mutation limit.
+func CopyRows(ctx context.Context, src, dst *spanner.Client, tables []Table) (int64, error) {
+	txn := src.ReadOnlyTransaction()
+	defer txn.Close()
+
+	var total int64
+	for _, t := range tables {
+		n, err := copyTable(ctx, txn, dst, t)
+		if err != nil {
+			return total, errors.Wrapf(err, "failed to copy table %s", t.Name)
+		}
+		if n > 0 {
+			log.Printf("Copied %d row(s) of %s\n", n, t.Name)
+		}
+		total += n
+	}
+
+	return total, nil
+}
+
+func copyTable(ctx context.Context, txn *spanner.ReadOnlyTransaction, dst *spanner.Client, t Table) (int64, error) {
+	// every written cell is a mutation, and secondary index entries count too, so leave half the limit for them
+	batchSize := max(mutationLimit/2/len(t.Columns), 1)
+	batch := make([]*spanner.Mutation, 0, batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := dst.Apply(ctx, batch); err != nil {
+			return errors.Wrap(err, "spanner.Client.Apply()")
+		}
+		batch = batch[:0]
+
+		return nil
+	}
+
+	var rows int64
+	if err := txn.Read(ctx, t.Name, spanner.AllKeys(), t.Columns).Do(func(r *spanner.Row) error {
+		values := make([]any, r.Size())
+		for i := range values {
+			var v spanner.GenericColumnValue
+			if err := r.Column(i, &v); err != nil {
+				return errors.Wrap(err, "spanner.Row.Column()")
+			}
+			values[i] = v
+		}
+		batch = append(batch, spanner.InsertOrUpdate(t.Name, t.Columns, values))
+		rows++
+
+		if len(batch) >= batchSize {
+			return flush()
+		}
+
+		return nil
+	}); err != nil {
+		return rows, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	return rows, flush()
+}
@@ -0,0 +1,91 @@
+package spannercopy
+
+import (
+	"slices"
+	"testing"
+)
+
+func Test_order(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		tables  []Table
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "independent tables keep name order",
+			tables: []Table{{Name: "Accounts"}, {Name: "Users"}},
+			want:   []string{"Accounts", "Users"},
+		},
+		{
+			name: "interleaved child after parent",
+			tables: []Table{
+				{Name: "Albums", DependsOn: []string{"Singers"}},
+				{Name: "Singers"},
+				{Name: "Songs", DependsOn: []string{"Albums"}},
+			},
+			want: []string{"Singers", "Albums", "Songs"},
+		},
+		{
+			name: "foreign key reference first",
+			tables: []Table{
+				{Name: "Orders", DependsOn: []string{"Users"}},
+				{Name: "Users"},
+			},
+			want: []string{"Users", "Orders"},
+		},
+		{
+			name: "circular foreign keys",
+			tables: []Table{
+				{Name: "A", DependsOn: []string{"B"}},
+				{Name: "B", DependsOn: []string{"A"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := order(tt.tables)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("order() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var names []string
+			for _, table := range got {
+				names = append(names, table.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("order() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func Test_splitDatabaseOptions(t *testing.T) {
+	t.Parallel()
+
+	ddl := []string{
+		"ALTER DATABASE `template-db` SET OPTIONS (optimizer_version = 6, optimizer_statistics_package = \"auto_20240101\")",
+		"CREATE TABLE Users (\n  Id STRING(36) NOT NULL,\n) PRIMARY KEY(Id)",
+		"alter database plain_name\n  SET OPTIONS (version_retention_period = '3d')",
+		"CREATE INDEX UsersByName ON Users(Name)",
+	}
+
+	schema, options := splitDatabaseOptions(ddl, "pr-123")
+
+	wantSchema := []string{ddl[1], ddl[3]}
+	if !slices.Equal(schema, wantSchema) {
+		t.Errorf("splitDatabaseOptions() schema = %q, want %q", schema, wantSchema)
+	}
+	wantOptions := []string{
+		"ALTER DATABASE `pr-123` SET OPTIONS (optimizer_version = 6, optimizer_statistics_package = \"auto_20240101\")",
+		"ALTER DATABASE `pr-123` SET OPTIONS (version_retention_period = '3d')",
+	}
+	if !slices.Equal(options, wantOptions) {
+		t.Errorf("splitDatabaseOptions() options = %q, want %q", options, wantOptions)
+	}
+}
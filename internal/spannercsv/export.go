@@ -0,0 +1,88 @@
+package spannercsv
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/go-playground/errors/v5"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Export writes every row of table to w in the format Load reads, and returns the number of rows written.
+// NULL values are written as nullValue, so a STRING value equal to nullValue can not be exported.
+func Export(ctx context.Context, txn *spanner.ReadOnlyTransaction, table string, columns []string, nullValue string, w io.Writer) (int64, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return 0, errors.Wrap(err, "csv.Writer.Write()")
+	}
+
+	var rows int64
+	record := make([]string, len(columns))
+	if err := txn.Read(ctx, table, spanner.AllKeys(), columns).Do(func(r *spanner.Row) error {
+		for i := range record {
+			var v spanner.GenericColumnValue
+			if err := r.Column(i, &v); err != nil {
+				return errors.Wrap(err, "spanner.Row.Column()")
+			}
+
+			field, err := formatValue(v, nullValue)
+			if err != nil {
+				return errors.Wrapf(err, "row %d, column %s", rows+1, columns[i])
+			}
+			record[i] = field
+		}
+		if err := cw.Write(record); err != nil {
+			return errors.Wrap(err, "csv.Writer.Write()")
+		}
+		rows++
+
+		return nil
+	}); err != nil {
+		return rows, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return rows, errors.Wrap(err, "csv.Writer.Flush()")
+	}
+
+	return rows, nil
+}
+
+// formatValue converts a column value into the CSV field Load parses for the column's type.
+// Spanner encodes most types as strings already in that format.
+func formatValue(v spanner.GenericColumnValue, nullValue string) (string, error) {
+	if _, ok := v.Value.GetKind().(*structpb.Value_NullValue); ok {
+		return nullValue, nil
+	}
+
+	switch code := v.Type.GetCode(); code {
+	case sppb.TypeCode_STRING:
+		if s := v.Value.GetStringValue(); s != nullValue {
+			return s, nil
+		}
+
+		return "", errors.Newf("value %q is the same as the null value; choose a different --null-value", nullValue)
+	case sppb.TypeCode_INT64, sppb.TypeCode_BYTES, sppb.TypeCode_DATE, sppb.TypeCode_TIMESTAMP, sppb.TypeCode_NUMERIC, sppb.TypeCode_JSON:
+		return v.Value.GetStringValue(), nil
+	case sppb.TypeCode_FLOAT64, sppb.TypeCode_FLOAT32:
+		// NaN and infinities are encoded as strings
+		if s, ok := v.Value.GetKind().(*structpb.Value_StringValue); ok {
+			return s.StringValue, nil
+		}
+		bitSize := 64
+		if code == sppb.TypeCode_FLOAT32 {
+			bitSize = 32
+		}
+
+		return strconv.FormatFloat(v.Value.GetNumberValue(), 'g', -1, bitSize), nil
+	case sppb.TypeCode_BOOL:
+		return strconv.FormatBool(v.Value.GetBoolValue()), nil
+	default:
+		return "", errors.Newf("unsupported column type %s", code)
+	}
+}
@@ -0,0 +1,77 @@
+package spannercsv
+
+import (
+	"math"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func Test_formatValue(t *testing.T) {
+	t.Parallel()
+
+	value := func(code sppb.TypeCode, v *structpb.Value) spanner.GenericColumnValue {
+		return spanner.GenericColumnValue{Type: &sppb.Type{Code: code}, Value: v}
+	}
+
+	tests := []struct {
+		name      string
+		v         spanner.GenericColumnValue
+		nullValue string
+		want      string
+		wantErr   bool
+	}{
+		{name: "null", v: value(sppb.TypeCode_INT64, structpb.NewNullValue()), nullValue: `\N`, want: `\N`},
+		{name: "string", v: value(sppb.TypeCode_STRING, structpb.NewStringValue("hello")), want: "hello"},
+		{name: "empty string with null marker", v: value(sppb.TypeCode_STRING, structpb.NewStringValue("")), nullValue: `\N`, want: ""},
+		{name: "empty string without null marker", v: value(sppb.TypeCode_STRING, structpb.NewStringValue("")), wantErr: true},
+		{name: "int64", v: value(sppb.TypeCode_INT64, structpb.NewStringValue("-42")), want: "-42"},
+		{name: "bytes", v: value(sppb.TypeCode_BYTES, structpb.NewStringValue("aGk=")), want: "aGk="},
+		{name: "timestamp", v: value(sppb.TypeCode_TIMESTAMP, structpb.NewStringValue("2024-01-02T03:04:05.5Z")), want: "2024-01-02T03:04:05.5Z"},
+		{name: "float64", v: value(sppb.TypeCode_FLOAT64, structpb.NewNumberValue(1.5)), want: "1.5"},
+		{name: "float32", v: value(sppb.TypeCode_FLOAT32, structpb.NewNumberValue(float64(float32(0.1)))), want: "0.1"},
+		{name: "float64 large", v: value(sppb.TypeCode_FLOAT64, structpb.NewNumberValue(math.MaxFloat64)), want: "1.7976931348623157e+308"},
+		{name: "float64 NaN", v: value(sppb.TypeCode_FLOAT64, structpb.NewStringValue("NaN")), want: "NaN"},
+		{name: "bool", v: value(sppb.TypeCode_BOOL, structpb.NewBoolValue(true)), want: "true"},
+		{name: "array", v: value(sppb.TypeCode_ARRAY, structpb.NewListValue(&structpb.ListValue{})), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := formatValue(tt.v, tt.nullValue)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("formatValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("formatValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formatValue_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		spannerType string
+		code        sppb.TypeCode
+		v           *structpb.Value
+	}{
+		{spannerType: "INT64", code: sppb.TypeCode_INT64, v: structpb.NewStringValue("9007199254740993")},
+		{spannerType: "FLOAT64", code: sppb.TypeCode_FLOAT64, v: structpb.NewStringValue("Infinity")},
+		{spannerType: "DATE", code: sppb.TypeCode_DATE, v: structpb.NewStringValue("2024-02-29")},
+		{spannerType: "NUMERIC", code: sppb.TypeCode_NUMERIC, v: structpb.NewStringValue("12.25")},
+		{spannerType: "JSON", code: sppb.TypeCode_JSON, v: structpb.NewStringValue(`{"a":1}`)},
+	} {
+		field, err := formatValue(spanner.GenericColumnValue{Type: &sppb.Type{Code: tt.code}, Value: tt.v}, "")
+		if err != nil {
+			t.Fatalf("formatValue(%s) error = %v", tt.spannerType, err)
+		}
+		if _, err := ParseValue(tt.spannerType, field); err != nil {
+			t.Errorf("ParseValue(%s, %q) error = %v", tt.spannerType, field, err)
+		}
+	}
+}
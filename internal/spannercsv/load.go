@@ -0,0 +1,267 @@
+// Package spannercsv loads and exports Spanner table rows as CSV files with a header row of column names.
+package spannercsv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/cccteam/deployment-tools/internal/spannertag"
+	"github.com/go-playground/errors/v5"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// groupsPerRequest is the number of mutation groups sent in a single BatchWrite request
+	groupsPerRequest = 50
+
+	// mutationLimit is the maximum number of mutations Spanner allows in a single mutation group
+	mutationLimit = 80000
+)
+
+// TableName returns the table a CSV file seeds. An optional numeric prefix
+// (e.g. 001_Users.csv) is used only for ordering and is not part of the name.
+func TableName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if prefix, table, ok := strings.Cut(name, "_"); ok {
+		if _, err := strconv.Atoi(prefix); err == nil {
+			return table
+		}
+	}
+
+	return name
+}
+
+// Load writes the rows of a CSV file in mutation groups of groupSize rows. Fields equal to nullValue are written as NULL.
+func Load(ctx context.Context, client *spanner.Client, path string, groupSize int, nullValue string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "os.Open()")
+	}
+	defer f.Close()
+
+	table := TableName(path)
+	r := csv.NewReader(f)
+	r.ReuseRecord = true
+
+	columns, err := r.Read()
+	if err != nil {
+		return errors.Wrap(err, "csv.Reader.Read()")
+	}
+	columns = slices.Clone(columns)
+
+	// each column written counts as a mutation, before secondary index entries are added
+	if mutations := groupSize * len(columns); mutations > mutationLimit {
+		return errors.Newf(
+			"--group-size %d with %d columns would write %d mutations per group, exceeding the limit of %d; use a --group-size of at most %d",
+			groupSize, len(columns), mutations, mutationLimit, mutationLimit/len(columns),
+		)
+	}
+
+	types, err := ColumnTypes(ctx, client, table, columns)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Loading %s into %s\n", path, table)
+
+	var rows int
+	groups := make([]*spanner.MutationGroup, 0, groupsPerRequest)
+	group := &spanner.MutationGroup{}
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "csv.Reader.Read()")
+		}
+
+		values := make([]any, len(record))
+		for i, v := range record {
+			if v == nullValue {
+				continue
+			}
+			values[i], err = ParseValue(types[i], v)
+			if err != nil {
+				return errors.Wrapf(err, "line %d, column %s", rows+2, columns[i])
+			}
+		}
+		group.Mutations = append(group.Mutations, spanner.InsertOrUpdate(table, columns, values))
+		rows++
+
+		if len(group.Mutations) == groupSize {
+			groups = append(groups, group)
+			group = &spanner.MutationGroup{}
+		}
+		if len(groups) == groupsPerRequest {
+			if err := batchWrite(ctx, client, table, groups); err != nil {
+				return err
+			}
+			groups = groups[:0]
+		}
+	}
+
+	if len(group.Mutations) > 0 {
+		groups = append(groups, group)
+	}
+	if len(groups) > 0 {
+		if err := batchWrite(ctx, client, table, groups); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("%d rows written to %s\n", rows, table)
+
+	return nil
+}
+
+func batchWrite(ctx context.Context, client *spanner.Client, table string, groups []*spanner.MutationGroup) error {
+	var failed int
+	var firstErr string
+	iter := client.BatchWriteWithOptions(ctx, groups, spanner.BatchWriteOptions{TransactionTag: spannertag.Tag("seed", table)})
+	if err := iter.Do(func(r *sppb.BatchWriteResponse) error {
+		if codes.Code(r.GetStatus().GetCode()) != codes.OK {
+			failed += len(r.GetIndexes())
+			if firstErr == "" {
+				firstErr = r.GetStatus().GetMessage()
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "spanner.BatchWriteResponseIterator.Do()")
+	}
+
+	if failed > 0 {
+		return errors.Newf("%d of %d mutation groups failed to apply: %s", failed, len(groups), firstErr)
+	}
+
+	return nil
+}
+
+// ColumnTypes returns the Spanner type of each column, in the order given.
+func ColumnTypes(ctx context.Context, client *spanner.Client, table string, columns []string) ([]string, error) {
+	stmt := spanner.Statement{
+		SQL: `SELECT COLUMN_NAME, SPANNER_TYPE
+			FROM INFORMATION_SCHEMA.COLUMNS
+			WHERE TABLE_SCHEMA = '' AND TABLE_NAME = @table`,
+		Params: map[string]any{"table": table},
+	}
+
+	schema := make(map[string]string)
+	iter := client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+	for {
+		row, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "spanner.RowIterator.Next()")
+		}
+
+		var name, typ string
+		if err := row.Columns(&name, &typ); err != nil {
+			return nil, errors.Wrap(err, "spanner.Row.Columns()")
+		}
+		schema[name] = typ
+	}
+
+	if len(schema) == 0 {
+		return nil, errors.Newf("table %s does not exist", table)
+	}
+
+	types := make([]string, len(columns))
+	for i, column := range columns {
+		typ, ok := schema[column]
+		if !ok {
+			return nil, errors.Newf("column %s does not exist in table %s", column, table)
+		}
+		types[i] = typ
+	}
+
+	return types, nil
+}
+
+// ParseValue converts a CSV field into a value for a column of the given Spanner type.
+func ParseValue(spannerType, v string) (any, error) {
+	switch {
+	case strings.HasPrefix(spannerType, "STRING"):
+		return v, nil
+	case strings.HasPrefix(spannerType, "BYTES"):
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "base64.StdEncoding.DecodeString()")
+		}
+
+		return b, nil
+	case spannerType == "INT64":
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "strconv.ParseInt()")
+		}
+
+		return i, nil
+	case spannerType == "FLOAT64", spannerType == "FLOAT32":
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "strconv.ParseFloat()")
+		}
+
+		return f, nil
+	case spannerType == "BOOL":
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "strconv.ParseBool()")
+		}
+
+		return b, nil
+	case spannerType == "DATE":
+		d, err := civil.ParseDate(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "civil.ParseDate()")
+		}
+
+		return d, nil
+	case spannerType == "TIMESTAMP":
+		if v == "PENDING_COMMIT_TIMESTAMP()" {
+			return spanner.CommitTimestamp, nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, errors.Wrap(err, "time.Parse()")
+		}
+
+		return t, nil
+	case spannerType == "NUMERIC":
+		r, ok := new(big.Rat).SetString(v)
+		if !ok {
+			return nil, errors.Newf("invalid NUMERIC value %q", v)
+		}
+
+		return r, nil
+	case spannerType == "JSON":
+		if !json.Valid([]byte(v)) {
+			return nil, errors.Newf("invalid JSON value %q", v)
+		}
+
+		return spanner.NullJSON{Value: json.RawMessage(v), Valid: true}, nil
+	default:
+		return nil, errors.Newf("unsupported column type %s", spannerType)
+	}
+}
@@ -0,0 +1,95 @@
+package spannercsv
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+)
+
+func TestTableName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "plain", path: "seed/Users.csv", want: "Users"},
+		{name: "numeric prefix", path: "seed/001_Users.csv", want: "Users"},
+		{name: "numeric prefix with underscore in table", path: "seed/002_User_Roles.csv", want: "User_Roles"},
+		{name: "non-numeric prefix", path: "seed/User_Roles.csv", want: "User_Roles"},
+		{name: "prefix only", path: "seed/001_.csv", want: ""},
+		{name: "no directory", path: "Accounts.csv", want: "Accounts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := TableName(tt.path); got != tt.want {
+				t.Errorf("TableName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		spannerType string
+		v           string
+		want        any
+		wantErr     bool
+	}{
+		{name: "string", spannerType: "STRING(MAX)", v: "hello", want: "hello"},
+		{name: "empty string", spannerType: "STRING(10)", v: "", want: ""},
+		{name: "bytes", spannerType: "BYTES(MAX)", v: "aGk=", want: []byte("hi")},
+		{name: "invalid bytes", spannerType: "BYTES(MAX)", v: "not base64!", wantErr: true},
+		{name: "int64", spannerType: "INT64", v: "-42", want: int64(-42)},
+		{name: "invalid int64", spannerType: "INT64", v: "4.2", wantErr: true},
+		{name: "float64", spannerType: "FLOAT64", v: "1.5", want: 1.5},
+		{name: "float32", spannerType: "FLOAT32", v: "2.25", want: 2.25},
+		{name: "bool", spannerType: "BOOL", v: "true", want: true},
+		{name: "invalid bool", spannerType: "BOOL", v: "yes", wantErr: true},
+		{name: "date", spannerType: "DATE", v: "2024-02-29", want: civil.Date{Year: 2024, Month: time.February, Day: 29}},
+		{name: "invalid date", spannerType: "DATE", v: "2023-02-29", wantErr: true},
+		{name: "timestamp", spannerType: "TIMESTAMP", v: "2024-01-02T03:04:05.5Z", want: time.Date(2024, 1, 2, 3, 4, 5, 500000000, time.UTC)},
+		{name: "commit timestamp", spannerType: "TIMESTAMP", v: "PENDING_COMMIT_TIMESTAMP()", want: spanner.CommitTimestamp},
+		{name: "invalid timestamp", spannerType: "TIMESTAMP", v: "2024-01-02", wantErr: true},
+		{name: "numeric", spannerType: "NUMERIC", v: "12.25", want: big.NewRat(49, 4)},
+		{name: "invalid numeric", spannerType: "NUMERIC", v: "twelve", wantErr: true},
+		{name: "json", spannerType: "JSON", v: `{"a":1}`, want: spanner.NullJSON{Value: json.RawMessage(`{"a":1}`), Valid: true}},
+		{name: "invalid json", spannerType: "JSON", v: `{"a":`, wantErr: true},
+		{name: "unsupported type", spannerType: "ARRAY<STRING(MAX)>", v: "a", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseValue(tt.spannerType, tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if want, ok := tt.want.(*big.Rat); ok {
+				if r, ok := got.(*big.Rat); !ok || r.Cmp(want) != 0 {
+					t.Errorf("ParseValue() = %v, want %v", got, want)
+				}
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Package spanneriam applies declarative database-level IAM bindings to a Spanner database.
+package spanneriam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/go-playground/errors/v5"
+)
+
+// Binding grants a role to a set of members on the database
+type Binding struct {
+	Role    string   `json:"role"`
+	Members []string `json:"members"`
+}
+
+// policyVersion is the IAM policy version that supports conditional bindings
+const policyVersion = 3
+
+type bindingsFile struct {
+	Bindings []Binding `json:"bindings"`
+}
+
+// Load reads bindings from a JSON file of the form
+//
+//	{"bindings": [{"role": "roles/spanner.databaseReader", "members": ["group:analysts@example.com"]}]}
+//
+// Environment variables in the file (e.g. ${GOOGLE_CLOUD_SPANNER_PROJECT}) are expanded before parsing,
+// so a single file can be shared across feature environments. Referencing an unset variable is an error.
+// Bindings for the same role are merged.
+func Load(path string) ([]Binding, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "os.ReadFile()")
+	}
+
+	var unset []string
+	expanded := os.Expand(string(b), func(name string) string {
+		v, ok := os.LookupEnv(name)
+		if !ok && !slices.Contains(unset, name) {
+			unset = append(unset, name)
+		}
+
+		return v
+	})
+	if len(unset) > 0 {
+		return nil, errors.Newf("%s: environment variables referenced in the bindings file are not set: %s", path, strings.Join(unset, ", "))
+	}
+
+	var f bindingsFile
+	if err := json.Unmarshal([]byte(expanded), &f); err != nil {
+		return nil, errors.Wrapf(err, "json.Unmarshal(): %s", path)
+	}
+
+	for i, binding := range f.Bindings {
+		if binding.Role == "" {
+			return nil, errors.Newf("%s: binding %d is missing a role", path, i)
+		}
+		if len(binding.Members) == 0 {
+			return nil, errors.Newf("%s: binding for role %s has no members", path, binding.Role)
+		}
+	}
+
+	return merge(f.Bindings), nil
+}
+
+// merge combines bindings for the same role, keeping the order roles and members first appear in
+func merge(bindings []Binding) []Binding {
+	merged := make([]Binding, 0, len(bindings))
+	for _, binding := range bindings {
+		i := slices.IndexFunc(merged, func(b Binding) bool { return b.Role == binding.Role })
+		if i < 0 {
+			merged = append(merged, Binding{Role: binding.Role})
+			i = len(merged) - 1
+		}
+		for _, member := range binding.Members {
+			if !slices.Contains(merged[i].Members, member) {
+				merged[i].Members = append(merged[i].Members, member)
+			}
+		}
+	}
+
+	return merged
+}
+
+// Apply adds the members of each binding to the database IAM policy. When prune is true, members of
+// the declared roles that are not listed in the bindings are removed. Roles that are not declared and
+// conditional bindings are never modified. bindings must not contain the same role twice, see [Load].
+// The returned changes describe each member added or removed.
+func Apply(ctx context.Context, admin *database.DatabaseAdminClient, dbStr string, bindings []Binding, prune bool) ([]string, error) {
+	// version 3 is required to read and write back conditional bindings without losing their conditions
+	policy, err := admin.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{
+		Resource: dbStr,
+		Options:  &iampb.GetPolicyOptions{RequestedPolicyVersion: policyVersion},
+	})
+	if err != nil {
+		return nil, errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.GetIamPolicy()")
+	}
+
+	changes := reconcile(policy, bindings, prune)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	// the policy etag guards against overwriting concurrent changes
+	policy.Version = policyVersion
+	if _, err := admin.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: dbStr, Policy: policy}); err != nil {
+		return nil, errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.SetIamPolicy()")
+	}
+
+	for _, change := range changes {
+		log.Println(change)
+	}
+
+	return changes, nil
+}
+
+// reconcile updates policy in place with the declared bindings and returns the changes made
+func reconcile(policy *iampb.Policy, bindings []Binding, prune bool) []string {
+	var changes []string
+	for _, binding := range bindings {
+		i := slices.IndexFunc(policy.Bindings, func(b *iampb.Binding) bool {
+			return b.GetRole() == binding.Role && b.GetCondition() == nil
+		})
+		if i < 0 {
+			policy.Bindings = append(policy.Bindings, &iampb.Binding{Role: binding.Role})
+			i = len(policy.Bindings) - 1
+		}
+		current := policy.Bindings[i]
+
+		for _, member := range binding.Members {
+			if !slices.Contains(current.Members, member) {
+				current.Members = append(current.Members, member)
+				changes = append(changes, fmt.Sprintf("Granted %s to %s", binding.Role, member))
+			}
+		}
+
+		if !prune {
+			continue
+		}
+		current.Members = slices.DeleteFunc(current.Members, func(member string) bool {
+			if slices.Contains(binding.Members, member) {
+				return false
+			}
+			changes = append(changes, fmt.Sprintf("Revoked %s from %s", binding.Role, member))
+
+			return true
+		})
+	}
+
+	// a binding without members is rejected by SetIamPolicy
+	policy.Bindings = slices.DeleteFunc(policy.Bindings, func(b *iampb.Binding) bool {
+		return len(b.GetMembers()) == 0
+	})
+
+	return changes
+}
@@ -0,0 +1,217 @@
+package spanneriam
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/protobuf/proto"
+)
+
+func Test_reconcile(t *testing.T) {
+	t.Parallel()
+
+	condition := &expr.Expr{Expression: `request.time < timestamp("2030-01-01T00:00:00Z")`}
+
+	tests := []struct {
+		name        string
+		policy      *iampb.Policy
+		bindings    []Binding
+		prune       bool
+		want        *iampb.Policy
+		wantChanges []string
+	}{
+		{
+			name:   "add role to empty policy",
+			policy: &iampb.Policy{},
+			bindings: []Binding{
+				{Role: "roles/spanner.databaseUser", Members: []string{"serviceAccount:app@p.iam.gserviceaccount.com"}},
+			},
+			want: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseUser", Members: []string{"serviceAccount:app@p.iam.gserviceaccount.com"}},
+			}},
+			wantChanges: []string{"Granted roles/spanner.databaseUser to serviceAccount:app@p.iam.gserviceaccount.com"},
+		},
+		{
+			name: "add member to existing role",
+			policy: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"user:a@example.com"}},
+			}},
+			bindings: []Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"group:analysts@example.com"}},
+			},
+			want: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"user:a@example.com", "group:analysts@example.com"}},
+			}},
+			wantChanges: []string{"Granted roles/spanner.databaseReader to group:analysts@example.com"},
+		},
+		{
+			name: "idempotent re-apply",
+			policy: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"group:analysts@example.com"}},
+			}},
+			bindings: []Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"group:analysts@example.com"}},
+			},
+			prune: true,
+			want: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"group:analysts@example.com"}},
+			}},
+		},
+		{
+			name: "prune removes unlisted members of declared roles only",
+			policy: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"user:a@example.com", "group:analysts@example.com"}},
+				{Role: "roles/spanner.databaseAdmin", Members: []string{"user:admin@example.com"}},
+			}},
+			bindings: []Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"group:analysts@example.com"}},
+			},
+			prune: true,
+			want: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"group:analysts@example.com"}},
+				{Role: "roles/spanner.databaseAdmin", Members: []string{"user:admin@example.com"}},
+			}},
+			wantChanges: []string{"Revoked roles/spanner.databaseReader from user:a@example.com"},
+		},
+		{
+			name: "without prune unlisted members are kept",
+			policy: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"user:a@example.com"}},
+			}},
+			bindings: []Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"user:a@example.com"}},
+			},
+			want: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"user:a@example.com"}},
+			}},
+		},
+		{
+			name: "duplicate roles are merged before reconciling",
+			policy: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseUser", Members: []string{"user:old@example.com"}},
+			}},
+			bindings: merge([]Binding{
+				{Role: "roles/spanner.databaseUser", Members: []string{"serviceAccount:app@p.iam.gserviceaccount.com"}},
+				{Role: "roles/spanner.databaseUser", Members: []string{"serviceAccount:worker@p.iam.gserviceaccount.com"}},
+			}),
+			prune: true,
+			want: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseUser", Members: []string{"serviceAccount:app@p.iam.gserviceaccount.com", "serviceAccount:worker@p.iam.gserviceaccount.com"}},
+			}},
+			wantChanges: []string{
+				"Granted roles/spanner.databaseUser to serviceAccount:app@p.iam.gserviceaccount.com",
+				"Granted roles/spanner.databaseUser to serviceAccount:worker@p.iam.gserviceaccount.com",
+				"Revoked roles/spanner.databaseUser from user:old@example.com",
+			},
+		},
+		{
+			name: "conditional bindings are not modified",
+			policy: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"user:temp@example.com"}, Condition: condition},
+			}},
+			bindings: []Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"group:analysts@example.com"}},
+			},
+			prune: true,
+			want: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"user:temp@example.com"}, Condition: condition},
+				{Role: "roles/spanner.databaseReader", Members: []string{"group:analysts@example.com"}},
+			}},
+			wantChanges: []string{"Granted roles/spanner.databaseReader to group:analysts@example.com"},
+		},
+		{
+			name: "empty bindings are removed",
+			policy: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseAdmin"},
+			}},
+			bindings: []Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"group:analysts@example.com"}},
+			},
+			want: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/spanner.databaseReader", Members: []string{"group:analysts@example.com"}},
+			}},
+			wantChanges: []string{"Granted roles/spanner.databaseReader to group:analysts@example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			changes := reconcile(tt.policy, tt.bindings, tt.prune)
+			if !slices.Equal(changes, tt.wantChanges) {
+				t.Errorf("reconcile() changes = %q, want %q", changes, tt.wantChanges)
+			}
+			if !proto.Equal(tt.policy, tt.want) {
+				t.Errorf("reconcile() policy = %v, want %v", tt.policy, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) { //nolint:paralleltest // uses t.Setenv
+	t.Setenv("SPANNERIAM_TEST_PROJECT", "my-project")
+
+	tests := []struct {
+		name    string
+		content string
+		want    []Binding
+		wantErr bool
+	}{
+		{
+			name:    "expands environment variables",
+			content: `{"bindings": [{"role": "roles/spanner.databaseUser", "members": ["serviceAccount:app@${SPANNERIAM_TEST_PROJECT}.iam.gserviceaccount.com"]}]}`,
+			want: []Binding{
+				{Role: "roles/spanner.databaseUser", Members: []string{"serviceAccount:app@my-project.iam.gserviceaccount.com"}},
+			},
+		},
+		{
+			name:    "unset environment variable",
+			content: `{"bindings": [{"role": "roles/spanner.databaseUser", "members": ["serviceAccount:app@${SPANNERIAM_TEST_UNSET}.iam.gserviceaccount.com"]}]}`,
+			wantErr: true,
+		},
+		{
+			name: "merges duplicate roles",
+			content: `{"bindings": [
+				{"role": "roles/spanner.databaseUser", "members": ["user:a@example.com"]},
+				{"role": "roles/spanner.databaseReader", "members": ["user:b@example.com"]},
+				{"role": "roles/spanner.databaseUser", "members": ["user:c@example.com", "user:a@example.com"]}
+			]}`,
+			want: []Binding{
+				{Role: "roles/spanner.databaseUser", Members: []string{"user:a@example.com", "user:c@example.com"}},
+				{Role: "roles/spanner.databaseReader", Members: []string{"user:b@example.com"}},
+			},
+		},
+		{
+			name:    "missing role",
+			content: `{"bindings": [{"members": ["user:a@example.com"]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing members",
+			content: `{"bindings": [{"role": "roles/spanner.databaseUser"}]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "iam.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.EqualFunc(got, tt.want, func(a, b Binding) bool {
+				return a.Role == b.Role && slices.Equal(a.Members, b.Members)
+			}) {
+				t.Errorf("Load() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+// Package spannerinstance describes the desired configuration of a Spanner instance.
+package spannerinstance
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	"github.com/cccteam/deployment-tools/internal/yamlfile"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+const (
+	configFlag                   = "config"
+	displayNameFlag              = "display-name"
+	processingUnitsFlag          = "processing-units"
+	minProcessingUnitsFlag       = "autoscaling-min-processing-units"
+	maxProcessingUnitsFlag       = "autoscaling-max-processing-units"
+	highPriorityCPUTargetFlag    = "autoscaling-high-priority-cpu-target"
+	storageUtilizationTargetFlag = "autoscaling-storage-target"
+
+	// maxAutoscalingRatio is the largest ratio between the autoscaling maximum and minimum Spanner allows
+	maxAutoscalingRatio = 10
+)
+
+// Spec is the desired configuration of a Spanner instance. Compute capacity is either a fixed
+// number of processing units or autoscaling limits, never both.
+type Spec struct {
+	Config                   string
+	DisplayName              string
+	ProcessingUnits          int32
+	MinProcessingUnits       int32
+	MaxProcessingUnits       int32
+	HighPriorityCPUTarget    int32
+	StorageUtilizationTarget int32
+
+	// highPriorityCPUTargetSet and storageUtilizationTargetSet record whether the targets were set
+	// explicitly, so updates keep the targets already configured on the instance
+	highPriorityCPUTargetSet    bool
+	storageUtilizationTargetSet bool
+
+	specFile string
+}
+
+// fileSpec is the YAML spec file read with --spec-file, e.g.
+//
+//	config: regional-us-central1
+//	displayName: Load test
+//	autoscaling:
+//	  minProcessingUnits: 1000
+//	  maxProcessingUnits: 5000
+//	  highPriorityCPUTarget: 65
+//	  storageTarget: 95
+type fileSpec struct {
+	Config          string           `yaml:"config"`
+	DisplayName     string           `yaml:"displayName"`
+	ProcessingUnits int32            `yaml:"processingUnits"`
+	Autoscaling     *fileAutoscaling `yaml:"autoscaling"`
+}
+
+// fileAutoscaling are the autoscaling limits and targets of a spec file. Unset targets keep their defaults, or the
+// targets of the instance when updating it.
+type fileAutoscaling struct {
+	MinProcessingUnits    int32  `yaml:"minProcessingUnits"`
+	MaxProcessingUnits    int32  `yaml:"maxProcessingUnits"`
+	HighPriorityCPUTarget *int32 `yaml:"highPriorityCPUTarget"`
+	StorageTarget         *int32 `yaml:"storageTarget"`
+}
+
+// Validate checks that the file sets either processing units or both autoscaling limits
+func (f *fileSpec) Validate() error {
+	var problems yamlfile.Problems
+	if f.ProcessingUnits != 0 && f.Autoscaling != nil {
+		problems.Add("processingUnits can not be combined with autoscaling")
+	}
+	if a := f.Autoscaling; a != nil && (a.MinProcessingUnits == 0 || a.MaxProcessingUnits == 0) {
+		problems.Add("autoscaling requires minProcessingUnits and maxProcessingUnits")
+	}
+
+	return problems.Err()
+}
+
+// AddFlags registers the flags describing the instance on cmd
+func (s *Spec) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&s.specFile, "spec-file", "", "Path to a YAML file describing the instance. Flags that are set override its values.")
+	cmd.Flags().StringVar(&s.DisplayName, displayNameFlag, "", "Display name of the instance. Defaults to the instance ID when creating an instance.")
+	cmd.Flags().Int32Var(&s.ProcessingUnits, processingUnitsFlag, 0, "Fixed compute capacity in processing units (multiples of 100 up to 1000, then multiples of 1000)")
+	cmd.Flags().Int32Var(&s.MinProcessingUnits, minProcessingUnitsFlag, 0, "Minimum processing units when autoscaling")
+	cmd.Flags().Int32Var(&s.MaxProcessingUnits, maxProcessingUnitsFlag, 0, "Maximum processing units when autoscaling")
+	cmd.Flags().
+		Int32Var(&s.HighPriorityCPUTarget, highPriorityCPUTargetFlag, 65, "Target high priority CPU utilization percentage when autoscaling. Updates keep the current target unless set.")
+	cmd.Flags().
+		Int32Var(&s.StorageUtilizationTarget, storageUtilizationTargetFlag, 95, "Target storage utilization percentage when autoscaling. Updates keep the current target unless set.")
+}
+
+// LoadFile reads the file named by --spec-file, if any, into the spec. Values of flags set on cmd take precedence,
+// and compute capacity set with flags replaces the capacity of the file as a whole. Call it before validating the spec.
+func (s *Spec) LoadFile(cmd *cobra.Command) error {
+	if s.specFile == "" {
+		return nil
+	}
+
+	f := &fileSpec{}
+	if _, err := yamlfile.Load(s.specFile, "instance spec", f); err != nil {
+		return errors.Wrap(err, "yamlfile.Load()")
+	}
+	s.apply(cmd, f)
+
+	return nil
+}
+
+// apply sets the values of f on the spec that were not set with flags on cmd
+func (s *Spec) apply(cmd *cobra.Command, f *fileSpec) {
+	changed := cmd.Flags().Changed
+
+	if !changed(configFlag) {
+		s.Config = f.Config
+	}
+	if !changed(displayNameFlag) {
+		s.DisplayName = f.DisplayName
+	}
+
+	// mixing the capacity of the file and the flags could combine processing units with autoscaling limits
+	if changed(processingUnitsFlag) || changed(minProcessingUnitsFlag) || changed(maxProcessingUnitsFlag) {
+		return
+	}
+	s.ProcessingUnits = f.ProcessingUnits
+	if a := f.Autoscaling; a != nil {
+		s.MinProcessingUnits, s.MaxProcessingUnits = a.MinProcessingUnits, a.MaxProcessingUnits
+		if a.HighPriorityCPUTarget != nil && !changed(highPriorityCPUTargetFlag) {
+			s.HighPriorityCPUTarget = *a.HighPriorityCPUTarget
+			s.highPriorityCPUTargetSet = true
+		}
+		if a.StorageTarget != nil && !changed(storageUtilizationTargetFlag) {
+			s.StorageUtilizationTarget = *a.StorageTarget
+			s.storageUtilizationTargetSet = true
+		}
+	}
+}
+
+// Autoscaling reports whether the spec configures autoscaling
+func (s *Spec) Autoscaling() bool {
+	return s.MinProcessingUnits > 0 || s.MaxProcessingUnits > 0
+}
+
+// HasCapacity reports whether the spec configures compute capacity
+func (s *Spec) HasCapacity() bool {
+	return s.ProcessingUnits > 0 || s.Autoscaling()
+}
+
+// Validate checks that the spec describes a valid compute capacity and records which autoscaling targets were set on cmd
+func (s *Spec) Validate(cmd *cobra.Command) error {
+	s.highPriorityCPUTargetSet = s.highPriorityCPUTargetSet || cmd.Flags().Changed(highPriorityCPUTargetFlag)
+	s.storageUtilizationTargetSet = s.storageUtilizationTargetSet || cmd.Flags().Changed(storageUtilizationTargetFlag)
+
+	if s.ProcessingUnits > 0 && s.Autoscaling() {
+		return errors.New("--processing-units can not be combined with autoscaling limits")
+	}
+
+	if s.ProcessingUnits > 0 {
+		if err := validProcessingUnits("--processing-units", s.ProcessingUnits); err != nil {
+			return err
+		}
+	}
+
+	if s.Autoscaling() {
+		if s.MinProcessingUnits == 0 || s.MaxProcessingUnits == 0 {
+			return errors.New("both --autoscaling-min-processing-units and --autoscaling-max-processing-units are required when autoscaling")
+		}
+		if err := validProcessingUnits("--autoscaling-min-processing-units", s.MinProcessingUnits); err != nil {
+			return err
+		}
+		if err := validProcessingUnits("--autoscaling-max-processing-units", s.MaxProcessingUnits); err != nil {
+			return err
+		}
+		if s.MinProcessingUnits > s.MaxProcessingUnits {
+			return errors.Newf("autoscaling minimum (%d) is greater than the maximum (%d)", s.MinProcessingUnits, s.MaxProcessingUnits)
+		}
+		if s.MaxProcessingUnits > maxAutoscalingRatio*s.MinProcessingUnits {
+			return errors.Newf("autoscaling maximum (%d) must be at most %d times the minimum (%d)", s.MaxProcessingUnits, maxAutoscalingRatio, s.MinProcessingUnits)
+		}
+		if s.HighPriorityCPUTarget < 10 || s.HighPriorityCPUTarget > 90 {
+			return errors.Newf("--autoscaling-high-priority-cpu-target must be between 10 and 90, got %d", s.HighPriorityCPUTarget)
+		}
+		if s.StorageUtilizationTarget < 10 || s.StorageUtilizationTarget > 99 {
+			return errors.Newf("--autoscaling-storage-target must be between 10 and 99, got %d", s.StorageUtilizationTarget)
+		}
+	}
+
+	return nil
+}
+
+// Apply sets the configured fields of the spec on instance and returns the paths of the fields that were set
+func (s *Spec) Apply(instance *instancepb.Instance) (paths []string) {
+	if s.DisplayName != "" {
+		instance.DisplayName = s.DisplayName
+		paths = append(paths, "display_name")
+	}
+
+	switch {
+	case s.Autoscaling():
+		instance.ProcessingUnits = 0
+		instance.NodeCount = 0
+		instance.AutoscalingConfig = &instancepb.AutoscalingConfig{
+			AutoscalingLimits: &instancepb.AutoscalingConfig_AutoscalingLimits{
+				MinLimit: &instancepb.AutoscalingConfig_AutoscalingLimits_MinProcessingUnits{MinProcessingUnits: s.MinProcessingUnits},
+				MaxLimit: &instancepb.AutoscalingConfig_AutoscalingLimits_MaxProcessingUnits{MaxProcessingUnits: s.MaxProcessingUnits},
+			},
+			AutoscalingTargets: s.targets(instance.GetAutoscalingConfig().GetAutoscalingTargets()),
+		}
+		paths = append(paths, "autoscaling_config")
+	case s.ProcessingUnits > 0:
+		instance.NodeCount = 0
+		instance.ProcessingUnits = s.ProcessingUnits
+		paths = append(paths, "processing_units")
+		if instance.GetAutoscalingConfig() != nil {
+			instance.AutoscalingConfig = nil
+			paths = append(paths, "autoscaling_config")
+		}
+	}
+
+	return paths
+}
+
+// targets returns the autoscaling targets, keeping the current targets of an autoscaling instance
+// unless they were set explicitly
+func (s *Spec) targets(current *instancepb.AutoscalingConfig_AutoscalingTargets) *instancepb.AutoscalingConfig_AutoscalingTargets {
+	targets := &instancepb.AutoscalingConfig_AutoscalingTargets{
+		HighPriorityCpuUtilizationPercent: s.HighPriorityCPUTarget,
+		StorageUtilizationPercent:         s.StorageUtilizationTarget,
+	}
+	if current == nil {
+		return targets
+	}
+
+	if !s.highPriorityCPUTargetSet {
+		targets.HighPriorityCpuUtilizationPercent = current.GetHighPriorityCpuUtilizationPercent()
+	}
+	if !s.storageUtilizationTargetSet {
+		targets.StorageUtilizationPercent = current.GetStorageUtilizationPercent()
+	}
+
+	return targets
+}
+
+// DescribeCapacity returns a human readable description of the compute capacity of instance
+func DescribeCapacity(instance *instancepb.Instance) string {
+	if ac := instance.GetAutoscalingConfig(); ac != nil {
+		limits := ac.GetAutoscalingLimits()
+
+		return fmt.Sprintf("autoscaling %d-%d processing units (high priority CPU target %d%%, storage target %d%%)",
+			limits.GetMinProcessingUnits(), limits.GetMaxProcessingUnits(),
+			ac.GetAutoscalingTargets().GetHighPriorityCpuUtilizationPercent(), ac.GetAutoscalingTargets().GetStorageUtilizationPercent(),
+		)
+	}
+
+	return fmt.Sprintf("%d processing units", instance.GetProcessingUnits())
+}
+
+func validProcessingUnits(flag string, pu int32) error {
+	switch {
+	case pu < 100:
+		return errors.Newf("%s must be at least 100, got %d", flag, pu)
+	case pu <= 1000 && pu%100 != 0:
+		return errors.Newf("%s must be a multiple of 100 up to 1000, got %d", flag, pu)
+	case pu > 1000 && pu%1000 != 0:
+		return errors.Newf("%s must be a multiple of 1000 above 1000, got %d", flag, pu)
+	}
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package spannerinstance
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+)
+
+// parse registers the flags of the create command and parses args
+func parse(t *testing.T, args ...string) (*Spec, *cobra.Command) {
+	t.Helper()
+
+	s := &Spec{}
+	cmd := &cobra.Command{}
+	cmd.Flags().StringVar(&s.Config, configFlag, "", "")
+	s.AddFlags(cmd)
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatalf("cobra.Command.ParseFlags() error = %v", err)
+	}
+
+	return s, cmd
+}
+
+func autoscaling(minPU, maxPU, cpu, storage int32) *instancepb.AutoscalingConfig {
+	return &instancepb.AutoscalingConfig{
+		AutoscalingLimits: &instancepb.AutoscalingConfig_AutoscalingLimits{
+			MinLimit: &instancepb.AutoscalingConfig_AutoscalingLimits_MinProcessingUnits{MinProcessingUnits: minPU},
+			MaxLimit: &instancepb.AutoscalingConfig_AutoscalingLimits_MaxProcessingUnits{MaxProcessingUnits: maxPU},
+		},
+		AutoscalingTargets: &instancepb.AutoscalingConfig_AutoscalingTargets{
+			HighPriorityCpuUtilizationPercent: cpu,
+			StorageUtilizationPercent:         storage,
+		},
+	}
+}
+
+func TestSpec_Apply(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		args      []string
+		instance  *instancepb.Instance
+		want      *instancepb.Instance
+		wantPaths []string
+	}{
+		{
+			name:      "create with processing units",
+			args:      []string{"--processing-units", "1000"},
+			instance:  &instancepb.Instance{DisplayName: "app"},
+			want:      &instancepb.Instance{DisplayName: "app", ProcessingUnits: 1000},
+			wantPaths: []string{"processing_units"},
+		},
+		{
+			name:      "create with autoscaling uses the default targets",
+			args:      []string{"--autoscaling-min-processing-units", "1000", "--autoscaling-max-processing-units", "5000"},
+			instance:  &instancepb.Instance{DisplayName: "app"},
+			want:      &instancepb.Instance{DisplayName: "app", AutoscalingConfig: autoscaling(1000, 5000, 65, 95)},
+			wantPaths: []string{"autoscaling_config"},
+		},
+		{
+			name:      "update autoscaling keeps the current targets",
+			args:      []string{"--autoscaling-min-processing-units", "2000", "--autoscaling-max-processing-units", "8000"},
+			instance:  &instancepb.Instance{AutoscalingConfig: autoscaling(1000, 5000, 50, 80)},
+			want:      &instancepb.Instance{AutoscalingConfig: autoscaling(2000, 8000, 50, 80)},
+			wantPaths: []string{"autoscaling_config"},
+		},
+		{
+			name: "update autoscaling with a target",
+			args: []string{
+				"--autoscaling-min-processing-units", "1000", "--autoscaling-max-processing-units", "5000",
+				"--autoscaling-high-priority-cpu-target", "70",
+			},
+			instance:  &instancepb.Instance{AutoscalingConfig: autoscaling(1000, 5000, 50, 80)},
+			want:      &instancepb.Instance{AutoscalingConfig: autoscaling(1000, 5000, 70, 80)},
+			wantPaths: []string{"autoscaling_config"},
+		},
+		{
+			name:      "update from nodes to autoscaling",
+			args:      []string{"--autoscaling-min-processing-units", "1000", "--autoscaling-max-processing-units", "2000"},
+			instance:  &instancepb.Instance{NodeCount: 1, ProcessingUnits: 1000},
+			want:      &instancepb.Instance{AutoscalingConfig: autoscaling(1000, 2000, 65, 95)},
+			wantPaths: []string{"autoscaling_config"},
+		},
+		{
+			name:      "update from autoscaling to processing units",
+			args:      []string{"--processing-units", "500"},
+			instance:  &instancepb.Instance{ProcessingUnits: 1000, AutoscalingConfig: autoscaling(1000, 5000, 50, 80)},
+			want:      &instancepb.Instance{ProcessingUnits: 500},
+			wantPaths: []string{"processing_units", "autoscaling_config"},
+		},
+		{
+			name:      "update display name only",
+			args:      []string{"--display-name", "Load test"},
+			instance:  &instancepb.Instance{DisplayName: "app", ProcessingUnits: 1000},
+			want:      &instancepb.Instance{DisplayName: "Load test", ProcessingUnits: 1000},
+			wantPaths: []string{"display_name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, cmd := parse(t, tt.args...)
+			if err := s.Validate(cmd); err != nil {
+				t.Fatalf("Spec.Validate() error = %v", err)
+			}
+
+			paths := s.Apply(tt.instance)
+			if !slices.Equal(paths, tt.wantPaths) {
+				t.Errorf("Spec.Apply() paths = %q, want %q", paths, tt.wantPaths)
+			}
+			if !proto.Equal(tt.instance, tt.want) {
+				t.Errorf("Spec.Apply() instance = %v, want %v", tt.instance, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpec_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "processing units", args: []string{"--processing-units", "300"}},
+		{name: "processing units above 1000", args: []string{"--processing-units", "3000"}},
+		{name: "processing units below 100", args: []string{"--processing-units", "50"}, wantErr: true},
+		{name: "processing units not a multiple of 100", args: []string{"--processing-units", "150"}, wantErr: true},
+		{name: "processing units above 1000 not a multiple of 1000", args: []string{"--processing-units", "1500"}, wantErr: true},
+		{name: "autoscaling", args: []string{"--autoscaling-min-processing-units", "1000", "--autoscaling-max-processing-units", "10000"}},
+		{
+			name:    "processing units and autoscaling",
+			args:    []string{"--processing-units", "1000", "--autoscaling-min-processing-units", "1000", "--autoscaling-max-processing-units", "2000"},
+			wantErr: true,
+		},
+		{name: "autoscaling without a maximum", args: []string{"--autoscaling-min-processing-units", "1000"}, wantErr: true},
+		{name: "autoscaling minimum above maximum", args: []string{"--autoscaling-min-processing-units", "2000", "--autoscaling-max-processing-units", "1000"}, wantErr: true},
+		{name: "autoscaling ratio above 10", args: []string{"--autoscaling-min-processing-units", "100", "--autoscaling-max-processing-units", "2000"}, wantErr: true},
+		{
+			name:    "autoscaling target out of range",
+			args:    []string{"--autoscaling-min-processing-units", "1000", "--autoscaling-max-processing-units", "2000", "--autoscaling-storage-target", "100"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, cmd := parse(t, tt.args...)
+			if err := s.Validate(cmd); (err != nil) != tt.wantErr {
+				t.Errorf("Spec.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSpec_LoadFile(t *testing.T) {
+	t.Parallel()
+
+	const file = `config: regional-us-central1
+displayName: Load test
+autoscaling:
+  minProcessingUnits: 1000
+  maxProcessingUnits: 5000
+  storageTarget: 90
+`
+
+	tests := []struct {
+		name    string
+		content string
+		args    []string
+		want    Spec
+		wantErr bool
+	}{
+		{
+			name:    "file only",
+			content: file,
+			want: Spec{
+				Config: "regional-us-central1", DisplayName: "Load test",
+				MinProcessingUnits: 1000, MaxProcessingUnits: 5000, HighPriorityCPUTarget: 65, StorageUtilizationTarget: 90,
+				storageUtilizationTargetSet: true,
+			},
+		},
+		{
+			name:    "flags override the file",
+			content: file,
+			args:    []string{"--display-name", "app", "--autoscaling-storage-target", "80"},
+			want: Spec{
+				Config: "regional-us-central1", DisplayName: "app",
+				MinProcessingUnits: 1000, MaxProcessingUnits: 5000, HighPriorityCPUTarget: 65, StorageUtilizationTarget: 80,
+			},
+		},
+		{
+			name:    "capacity flags replace the capacity of the file",
+			content: file,
+			args:    []string{"--processing-units", "2000"},
+			want: Spec{
+				Config: "regional-us-central1", DisplayName: "Load test",
+				ProcessingUnits: 2000, HighPriorityCPUTarget: 65, StorageUtilizationTarget: 95,
+			},
+		},
+		{
+			name:    "processing units and autoscaling",
+			content: "processingUnits: 1000\nautoscaling:\n  minProcessingUnits: 1000\n  maxProcessingUnits: 2000\n",
+			wantErr: true,
+		},
+		{name: "autoscaling without limits", content: "autoscaling:\n  storageTarget: 90\n", wantErr: true},
+		{name: "unknown field", content: "nodeCount: 1\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "instance.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			s, cmd := parse(t, append([]string{"--spec-file", path}, tt.args...)...)
+			err := s.LoadFile(cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Spec.LoadFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			tt.want.specFile = path
+			if *s != tt.want {
+				t.Errorf("Spec.LoadFile() = %+v, want %+v", *s, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package spannermigrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/errors/v5"
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// LinkDirs returns a single source URL containing the migrations of every directory in sourceURLs.
+// Multiple directories are hard linked into a temporary directory in the working directory, which
+// cleanup removes. When using multiple directories the first migration version of each directory
+// should resume where the previous directory ended.
+func LinkDirs(sourceURLs []string) (sourceURL string, cleanup func(), err error) {
+	if len(sourceURLs) == 1 {
+		return sourceURLs[0], func() {}, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "os.Getwd()")
+	}
+
+	tempAllMigrationsDirPath, err := os.MkdirTemp(cwd, "all_migrations")
+	if err != nil {
+		return "", nil, errors.Wrap(err, "os.MkdirTemp()")
+	}
+	cleanup = func() {
+		if err := os.RemoveAll(tempAllMigrationsDirPath); err != nil {
+			log.Printf("error: %v\n", errors.Wrap(err, "os.RemoveAll()"))
+		}
+	}
+
+	for _, migrationSourceURL := range sourceURLs {
+		migrationDirClean := strings.TrimPrefix(migrationSourceURL, "file://")
+		migrationDir, err := os.ReadDir(migrationDirClean)
+		if err != nil {
+			cleanup()
+
+			return "", nil, errors.Wrap(err, "os.ReadDir()")
+		}
+
+		for _, dirEntry := range migrationDir {
+			if dirEntry.IsDir() {
+				continue
+			}
+
+			oldPath := filepath.Join(migrationDirClean, dirEntry.Name())
+			newPath := filepath.Join(tempAllMigrationsDirPath, dirEntry.Name())
+
+			if err := os.Link(oldPath, newPath); err != nil {
+				cleanup()
+
+				return "", nil, errors.Wrap(err, "os.Link()")
+			}
+		}
+	}
+
+	return fmt.Sprintf("file://%s", tempAllMigrationsDirPath), cleanup, nil
+}
+
+// SchemaSet is a group of schema migration directories whose versions are recorded in their own migrations
+// table. The default set has an empty Name and uses the client's schema migrations table.
+type SchemaSet struct {
+	Name string
+	Dirs []string
+}
+
+// ParseSchemaDirs groups schema directory values into sets, in the order they are applied. A value of the form
+// <name>=<dir> is a set of its own, recorded in the table SchemaMigrations_<name>, e.g. platform=file://platform/schema.
+// Values without a name form the default set, which is applied at the position of the first of them.
+func ParseSchemaDirs(values []string) ([]SchemaSet, error) {
+	var sets []SchemaSet
+	defaultSet := -1
+	seen := make(map[string]bool)
+	for _, v := range values {
+		name, dir, ok := strings.Cut(v, "=")
+		if !ok || strings.ContainsAny(name, ":/") {
+			if defaultSet < 0 {
+				defaultSet = len(sets)
+				sets = append(sets, SchemaSet{})
+			}
+			sets[defaultSet].Dirs = append(sets[defaultSet].Dirs, v)
+
+			continue
+		}
+
+		if !schemaSetName(name) {
+			return nil, errors.Newf("invalid schema set name %q in %q: use letters, digits and underscores", name, v)
+		}
+		if seen[name] {
+			return nil, errors.Newf("schema set %q is given more than once", name)
+		}
+		seen[name] = true
+		sets = append(sets, SchemaSet{Name: name, Dirs: []string{dir}})
+	}
+
+	return sets, nil
+}
+
+// schemaSetName reports whether name can be used in a migrations table name
+func schemaSetName(name string) bool {
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && (r > unicode.MaxASCII || !unicode.IsLetter(r) && !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MigrateUpSchemaDirs runs the schema migrations of every directory in sourceURLs. Directories are grouped
+// into sets by [ParseSchemaDirs] and the directories of a set are combined with [LinkDirs]. Returns
+// [migrate.ErrNoChange] only when no set had migrations to apply.
+func (c *Client) MigrateUpSchemaDirs(ctx context.Context, sourceURLs []string) error {
+	sets, err := ParseSchemaDirs(sourceURLs)
+	if err != nil {
+		return err
+	}
+
+	var changed bool
+	for _, set := range sets {
+		if set.Name != "" {
+			log.Printf("Running schema migrations for schema set %s\n", set.Name)
+		}
+
+		sourceURL, cleanup, err := LinkDirs(set.Dirs)
+		if err != nil {
+			return err
+		}
+		err = c.migrateUpSchema(ctx, sourceURL, set.Name)
+		cleanup()
+		if errors.Is(err, migrate.ErrNoChange) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		changed = true
+	}
+
+	if !changed {
+		return errors.Wrap(migrate.ErrNoChange, "spannermigrate.Client.MigrateUpSchemaDirs()")
+	}
+
+	return nil
+}
+
+// schemaSetTable returns the migrations table of the named schema set
+func (c *Client) schemaSetTable(name string) string {
+	if name == "" {
+		return c.schemaMigrationsTable
+	}
+
+	return c.schemaMigrationsTable + "_" + name
+}
+
+// MigrateUpDataDirs runs [Client.MigrateUpData] with the migrations of every directory in sourceURLs. See [LinkDirs].
+func (c *Client) MigrateUpDataDirs(ctx context.Context, sourceURLs []string) error {
+	sourceURL, cleanup, err := LinkDirs(sourceURLs)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	return c.MigrateUpData(ctx, sourceURL)
+}
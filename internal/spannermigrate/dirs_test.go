@@ -0,0 +1,67 @@
+package spannermigrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSchemaDirs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		values  []string
+		want    []SchemaSet
+		wantErr bool
+	}{
+		{
+			name:   "default set only",
+			values: []string{"file://schema/a", "file://schema/b"},
+			want:   []SchemaSet{{Dirs: []string{"file://schema/a", "file://schema/b"}}},
+		},
+		{
+			name:   "named set first",
+			values: []string{"platform=file://platform/schema", "file://schema/migrations"},
+			want: []SchemaSet{
+				{Name: "platform", Dirs: []string{"file://platform/schema"}},
+				{Dirs: []string{"file://schema/migrations"}},
+			},
+		},
+		{
+			name:   "default set placed at first unnamed dir",
+			values: []string{"file://schema/a", "platform=file://platform/schema", "file://schema/b"},
+			want: []SchemaSet{
+				{Dirs: []string{"file://schema/a", "file://schema/b"}},
+				{Name: "platform", Dirs: []string{"file://platform/schema"}},
+			},
+		},
+		{
+			name:   "equals sign in path is not a name",
+			values: []string{"file://schema/v=2"},
+			want:   []SchemaSet{{Dirs: []string{"file://schema/v=2"}}},
+		},
+		{
+			name:    "invalid name",
+			values:  []string{"plat-form=file://platform/schema"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate name",
+			values:  []string{"platform=file://a", "platform=file://b"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseSchemaDirs(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSchemaDirs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSchemaDirs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package spannermigrate
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cloudspannerecosystem/memefish"
+	"github.com/cloudspannerecosystem/memefish/ast"
+	"github.com/go-playground/errors/v5"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+)
+
+// Schema holds the tables and their columns, and the indexes, views and change streams of a database by name.
+// Names are compared case-insensitively, as Spanner does. Only what [Schema.Drift] compares is kept: column types
+// and NOT NULL, but not options, constraints or the definitions of indexes and views.
+type Schema struct {
+	tables  map[string]*schemaTable
+	objects map[string]string
+}
+
+type schemaTable struct {
+	name    string
+	columns map[string]schemaColumn
+}
+
+type schemaColumn struct {
+	name string
+	def  string
+}
+
+// NewSchema returns an empty Schema
+func NewSchema() *Schema {
+	return &Schema{tables: make(map[string]*schemaTable), objects: make(map[string]string)}
+}
+
+// ApplyMigrations applies the up migrations in sourceURL up to and including the version recorded in state, so the
+// schema is the one the migrations would have created
+func (s *Schema) ApplyMigrations(sourceURL string, state MigrationState) error {
+	if state.Version == migratedb.NilVersion {
+		return nil
+	}
+
+	migrations, err := readMigrations(sourceURL, migratedb.NilVersion)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.version > uint(state.Version) {
+			break
+		}
+		if err := s.Apply(m.String(), m.stmts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Apply adds the tables, columns, indexes, views and change streams stmts create and removes the ones they drop.
+// Statements that do not change them are ignored. filePath is only used in error messages.
+func (s *Schema) Apply(filePath string, stmts []string) error {
+	for _, stmt := range stmts {
+		ddl, err := memefish.ParseDDL(filePath, stmt)
+		if err != nil {
+			return errors.Wrapf(err, "memefish.ParseDDL(): %s", filePath)
+		}
+		s.apply(ddl)
+	}
+
+	return nil
+}
+
+func (s *Schema) apply(ddl ast.DDL) {
+	switch ddl := ddl.(type) {
+	case *ast.CreateTable:
+		t := &schemaTable{name: pathName(ddl.Name), columns: make(map[string]schemaColumn)}
+		for _, c := range ddl.Columns {
+			t.add(c.Name.Name, columnDef(c.Type, c.NotNull))
+		}
+		s.tables[strings.ToLower(t.name)] = t
+	case *ast.DropTable:
+		delete(s.tables, strings.ToLower(pathName(ddl.Name)))
+	case *ast.RenameTable:
+		for _, to := range ddl.Tos {
+			s.rename(to.Old.Name, to.New.Name)
+		}
+	case *ast.AlterTable:
+		name := pathName(ddl.Name)
+		t, ok := s.tables[strings.ToLower(name)]
+		if !ok {
+			return
+		}
+		switch alt := ddl.TableAlteration.(type) {
+		case *ast.AddColumn:
+			t.add(alt.Column.Name.Name, columnDef(alt.Column.Type, alt.Column.NotNull))
+		case *ast.DropColumn:
+			delete(t.columns, strings.ToLower(alt.Name.Name))
+		case *ast.AlterColumn:
+			if typ, ok := alt.Alteration.(*ast.AlterColumnType); ok {
+				t.add(alt.Name.Name, columnDef(typ.Type, typ.NotNull))
+			}
+		case *ast.RenameTo:
+			s.rename(name, alt.Name.Name)
+		}
+	case *ast.CreateIndex:
+		s.add("index", pathName(ddl.Name))
+	case *ast.DropIndex:
+		s.drop("index", pathName(ddl.Name))
+	case *ast.CreateSearchIndex:
+		s.add("search index", ddl.Name.Name)
+	case *ast.DropSearchIndex:
+		s.drop("search index", ddl.Name.Name)
+	case *ast.CreateView:
+		s.add("view", pathName(ddl.Name))
+	case *ast.DropView:
+		s.drop("view", pathName(ddl.Name))
+	case *ast.CreateChangeStream:
+		s.add("change stream", ddl.Name.Name)
+	case *ast.DropChangeStream:
+		s.drop("change stream", ddl.Name.Name)
+	}
+}
+
+func (s *Schema) add(kind, name string) {
+	s.objects[kind+" "+strings.ToLower(name)] = kind + " " + name
+}
+
+func (s *Schema) drop(kind, name string) {
+	delete(s.objects, kind+" "+strings.ToLower(name))
+}
+
+func (s *Schema) rename(from, to string) {
+	t, ok := s.tables[strings.ToLower(from)]
+	if !ok {
+		return
+	}
+	delete(s.tables, strings.ToLower(from))
+	t.name = to
+	s.tables[strings.ToLower(to)] = t
+}
+
+func (t *schemaTable) add(name, def string) {
+	t.columns[strings.ToLower(name)] = schemaColumn{name: name, def: def}
+}
+
+// Drift compares the schema of a database with s, the schema its migrations create, and returns a line per
+// difference in name order. Tables that hold migration or lock state are not compared, see [IsDeploymentTable].
+func (s *Schema) Drift(database *Schema) []string {
+	var drift []string
+	for key, t := range s.tables {
+		if IsDeploymentTable(t.name) {
+			continue
+		}
+		live, ok := database.tables[key]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("table %s is missing", t.name))
+
+			continue
+		}
+		for key, c := range t.columns {
+			liveColumn, ok := live.columns[key]
+			switch {
+			case !ok:
+				drift = append(drift, fmt.Sprintf("column %s.%s is missing", t.name, c.name))
+			case liveColumn.def != c.def:
+				drift = append(drift, fmt.Sprintf("column %s.%s is %s, the migrations make it %s", t.name, c.name, liveColumn.def, c.def))
+			}
+		}
+		for key, c := range live.columns {
+			if _, ok := t.columns[key]; !ok {
+				drift = append(drift, fmt.Sprintf("column %s.%s is not created by the migrations", t.name, c.name))
+			}
+		}
+	}
+	for key, t := range database.tables {
+		if _, ok := s.tables[key]; !ok && !IsDeploymentTable(t.name) {
+			drift = append(drift, fmt.Sprintf("table %s is not created by the migrations", t.name))
+		}
+	}
+
+	for key, name := range s.objects {
+		if _, ok := database.objects[key]; !ok {
+			drift = append(drift, name+" is missing")
+		}
+	}
+	for key, name := range database.objects {
+		if _, ok := s.objects[key]; !ok {
+			drift = append(drift, name+" is not created by the migrations")
+		}
+	}
+	slices.Sort(drift)
+
+	return drift
+}
+
+func pathName(p *ast.Path) string {
+	names := make([]string, len(p.Idents))
+	for i, ident := range p.Idents {
+		names[i] = ident.Name
+	}
+
+	return strings.Join(names, ".")
+}
+
+func columnDef(typ ast.SchemaType, notNull bool) string {
+	if notNull {
+		return typ.SQL() + " NOT NULL"
+	}
+
+	return typ.SQL()
+}
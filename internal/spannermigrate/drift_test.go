@@ -0,0 +1,75 @@
+package spannermigrate
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSchema_Drift(t *testing.T) {
+	t.Parallel()
+
+	migrations := [][]string{
+		{
+			"CREATE TABLE Users (UserId INT64 NOT NULL, Email STRING(64)) PRIMARY KEY (UserId)",
+			"CREATE TABLE Legacy (Id INT64 NOT NULL) PRIMARY KEY (Id)",
+		},
+		{
+			"ALTER TABLE users ADD COLUMN Name STRING(MAX)",
+			"ALTER TABLE Users ALTER COLUMN Email STRING(128) NOT NULL",
+			"CREATE UNIQUE INDEX UsersByEmail ON Users (Email)",
+			"DROP TABLE Legacy",
+			"ALTER TABLE Users RENAME TO Accounts",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		database []string
+		want     []string
+	}{
+		{
+			name: "matches",
+			database: []string{
+				"CREATE TABLE Accounts (UserId INT64 NOT NULL, Email STRING(128) NOT NULL, Name STRING(MAX)) PRIMARY KEY (UserId)",
+				"CREATE UNIQUE INDEX UsersByEmail ON Accounts (Email)",
+				"CREATE TABLE SchemaMigrations (Version INT64 NOT NULL, Dirty BOOL NOT NULL) PRIMARY KEY (Version)",
+			},
+		},
+		{
+			name: "changed outside the migrations",
+			database: []string{
+				"CREATE TABLE Accounts (UserId INT64 NOT NULL, Email STRING(64), Phone STRING(16)) PRIMARY KEY (UserId)",
+				"CREATE INDEX AccountsByPhone ON Accounts (Phone)",
+				"CREATE TABLE Scratch (Id INT64 NOT NULL) PRIMARY KEY (Id)",
+			},
+			want: []string{
+				"column Accounts.Email is STRING(64), the migrations make it STRING(128) NOT NULL",
+				"column Accounts.Name is missing",
+				"column Accounts.Phone is not created by the migrations",
+				"index AccountsByPhone is not created by the migrations",
+				"index UsersByEmail is missing",
+				"table Scratch is not created by the migrations",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expected := NewSchema()
+			for _, stmts := range migrations {
+				if err := expected.Apply("migration", stmts); err != nil {
+					t.Fatalf("Schema.Apply() error = %v", err)
+				}
+			}
+			database := NewSchema()
+			if err := database.Apply("database", tt.database); err != nil {
+				t.Fatalf("Schema.Apply() error = %v", err)
+			}
+
+			if got := expected.Drift(database); !slices.Equal(got, tt.want) {
+				t.Errorf("Schema.Drift() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
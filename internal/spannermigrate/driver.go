@@ -0,0 +1,218 @@
+package spannermigrate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/spannersql"
+	"github.com/cccteam/deployment-tools/internal/spannertag"
+	"github.com/cloudspannerecosystem/memefish"
+	"github.com/go-playground/errors/v5"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+)
+
+// dataDriver wraps the spanner migrate driver to execute DML-only migrations with per-statement
+// timeouts, running statements flagged by the mutation check as partitioned DML. Migrations
+// containing any other statements are passed through to the wrapped driver, which does not
+// support the statement timeout.
+//
+// DML-only migrations are recorded in the history table in the same transaction as their last
+// statements. Migrations passed through to the wrapped driver are recorded once migrate marks
+// their version clean.
+type dataDriver struct {
+	migratedb.Driver
+	ctx         context.Context //nolint:containedctx // migratedb.Driver.Run() does not accept a context
+	c           *Client
+	pending     map[int]pendingMigration
+	partitioned map[string]bool
+	// version is the migration currently being applied
+	version int
+	started time.Time
+}
+
+// SetVersion implements database.Driver
+//
+// migrate marks a version dirty before running its migration and clean after it succeeds,
+// which brackets the time spent applying it.
+func (d *dataDriver) SetVersion(version int, dirty bool) error {
+	if err := d.Driver.SetVersion(version, dirty); err != nil {
+		return errors.Wrap(err, "database.Driver.SetVersion()")
+	}
+
+	p, ok := d.pending[version]
+	if !ok {
+		return nil
+	}
+
+	if dirty {
+		d.version = version
+		d.started = time.Now()
+
+		return nil
+	}
+
+	// the migration has already been applied, so a failure to record it must not fail the deployment
+	if err := d.c.recordHistory(d.ctx, p, time.Since(d.started)); err != nil {
+		log.Printf("ERROR: %v. Insert the history row manually to keep the audit trail complete.\n", err)
+	}
+	delete(d.pending, version)
+
+	return nil
+}
+
+// statusDriver reports the migration version being applied to the heartbeat
+type statusDriver struct {
+	migratedb.Driver
+	status *heartbeat.Status
+}
+
+// SetVersion implements database.Driver
+func (d *statusDriver) SetVersion(version int, dirty bool) error {
+	if dirty {
+		d.status.Set(fmt.Sprintf("applying version %d", version))
+	}
+
+	if err := d.Driver.SetVersion(version, dirty); err != nil {
+		return errors.Wrap(err, "database.Driver.SetVersion()")
+	}
+
+	return nil
+}
+
+// Run implements database.Driver
+func (d *dataDriver) Run(migration io.Reader) error {
+	b, err := io.ReadAll(migration)
+	if err != nil {
+		return errors.Wrap(err, "io.ReadAll()")
+	}
+
+	stmts, err := spannersql.Split("", string(b))
+	if err != nil {
+		return &migratedb.Error{OrigErr: err, Err: "migration failed", Query: b}
+	}
+
+	for _, stmt := range stmts {
+		if _, err := memefish.ParseDML("", stmt); err != nil {
+			// the wrapped driver runs without a context, so the statement timeout cannot be applied
+			if d.c.statementTimeout > 0 {
+				d.c.report.Warn("migration contains statements other than DML, running it without the statement timeout of %s: %s", d.c.statementTimeout, summarize(stmt))
+			}
+
+			return d.Driver.Run(bytes.NewReader(b))
+		}
+	}
+
+	txnStmts := make([]string, 0, len(stmts))
+	for _, stmt := range stmts {
+		if !d.partitioned[stmt] {
+			txnStmts = append(txnStmts, stmt)
+
+			continue
+		}
+
+		if err := d.runTransaction(txnStmts); err != nil {
+			return err
+		}
+		txnStmts = txnStmts[:0]
+
+		if err := d.runPartitioned(stmt); err != nil {
+			return err
+		}
+	}
+
+	var mutations []*spanner.Mutation
+	p, ok := d.pending[d.version]
+	if ok {
+		mutations = append(mutations, d.c.historyMutation(p, time.Since(d.started)))
+	}
+
+	if err := d.runTransaction(txnStmts, mutations...); err != nil {
+		return err
+	}
+	delete(d.pending, d.version)
+
+	return nil
+}
+
+// runTransaction executes the statements and applies the mutations in a single read-write transaction
+func (d *dataDriver) runTransaction(stmts []string, mutations ...*spanner.Mutation) error {
+	if len(stmts) == 0 && len(mutations) == 0 {
+		return nil
+	}
+
+	tag := migrationTag(d.version)
+	var failed string
+	if _, err := d.c.client.ReadWriteTransactionWithOptions(d.ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		for _, stmt := range stmts {
+			failed = stmt
+			if err := d.c.withStatementTimeout(ctx, func(ctx context.Context) error {
+				if _, err := txn.UpdateWithOptions(ctx, spanner.Statement{SQL: stmt}, spanner.QueryOptions{RequestTag: tag}); err != nil {
+					return errors.Wrap(err, "spanner.ReadWriteTransaction.UpdateWithOptions()")
+				}
+
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+
+		if err := txn.BufferWrite(mutations); err != nil {
+			return errors.Wrap(err, "spanner.ReadWriteTransaction.BufferWrite()")
+		}
+
+		return nil
+	}, spanner.TransactionOptions{TransactionTag: tag}); err != nil {
+		return &migratedb.Error{OrigErr: err, Err: "migration failed", Query: []byte(failed)}
+	}
+
+	return nil
+}
+
+func (d *dataDriver) runPartitioned(stmt string) error {
+	d.c.report.Warn("executing statement as partitioned DML outside the migration transaction: %s", summarize(stmt))
+
+	if err := d.c.withStatementTimeout(d.ctx, func(ctx context.Context) error {
+		if _, err := d.c.client.PartitionedUpdateWithOptions(ctx, spanner.Statement{SQL: stmt}, spanner.QueryOptions{RequestTag: migrationTag(d.version)}); err != nil {
+			return errors.Wrap(err, "spanner.Client.PartitionedUpdateWithOptions()")
+		}
+
+		return nil
+	}); err != nil {
+		return &migratedb.Error{OrigErr: err, Err: "partitioned migration failed", Query: []byte(stmt)}
+	}
+
+	return nil
+}
+
+// migrationTag returns the request and transaction tag of the data migration version
+func migrationTag(version int) string {
+	return spannertag.Tag("migration", fmt.Sprintf("v%d", version))
+}
+
+// statementContext returns a context bounded by the statement timeout, if one is set
+func (c *Client) statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.statementTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, c.statementTimeout)
+}
+
+// withStatementTimeout runs fn with the statement timeout applied, reporting when the timeout was exceeded
+func (c *Client) withStatementTimeout(ctx context.Context, fn func(ctx context.Context) error) error {
+	sctx, cancel := c.statementContext(ctx)
+	defer cancel()
+
+	err := fn(sctx)
+	if err != nil && errors.Is(sctx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return errors.Wrapf(err, "statement exceeded the timeout of %s", c.statementTimeout)
+	}
+
+	return err
+}
@@ -0,0 +1,147 @@
+package spannermigrate
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/go-playground/errors/v5"
+	"google.golang.org/api/iterator"
+)
+
+// dropQueries returns queries that generate the DDL to drop every object in the schema, in dependency order:
+// views, foreign key constraints, search indexes, indexes, then tables (interleaved children first).
+//
+// The spanner emulator reports search indexes with an index_type of 'INDEX', so they are dropped as indexes there.
+func dropQueries() []string {
+	return []string{
+		`SELECT CONCAT('DROP VIEW ` + "`" + `', TABLE_NAME, '` + "`" + `') AS ddl
+	FROM information_schema.tables
+	WHERE NOT TABLE_SCHEMA IN('INFORMATION_SCHEMA', 'SPANNER_SYS')
+	  AND TABLE_TYPE = 'VIEW'
+	ORDER BY TABLE_NAME`,
+		`SELECT CONCAT(
+		'ALTER TABLE ',
+		CASE
+			WHEN tc.table_schema = '' THEN CONCAT('` + "`" + `', tc.table_name, '` + "`" + `')
+			ELSE CONCAT('` + "`" + `', tc.table_schema, '` + "`.`" + `', tc.table_name, '` + "`" + `')
+		END,
+		' DROP CONSTRAINT ` + "`" + `', tc.constraint_name, '` + "`" + `'
+	) AS ddl
+	FROM information_schema.table_constraints tc
+	WHERE tc.constraint_type = 'FOREIGN KEY'
+		AND NOT CONSTRAINT_SCHEMA IN('INFORMATION_SCHEMA', 'SPANNER_SYS')
+	ORDER BY tc.table_schema, tc.table_name, tc.constraint_name`,
+		`SELECT CONCAT('DROP SEARCH INDEX ` + "`" + `', idx.index_name, '` + "`" + `') AS ddl
+	FROM information_schema.indexes idx
+	WHERE idx.index_type = 'SEARCH'
+		AND NOT TABLE_SCHEMA IN('INFORMATION_SCHEMA', 'SPANNER_SYS')
+	ORDER BY idx.table_schema, idx.table_name, idx.index_name`,
+		`SELECT CONCAT('DROP INDEX IF EXISTS ` + "`" + `', idx.index_name, '` + "`" + `') AS ddl
+	FROM information_schema.indexes idx
+	WHERE idx.index_type = 'INDEX'
+		AND NOT TABLE_SCHEMA IN('INFORMATION_SCHEMA', 'SPANNER_SYS')
+	ORDER BY idx.table_schema, idx.table_name, idx.index_name`,
+		`WITH t AS (
+		SELECT table_name, parent_table_name
+		FROM information_schema.tables
+		WHERE NOT TABLE_SCHEMA IN('INFORMATION_SCHEMA', 'SPANNER_SYS')
+		  AND table_type = 'BASE TABLE'
+	),
+	d AS (
+		SELECT
+			c.table_name,
+			CAST(p1.table_name IS NOT NULL AS INT64) +
+			CAST(p2.table_name IS NOT NULL AS INT64) +
+			CAST(p3.table_name IS NOT NULL AS INT64) +
+			CAST(p4.table_name IS NOT NULL AS INT64) +
+			CAST(p5.table_name IS NOT NULL AS INT64) +
+			CAST(p6.table_name IS NOT NULL AS INT64) +
+			CAST(p7.table_name IS NOT NULL AS INT64) AS depth
+		FROM t c
+		LEFT JOIN t p1 ON c.parent_table_name = p1.table_name
+		LEFT JOIN t p2 ON p1.parent_table_name = p2.table_name
+		LEFT JOIN t p3 ON p2.parent_table_name = p3.table_name
+		LEFT JOIN t p4 ON p3.parent_table_name = p4.table_name
+		LEFT JOIN t p5 ON p4.parent_table_name = p5.table_name
+		LEFT JOIN t p6 ON p5.parent_table_name = p6.table_name
+		LEFT JOIN t p7 ON p6.parent_table_name = p7.table_name
+	)
+	SELECT CONCAT('DROP TABLE ` + "`" + `', table_name, '` + "`" + `') AS ddl
+	FROM d
+	ORDER BY depth DESC, table_name`,
+	}
+}
+
+// MigrateDropSchema drops all objects in the schema
+//
+// This happens in the following order:
+//  1. Drop views
+//  2. Drop FK constraints
+//  3. Drop Search Indexes
+//  4. Drop Indexes
+//  5. Drop tables
+//
+// The deployment lock table is not dropped.
+func (c *Client) MigrateDropSchema(ctx context.Context) error {
+	var stmts []string
+	for _, query := range dropQueries() {
+		ddl, err := c.ddlStatements(ctx, query)
+		if err != nil {
+			return err
+		}
+		stmts = append(stmts, ddl...)
+	}
+
+	// deployment locks must survive a rebuild of the schema
+	stmts = slices.DeleteFunc(stmts, func(stmt string) bool {
+		return stmt == "DROP TABLE `"+LockTable+"`"
+	})
+
+	if len(stmts) == 0 {
+		log.Println("No database objects found to drop")
+
+		return nil
+	}
+
+	op, err := c.admin.UpdateDatabaseDdl(ctx, &databasepb.UpdateDatabaseDdlRequest{
+		Database:   c.dbStr,
+		Statements: stmts,
+	})
+	if err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.UpdateDatabaseDdl()")
+	}
+	if err := op.Wait(ctx); err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "database.UpdateDatabaseDdlOperation.Wait()")
+	}
+
+	return nil
+}
+
+// ddlStatements runs a query returning a single column of DDL statements
+func (c *Client) ddlStatements(ctx context.Context, query string) ([]string, error) {
+	iter := c.client.Single().Query(ctx, spanner.NewStatement(query))
+	defer iter.Stop()
+
+	var stmts []string
+	for {
+		row, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "spanner.RowIterator.Next()")
+		}
+
+		var stmt string
+		if err := row.Columns(&stmt); err != nil {
+			return nil, errors.Wrap(err, "spanner.Row.Columns()")
+		}
+		stmts = append(stmts, stmt)
+	}
+
+	return stmts, nil
+}
@@ -0,0 +1,170 @@
+package spannermigrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/memefish"
+	"github.com/cloudspannerecosystem/memefish/ast"
+	"github.com/go-playground/errors/v5"
+)
+
+// statementEstimate is the estimated number of mutations a single DML statement produces
+type statementEstimate struct {
+	sql       string
+	mutations int64
+	// partitionable is true for statements that can be executed as partitioned DML
+	partitionable bool
+}
+
+// checkMutations estimates the mutations produced by each pending data migration and returns an error
+// describing every migration that would exceed the mutation limit. The returned set contains the statements
+// that should be executed as partitioned DML instead of inside the migration transaction.
+//
+// Every migration is estimated against the current state of the database. A migration that updates or
+// deletes rows inserted by an earlier pending migration sees none of those rows, so a large backfill that
+// follows the migration loading its data is underestimated and passes the check.
+func (c *Client) checkMutations(ctx context.Context, pending []pendingMigration) (map[string]bool, error) {
+	partitioned := make(map[string]bool)
+	if c.mutationLimit <= 0 {
+		return partitioned, nil
+	}
+
+	var violations []string
+	for _, p := range pending {
+		estimates, err := c.estimate(ctx, p.stmts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to estimate mutations for data migration %s", p)
+		}
+
+		var total int64
+		for _, e := range estimates {
+			if c.partitionLargeDML && e.partitionable && e.mutations > c.mutationLimit {
+				partitioned[e.sql] = true
+
+				continue
+			}
+			total += e.mutations
+		}
+
+		if total > c.mutationLimit {
+			violations = append(violations, describeViolation(p.String(), total, estimates, partitioned))
+		}
+	}
+
+	if len(violations) > 0 {
+		return nil, errors.Newf(
+			"%d data migration(s) are estimated to exceed the limit of %d mutations per transaction:\n%s\n"+
+				"Estimates are made against the database as it is now, so rows written by earlier pending migrations are not counted.\n"+
+				"Split large statements into smaller key ranges across separate migration files, "+
+				"or rerun with --partition-large-dml to execute oversized UPDATE and DELETE statements as partitioned DML",
+			len(violations), c.mutationLimit, strings.Join(violations, "\n"),
+		)
+	}
+
+	return partitioned, nil
+}
+
+// estimate returns the estimated mutations of each statement. Migrations containing statements
+// other than DML are run by the migrate driver as-is, so no estimate is returned for them.
+func (c *Client) estimate(ctx context.Context, stmts []string) ([]statementEstimate, error) {
+	estimates := make([]statementEstimate, 0, len(stmts))
+	for _, stmt := range stmts {
+		dml, err := memefish.ParseDML("", stmt)
+		if err != nil {
+			return nil, nil //nolint:nilerr // not a DML only migration
+		}
+
+		e, err := c.estimateDML(ctx, stmt, dml)
+		if err != nil {
+			return nil, err
+		}
+		estimates = append(estimates, e)
+	}
+
+	return estimates, nil
+}
+
+// estimateDML estimates mutations as the number of affected rows multiplied by the number of columns written.
+// Secondary index entries are not counted, so the estimate is a lower bound.
+func (c *Client) estimateDML(ctx context.Context, stmt string, dml ast.DML) (statementEstimate, error) {
+	e := statementEstimate{sql: stmt}
+
+	switch d := dml.(type) {
+	case *ast.Insert:
+		switch input := d.Input.(type) {
+		case *ast.ValuesInput:
+			e.mutations = int64(len(input.Rows) * len(d.Columns))
+		case *ast.SubQueryInput:
+			rows, err := c.count(ctx, "SELECT COUNT(*) FROM ("+input.Query.SQL()+")")
+			if err != nil {
+				return e, err
+			}
+			e.mutations = rows * int64(len(d.Columns))
+		}
+	case *ast.Update:
+		rows, err := c.count(ctx, countSQL(d.TableName, d.As, d.Where))
+		if err != nil {
+			return e, err
+		}
+		e.mutations = rows * int64(len(d.Updates))
+		e.partitionable = true
+	case *ast.Delete:
+		rows, err := c.count(ctx, countSQL(d.TableName, d.As, d.Where))
+		if err != nil {
+			return e, err
+		}
+		e.mutations = rows
+		e.partitionable = true
+	}
+
+	return e, nil
+}
+
+func countSQL(table *ast.Path, as *ast.AsAlias, where *ast.Where) string {
+	sql := "SELECT COUNT(*) FROM " + table.SQL()
+	if as != nil {
+		sql += " " + as.SQL()
+	}
+
+	return sql + " " + where.SQL()
+}
+
+func (c *Client) count(ctx context.Context, sql string) (int64, error) {
+	ctx, cancel := c.statementContext(ctx)
+	defer cancel()
+
+	var count int64
+	if err := c.client.Single().Query(ctx, spanner.Statement{SQL: sql}).Do(func(r *spanner.Row) error {
+		return r.Column(0, &count)
+	}); err != nil {
+		return 0, errors.Wrapf(err, "spanner.RowIterator.Do(): %s", sql)
+	}
+
+	return count, nil
+}
+
+func describeViolation(migration string, total int64, estimates []statementEstimate, partitioned map[string]bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %s: ~%d mutations", migration, total)
+	for _, e := range estimates {
+		if partitioned[e.sql] {
+			continue
+		}
+		fmt.Fprintf(&b, "\n    ~%d mutations: %s", e.mutations, summarize(e.sql))
+	}
+
+	return b.String()
+}
+
+// summarize returns the first line of a statement, truncated for display
+func summarize(stmt string) string {
+	line, _, _ := strings.Cut(stmt, "\n")
+	if len(line) > 80 {
+		return line[:77] + "..."
+	}
+
+	return line
+}
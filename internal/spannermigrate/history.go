@@ -0,0 +1,123 @@
+package spannermigrate
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	adminpb "cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/go-playground/errors/v5"
+)
+
+// HistoryRecord is a data migration that was applied to the database
+type HistoryRecord struct {
+	Version     int64
+	Name        string
+	Checksum    string
+	Duration    time.Duration
+	Environment string
+	AppliedAt   time.Time
+}
+
+// DataMigrationHistory returns the most recently applied data migrations, newest first.
+// No records are returned when the history table does not exist yet.
+func (c *Client) DataMigrationHistory(ctx context.Context, limit int64) ([]HistoryRecord, error) {
+	exists, err := tableExists(ctx, c.client, c.dataMigrationHistoryTable)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	stmt := spanner.Statement{
+		SQL: `SELECT Version, Name, Checksum, DurationMs, Environment, AppliedAt
+			FROM ` + c.dataMigrationHistoryTable + `
+			ORDER BY AppliedAt DESC, Version DESC
+			LIMIT @limit`,
+		Params: map[string]any{"limit": limit},
+	}
+
+	var records []HistoryRecord
+	if err := c.client.Single().Query(ctx, stmt).Do(func(r *spanner.Row) error {
+		var rec HistoryRecord
+		var durationMs int64
+		if err := r.Columns(&rec.Version, &rec.Name, &rec.Checksum, &durationMs, &rec.Environment, &rec.AppliedAt); err != nil {
+			return errors.Wrap(err, "spanner.Row.Columns()")
+		}
+		rec.Duration = time.Duration(durationMs) * time.Millisecond
+		records = append(records, rec)
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	return records, nil
+}
+
+// ensureHistoryTable creates the data migration history table if it does not exist
+func (c *Client) ensureHistoryTable(ctx context.Context) error {
+	exists, err := tableExists(ctx, c.client, c.dataMigrationHistoryTable)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	op, err := c.admin.UpdateDatabaseDdl(ctx, &adminpb.UpdateDatabaseDdlRequest{
+		Database: c.dbStr,
+		Statements: []string{
+			`CREATE TABLE ` + c.dataMigrationHistoryTable + ` (
+				Version INT64 NOT NULL,
+				Name STRING(MAX) NOT NULL,
+				Checksum STRING(64) NOT NULL,
+				DurationMs INT64 NOT NULL,
+				Environment STRING(MAX) NOT NULL,
+				AppliedAt TIMESTAMP NOT NULL OPTIONS (allow_commit_timestamp=true),
+			) PRIMARY KEY (Version, AppliedAt)`,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.UpdateDatabaseDdl()")
+	}
+	if err := op.Wait(ctx); err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "database.UpdateDatabaseDdlOperation.Wait()")
+	}
+
+	return nil
+}
+
+func (c *Client) recordHistory(ctx context.Context, p pendingMigration, duration time.Duration) error {
+	if _, err := c.client.Apply(ctx, []*spanner.Mutation{c.historyMutation(p, duration)}, spanner.TransactionTag(migrationTag(int(p.version)))); err != nil {
+		return errors.Wrapf(err, "data migration %s was applied but could not be recorded in %s", p, c.dataMigrationHistoryTable)
+	}
+
+	return nil
+}
+
+func (c *Client) historyMutation(p pendingMigration, duration time.Duration) *spanner.Mutation {
+	return spanner.Insert(c.dataMigrationHistoryTable,
+		[]string{"Version", "Name", "Checksum", "DurationMs", "Environment", "AppliedAt"},
+		[]any{int64(p.version), p.identifier, p.checksum, duration.Milliseconds(), c.environment, spanner.CommitTimestamp},
+	)
+}
+
+// tableExists reports whether table exists, using a single-use read-only transaction
+func tableExists(ctx context.Context, client *spanner.Client, table string) (bool, error) {
+	stmt := spanner.Statement{
+		SQL:    `SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = '' AND TABLE_NAME = @table`,
+		Params: map[string]any{"table": table},
+	}
+
+	var count int64
+	if err := client.Single().Query(ctx, stmt).Do(func(r *spanner.Row) error {
+		return r.Column(0, &count)
+	}); err != nil {
+		return false, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	return count > 0, nil
+}
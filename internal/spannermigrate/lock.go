@@ -0,0 +1,206 @@
+package spannermigrate
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	adminpb "cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/spannertag"
+	"github.com/go-playground/errors/v5"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// LockTable holds deployment locks. It is kept when the schema is dropped.
+	LockTable = "DeploymentLocks"
+
+	// MaintenanceLock is held while a database is rebuilt
+	MaintenanceLock = "maintenance"
+
+	// MigrationLock is held while migrations are applied by bootstrap or migrate-all
+	MigrationLock = "migration"
+
+	// DefaultLockTTL is how long a lock is held if its holder dies without releasing it
+	DefaultLockTTL = 2 * time.Hour
+)
+
+// conflictingLocks lists the locks that can not be acquired while another is held. They are checked in the same
+// transaction that takes the lock, so a reseed can not drop the schema under a running migration or the reverse.
+var conflictingLocks = map[string][]string{
+	MaintenanceLock: {MigrationLock},
+	MigrationLock:   {MaintenanceLock},
+}
+
+// LockHolder returns a holder name for a lock taken by command, unique to this process so two builds never share
+// a lock. The build ID is included when set.
+func LockHolder(command, buildID string) string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+
+	if buildID != "" {
+		return fmt.Sprintf("%s (build %s, %x)", command, buildID, b)
+	}
+
+	return fmt.Sprintf("%s (%x)", command, b)
+}
+
+// lockPollInterval is how often a held lock is retried while waiting for it
+const lockPollInterval = 15 * time.Second
+
+// LockedError is returned when a lock is held by another holder
+type LockedError struct {
+	Name      string
+	Holder    string
+	ExpiresAt time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("database is locked for %s by %s until %s", e.Name, e.Holder, e.ExpiresAt.UTC().Format(time.RFC3339))
+}
+
+// rowReader is implemented by spanner transactions
+type rowReader interface {
+	ReadRow(ctx context.Context, table string, key spanner.Key, columns []string) (*spanner.Row, error)
+}
+
+// AcquireLock takes the named lock for holder until ttl elapses. It fails if another holder has an unexpired lock
+// on it or on a conflicting lock.
+func (c *Client) AcquireLock(ctx context.Context, name, holder string, ttl time.Duration) error {
+	if err := c.ensureLockTable(ctx); err != nil {
+		return err
+	}
+
+	if _, err := c.client.ReadWriteTransactionWithOptions(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		for _, n := range append([]string{name}, conflictingLocks[name]...) {
+			if err := checkLock(ctx, txn, n, holder); err != nil {
+				return err
+			}
+		}
+
+		return txn.BufferWrite([]*spanner.Mutation{
+			spanner.InsertOrUpdate(LockTable,
+				[]string{"Name", "Holder", "AcquiredAt", "ExpiresAt"},
+				[]any{name, holder, spanner.CommitTimestamp, time.Now().Add(ttl)},
+			),
+		})
+	}, spanner.TransactionOptions{TransactionTag: spannertag.Tag("lock", name)}); err != nil {
+		return errors.Wrap(err, "spanner.Client.ReadWriteTransactionWithOptions()")
+	}
+
+	return nil
+}
+
+// AcquireLockWait is AcquireLock, retrying for up to wait while the lock is held by another holder, so concurrent
+// deploys of a database queue instead of failing. A wait of zero fails fast like AcquireLock.
+func (c *Client) AcquireLockWait(ctx context.Context, name, holder string, ttl, wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+	for {
+		err := c.AcquireLock(ctx, name, holder, ttl)
+		if err == nil {
+			return nil
+		}
+
+		var locked *LockedError
+		if !errors.As(err, &locked) || !time.Now().Before(deadline) {
+			return errors.Wrap(err, "Client.AcquireLock()")
+		}
+
+		log.Printf("Waiting for the %s lock held by %s, giving up at %s\n", locked.Name, locked.Holder, deadline.UTC().Format(time.RFC3339))
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "waiting for lock")
+		case <-time.After(min(lockPollInterval, time.Until(deadline))):
+		}
+	}
+}
+
+// ReleaseLock releases the named lock if it is held by holder
+func (c *Client) ReleaseLock(ctx context.Context, name, holder string) error {
+	if _, err := c.client.ReadWriteTransactionWithOptions(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		row, err := txn.ReadRow(ctx, LockTable, spanner.Key{name}, []string{"Holder"})
+		if spanner.ErrCode(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "spanner.ReadWriteTransaction.ReadRow()")
+		}
+
+		var current string
+		if err := row.Columns(&current); err != nil {
+			return errors.Wrap(err, "spanner.Row.Columns()")
+		}
+		if current != holder {
+			return nil
+		}
+
+		return txn.BufferWrite([]*spanner.Mutation{spanner.Delete(LockTable, spanner.Key{name})})
+	}, spanner.TransactionOptions{TransactionTag: spannertag.Tag("lock", name)}); err != nil {
+		return errors.Wrap(err, "spanner.Client.ReadWriteTransactionWithOptions()")
+	}
+
+	return nil
+}
+
+// checkLock returns an error if the named lock is held by anyone other than holder and has not expired
+func checkLock(ctx context.Context, txn rowReader, name, holder string) error {
+	row, err := txn.ReadRow(ctx, LockTable, spanner.Key{name}, []string{"Holder", "ExpiresAt"})
+	if spanner.ErrCode(err) == codes.NotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "spanner.ReadRow()")
+	}
+
+	var current string
+	var expiresAt time.Time
+	if err := row.Columns(&current, &expiresAt); err != nil {
+		return errors.Wrap(err, "spanner.Row.Columns()")
+	}
+
+	if current != holder && time.Now().Before(expiresAt) {
+		return &LockedError{Name: name, Holder: current, ExpiresAt: expiresAt}
+	}
+
+	return nil
+}
+
+// ensureLockTable creates the lock table if it does not exist
+func (c *Client) ensureLockTable(ctx context.Context) error {
+	exists, err := tableExists(ctx, c.client, LockTable)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	op, err := c.admin.UpdateDatabaseDdl(ctx, &adminpb.UpdateDatabaseDdlRequest{
+		Database: c.dbStr,
+		Statements: []string{
+			`CREATE TABLE ` + LockTable + ` (
+				Name STRING(MAX) NOT NULL,
+				Holder STRING(MAX) NOT NULL,
+				AcquiredAt TIMESTAMP NOT NULL OPTIONS (allow_commit_timestamp=true),
+				ExpiresAt TIMESTAMP NOT NULL,
+			) PRIMARY KEY (Name)`,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.UpdateDatabaseDdl()")
+	}
+	if err := op.Wait(ctx); err != nil {
+		// another process may have created it first
+		if exists, existsErr := tableExists(ctx, c.client, LockTable); existsErr == nil && exists {
+			return nil
+		}
+
+		return errors.Wrap(deployerr.FromSpanner(err), "database.UpdateDatabaseDdlOperation.Wait()")
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package spannermigrate
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/go-playground/errors/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeRowReader struct {
+	row *spanner.Row
+	err error
+}
+
+func (f fakeRowReader) ReadRow(context.Context, string, spanner.Key, []string) (*spanner.Row, error) {
+	return f.row, f.err
+}
+
+func lockRow(t *testing.T, holder string, expiresAt time.Time) *spanner.Row {
+	t.Helper()
+
+	row, err := spanner.NewRow([]string{"Holder", "ExpiresAt"}, []any{holder, expiresAt})
+	if err != nil {
+		t.Fatalf("spanner.NewRow() error = %v", err)
+	}
+
+	return row
+}
+
+func Test_checkLock(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		reader  fakeRowReader
+		holder  string
+		wantErr bool
+	}{
+		{
+			name:   "no lock",
+			reader: fakeRowReader{err: status.Error(codes.NotFound, "row not found")},
+		},
+		{
+			name:    "held by someone else",
+			reader:  fakeRowReader{row: lockRow(t, "reseed", time.Now().Add(time.Hour))},
+			wantErr: true,
+		},
+		{
+			name:   "held by someone else but expired",
+			reader: fakeRowReader{row: lockRow(t, "reseed", time.Now().Add(-time.Minute))},
+		},
+		{
+			name:   "held by holder",
+			reader: fakeRowReader{row: lockRow(t, "reseed", time.Now().Add(time.Hour))},
+			holder: "reseed",
+		},
+		{
+			name:    "read error",
+			reader:  fakeRowReader{err: status.Error(codes.Unavailable, "unavailable")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkLock(context.Background(), tt.reader, "maintenance", tt.holder)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkLock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var locked *LockedError
+			if isLocked := errors.As(err, &locked); isLocked != (tt.name == "held by someone else") {
+				t.Errorf("checkLock() error is LockedError = %v, want only for a held lock", isLocked)
+			}
+		})
+	}
+}
+
+func TestLockHolder(t *testing.T) {
+	t.Parallel()
+
+	a, b := LockHolder("bootstrap", "1234"), LockHolder("bootstrap", "1234")
+	if a == b {
+		t.Errorf("LockHolder() returned %q twice, want a holder unique to each call", a)
+	}
+	if !strings.HasPrefix(a, "bootstrap (build 1234, ") {
+		t.Errorf("LockHolder() = %q, want the command and build ID", a)
+	}
+}
@@ -0,0 +1,152 @@
+package spannermigrate
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	adminpb "cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/go-playground/errors/v5"
+	"github.com/spf13/cobra"
+)
+
+// OptimizerOptions are the database's default query optimizer version and statistics package.
+// Zero values are not managed.
+type OptimizerOptions struct {
+	Version           int64
+	StatisticsPackage string
+}
+
+// AddFlags registers the optimizer option flags on cmd
+func (o *OptimizerOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().Int64Var(&o.Version, "optimizer-version", 0, "Default query optimizer version of the database. Zero leaves it unmanaged.")
+	cmd.Flags().
+		StringVar(&o.StatisticsPackage, "optimizer-statistics-package", "", "Default query optimizer statistics package of the database. Empty leaves it unmanaged.")
+}
+
+// ValidateFlags checks the options set by the flags registered with AddFlags
+func (o OptimizerOptions) ValidateFlags() error {
+	if o.Version < 0 {
+		return errors.Newf("--optimizer-version must not be negative, got %d", o.Version)
+	}
+
+	return nil
+}
+
+// IsZero reports whether no option is set
+func (o OptimizerOptions) IsZero() bool {
+	return o.Version == 0 && o.StatisticsPackage == ""
+}
+
+// String implements fmt.Stringer
+func (o OptimizerOptions) String() string {
+	pkg := "default"
+	if o.StatisticsPackage != "" {
+		pkg = o.StatisticsPackage
+	}
+
+	return fmt.Sprintf("optimizer_version=%s, optimizer_statistics_package=%s", orDefault(o.Version), pkg)
+}
+
+// mismatches describes each option set on o that differs from current
+func (o OptimizerOptions) mismatches(current OptimizerOptions) []string {
+	var m []string
+	if o.Version != 0 && o.Version != current.Version {
+		m = append(m, fmt.Sprintf("optimizer_version is %s, want %d", orDefault(current.Version), o.Version))
+	}
+	if o.StatisticsPackage != "" && o.StatisticsPackage != current.StatisticsPackage {
+		m = append(m, fmt.Sprintf("optimizer_statistics_package is %q, want %q", current.StatisticsPackage, o.StatisticsPackage))
+	}
+
+	return m
+}
+
+// OptimizerOptions returns the optimizer options currently set on the database.
+// Options left at the Spanner default are returned as zero values.
+func (c *Client) OptimizerOptions(ctx context.Context) (OptimizerOptions, error) {
+	stmt := spanner.Statement{SQL: `SELECT OPTION_NAME, OPTION_VALUE
+		FROM INFORMATION_SCHEMA.DATABASE_OPTIONS
+		WHERE SCHEMA_NAME = '' AND OPTION_NAME IN ('optimizer_version', 'optimizer_statistics_package')`}
+
+	var opts OptimizerOptions
+	if err := c.client.Single().Query(ctx, stmt).Do(func(r *spanner.Row) error {
+		var name, value string
+		if err := r.Columns(&name, &value); err != nil {
+			return errors.Wrap(err, "spanner.Row.Columns()")
+		}
+
+		switch name {
+		case "optimizer_version":
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return errors.Wrapf(err, "strconv.ParseInt(): optimizer_version %q", value)
+			}
+			opts.Version = v
+		case "optimizer_statistics_package":
+			opts.StatisticsPackage = value
+		}
+
+		return nil
+	}); err != nil {
+		return OptimizerOptions{}, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	return opts, nil
+}
+
+// SetOptimizerOptions sets each option of desired that differs from the database and reports whether anything changed
+func (c *Client) SetOptimizerOptions(ctx context.Context, desired OptimizerOptions) (bool, error) {
+	current, err := c.OptimizerOptions(ctx)
+	if err != nil {
+		return false, err
+	}
+	if len(desired.mismatches(current)) == 0 {
+		return false, nil
+	}
+
+	var set []string
+	if desired.Version != 0 {
+		set = append(set, fmt.Sprintf("optimizer_version = %d", desired.Version))
+	}
+	if desired.StatisticsPackage != "" {
+		set = append(set, fmt.Sprintf("optimizer_statistics_package = %s", strconv.Quote(desired.StatisticsPackage)))
+	}
+
+	op, err := c.admin.UpdateDatabaseDdl(ctx, &adminpb.UpdateDatabaseDdlRequest{
+		Database:   c.dbStr,
+		Statements: []string{fmt.Sprintf("ALTER DATABASE `%s` SET OPTIONS (%s)", c.dbName, strings.Join(set, ", "))},
+	})
+	if err != nil {
+		return false, errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.UpdateDatabaseDdl()")
+	}
+	if err := op.Wait(ctx); err != nil {
+		return false, errors.Wrap(deployerr.FromSpanner(err), "database.UpdateDatabaseDdlOperation.Wait()")
+	}
+
+	return true, nil
+}
+
+// VerifyOptimizerOptions returns an error describing each option of desired that differs from the database
+func (c *Client) VerifyOptimizerOptions(ctx context.Context, desired OptimizerOptions) error {
+	current, err := c.OptimizerOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	if m := desired.mismatches(current); len(m) > 0 {
+		return errors.Newf("database optimizer options have drifted: %s", strings.Join(m, "; "))
+	}
+
+	return nil
+}
+
+func orDefault(version int64) string {
+	if version == 0 {
+		return "default"
+	}
+
+	return strconv.FormatInt(version, 10)
+}
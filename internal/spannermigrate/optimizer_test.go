@@ -0,0 +1,55 @@
+package spannermigrate
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOptimizerOptions_mismatches(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		desired OptimizerOptions
+		current OptimizerOptions
+		want    []string
+	}{
+		{
+			name:    "unmanaged",
+			desired: OptimizerOptions{},
+			current: OptimizerOptions{Version: 7, StatisticsPackage: "auto_20240101"},
+		},
+		{
+			name:    "matching",
+			desired: OptimizerOptions{Version: 7, StatisticsPackage: "auto_20240101"},
+			current: OptimizerOptions{Version: 7, StatisticsPackage: "auto_20240101"},
+		},
+		{
+			name:    "version left at default",
+			desired: OptimizerOptions{Version: 7},
+			current: OptimizerOptions{},
+			want:    []string{"optimizer_version is default, want 7"},
+		},
+		{
+			name:    "only statistics package managed",
+			desired: OptimizerOptions{StatisticsPackage: "auto_20240101"},
+			current: OptimizerOptions{Version: 6, StatisticsPackage: "auto_20230101"},
+			want:    []string{`optimizer_statistics_package is "auto_20230101", want "auto_20240101"`},
+		},
+		{
+			name:    "both differ",
+			desired: OptimizerOptions{Version: 7, StatisticsPackage: "auto_20240101"},
+			current: OptimizerOptions{Version: 6},
+			want:    []string{"optimizer_version is 6, want 7", `optimizer_statistics_package is "", want "auto_20240101"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.desired.mismatches(tt.current); !slices.Equal(got, tt.want) {
+				t.Errorf("OptimizerOptions.mismatches() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package spannermigrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cccteam/deployment-tools/internal/spannersql"
+	"github.com/go-playground/errors/v5"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// pendingMigration is an up migration that has not been applied to the database yet
+type pendingMigration struct {
+	version    uint
+	identifier string
+	checksum   string
+	stmts      []string
+}
+
+func (p pendingMigration) String() string {
+	return fmt.Sprintf("%d_%s", p.version, p.identifier)
+}
+
+// pendingMigrations returns the up migrations from sourceURL that are newer than the database version.
+// Nothing is pending for a dirty database, since migrate refuses to run against it and reports why.
+func pendingMigrations(driver migratedb.Driver, sourceURL string) ([]pendingMigration, error) {
+	version, dirty, err := driver.Version()
+	if err != nil {
+		return nil, errors.Wrap(err, "database.Driver.Version()")
+	}
+	if dirty {
+		return nil, nil
+	}
+
+	return readMigrations(sourceURL, version)
+}
+
+// readMigrations returns the up migrations from sourceURL newer than version, or all of them
+// when version is [migratedb.NilVersion]
+func readMigrations(sourceURL string, version int) ([]pendingMigration, error) {
+	src, err := source.Open(sourceURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "source.Open(): %s", sourceURL)
+	}
+	defer src.Close()
+
+	var v uint
+	if version == migratedb.NilVersion {
+		v, err = src.First()
+	} else {
+		v, err = src.Next(uint(version))
+	}
+
+	var pending []pendingMigration
+	for ; err == nil; v, err = src.Next(v) {
+		p, ok, err := readUp(src, v)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			pending = append(pending, p)
+		}
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrap(err, "source.Driver.Next()")
+	}
+
+	return pending, nil
+}
+
+// readUp reads the up migration for version v. ok is false when the version has no up migration.
+func readUp(src source.Driver, v uint) (p pendingMigration, ok bool, err error) {
+	r, identifier, err := src.ReadUp(v)
+	if errors.Is(err, os.ErrNotExist) {
+		return pendingMigration{}, false, nil
+	}
+	if err != nil {
+		return pendingMigration{}, false, errors.Wrap(err, "source.Driver.ReadUp()")
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return pendingMigration{}, false, errors.Wrap(err, "io.ReadAll()")
+	}
+
+	stmts, err := spannersql.Split(identifier, string(b))
+	if err != nil {
+		return pendingMigration{}, false, errors.Wrap(err, "spannersql.Split()")
+	}
+
+	return pendingMigration{
+		version:    v,
+		identifier: identifier,
+		checksum:   checksum(b),
+		stmts:      stmts,
+	}, true, nil
+}
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// PendingSchemaVersions returns the versions of the schema migrations from sourceURL that have not been applied yet
+// to the named schema set. See [SchemaSet].
+func (c *Client) PendingSchemaVersions(sourceURL, set string) ([]uint, error) {
+	driver, err := c.driver(c.schemaSetTable(set))
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := pendingMigrations(driver, sourceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]uint, 0, len(pending))
+	for _, p := range pending {
+		versions = append(versions, p.version)
+	}
+
+	return versions, nil
+}
@@ -0,0 +1,154 @@
+package spannermigrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-playground/errors/v5"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+)
+
+// Report is a machine-readable record of the migrations a [Client] applied, kept with release records
+type Report struct {
+	mu sync.Mutex
+
+	Database   string            `json:"database"`
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt time.Time         `json:"finishedAt"`
+	Migrations []MigrationRecord `json:"migrations"`
+	Warnings   []string          `json:"warnings"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// MigrationRecord is a single migration applied, or attempted, by a [Client]
+type MigrationRecord struct {
+	// Kind is "schema", "schema:<set>" for a named schema set, or "data"
+	Kind       string    `json:"kind"`
+	Version    uint      `json:"version"`
+	File       string    `json:"file"`
+	Checksum   string    `json:"checksum"`
+	Statements int       `json:"statements"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	// Succeeded is false for the migration that failed, which leaves the database dirty at its version
+	Succeeded bool `json:"succeeded"`
+}
+
+// NewReport returns an empty report started now
+func NewReport() *Report {
+	return &Report{
+		StartedAt:  time.Now().UTC(),
+		Migrations: []MigrationRecord{},
+		Warnings:   []string{},
+	}
+}
+
+// Warn logs a warning and records it in the report. It is safe to call on a nil report.
+func (r *Report) Warn(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	log.Printf("WARNING: %s\n", msg)
+
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Warnings = append(r.Warnings, msg)
+}
+
+// Finish records the outcome of the run
+func (r *Report) Finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.FinishedAt = time.Now().UTC()
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// JSON returns the report as indented JSON
+func (r *Report) JSON() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "json.MarshalIndent()")
+	}
+
+	return append(b, '\n'), nil
+}
+
+// WriteFile writes the report as JSON to path
+func (r *Report) WriteFile(path string) error {
+	b, err := r.JSON()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, b, 0o644); err != nil { //nolint:gosec // the report is a build artifact
+		return errors.Wrap(err, "os.WriteFile()")
+	}
+
+	return nil
+}
+
+func (r *Report) begin(kind string, p pendingMigration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Migrations = append(r.Migrations, MigrationRecord{
+		Kind:       kind,
+		Version:    p.version,
+		File:       p.String(),
+		Checksum:   p.checksum,
+		Statements: len(p.stmts),
+		StartedAt:  time.Now().UTC(),
+	})
+}
+
+func (r *Report) succeed() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.Migrations) == 0 {
+		return
+	}
+
+	m := &r.Migrations[len(r.Migrations)-1]
+	m.DurationMs = time.Since(m.StartedAt).Milliseconds()
+	m.Succeeded = true
+}
+
+// reportDriver records each migration applied by the wrapped driver in a report
+type reportDriver struct {
+	migratedb.Driver
+	report  *Report
+	kind    string
+	pending map[int]pendingMigration
+}
+
+// SetVersion implements database.Driver
+func (d *reportDriver) SetVersion(version int, dirty bool) error {
+	p, ok := d.pending[version]
+	if ok && dirty {
+		d.report.begin(d.kind, p)
+	}
+
+	if err := d.Driver.SetVersion(version, dirty); err != nil {
+		return errors.Wrap(err, "database.Driver.SetVersion()")
+	}
+
+	if ok && !dirty {
+		d.report.succeed()
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package spannermigrate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-playground/errors/v5"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+)
+
+// versionDriver is a driver that only supports SetVersion
+type versionDriver struct {
+	migratedb.Driver
+	failVersion int
+}
+
+func (d *versionDriver) SetVersion(version int, dirty bool) error {
+	if version == d.failVersion && !dirty {
+		return errors.New("failed")
+	}
+
+	return nil
+}
+
+func Test_reportDriver(t *testing.T) {
+	t.Parallel()
+
+	report := NewReport()
+	d := &reportDriver{
+		Driver: &versionDriver{failVersion: 3},
+		report: report,
+		kind:   "data",
+		pending: map[int]pendingMigration{
+			2: {version: 2, identifier: "users", checksum: "abc", stmts: []string{"INSERT 1", "INSERT 2"}},
+			3: {version: 3, identifier: "roles", stmts: []string{"UPDATE 1"}},
+		},
+	}
+
+	// version 1 is not pending, so it is not recorded
+	for _, step := range []struct {
+		version int
+		dirty   bool
+	}{{1, true}, {1, false}, {2, true}, {2, false}, {3, true}, {3, false}} {
+		_ = d.SetVersion(step.version, step.dirty)
+	}
+	report.Finish(errors.New("migration 3 failed"))
+
+	if len(report.Migrations) != 2 {
+		t.Fatalf("len(Migrations) = %d, want 2", len(report.Migrations))
+	}
+	got := report.Migrations[0]
+	if got.Kind != "data" || got.Version != 2 || got.File != "2_users" || got.Checksum != "abc" || got.Statements != 2 || !got.Succeeded {
+		t.Errorf("Migrations[0] = %+v", got)
+	}
+	if report.Migrations[1].Succeeded {
+		t.Errorf("Migrations[1].Succeeded = true, want false")
+	}
+	if !strings.Contains(report.Error, "migration 3 failed") || report.FinishedAt.IsZero() {
+		t.Errorf("Finish() did not record the outcome: error = %q, finishedAt = %s", report.Error, report.FinishedAt)
+	}
+}
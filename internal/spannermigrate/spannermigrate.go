@@ -0,0 +1,347 @@
+// Package spannermigrate runs schema and data migrations against an existing Spanner database.
+package spannermigrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/heartbeat"
+	"github.com/cccteam/deployment-tools/internal/purge"
+	"github.com/cccteam/deployment-tools/internal/spanneriam"
+	"github.com/cccteam/deployment-tools/internal/spannerrole"
+	"github.com/cccteam/deployment-tools/internal/spannertag"
+	"github.com/go-playground/errors/v5"
+	"github.com/golang-migrate/migrate/v4"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+	spannerDriver "github.com/golang-migrate/migrate/v4/database/spanner"
+	_ "github.com/golang-migrate/migrate/v4/source/file" // up/down script file source driver for the migrate package
+	"google.golang.org/api/option"
+)
+
+const (
+	// DefaultMutationLimit is the maximum number of mutations Spanner allows in a single transaction
+	DefaultMutationLimit = 80000
+
+	// SchemaMigrationsTable records the schema migration version. Named schema sets add a _<name> suffix.
+	SchemaMigrationsTable = "SchemaMigrations"
+
+	// DataMigrationsTable records the data migration version
+	DataMigrationsTable = "DataMigrations"
+
+	// DataMigrationHistoryTable records every data migration applied
+	DataMigrationHistoryTable = "DataMigrationHistory"
+)
+
+// IsDeploymentTable reports whether table holds migration, lock or purge audit state rather than application data
+func IsDeploymentTable(table string) bool {
+	switch {
+	case table == LockTable,
+		table == purge.AuditTable,
+		table == DataMigrationsTable,
+		table == DataMigrationHistoryTable,
+		table == SchemaMigrationsTable,
+		strings.HasPrefix(table, SchemaMigrationsTable+"_"):
+		return true
+	default:
+		return false
+	}
+}
+
+// Client handles connecting to an existing spanner database and running migrations
+type Client struct {
+	dbStr                 string
+	dbName                string
+	admin                 *database.DatabaseAdminClient
+	client                *spanner.Client
+	schemaMigrationsTable string
+	dataMigrationsTable   string
+	// dataMigrationHistoryTable records every data migration applied to the database
+	dataMigrationHistoryTable string
+	environment               string
+	heartbeatInterval         time.Duration
+	statementTimeout          time.Duration
+	mutationLimit             int64
+	partitionLargeDML         bool
+	report                    *Report
+	// driverDB is shared by the migration drivers, which are created once per migrations table and reused
+	driverDB *spannerDriver.DB
+	drivers  map[string]migratedb.Driver
+}
+
+// Connect connects to an existing spanner database and returns a [Client]
+//
+// Uses the following tables by default to store migration versions:
+//   - Data Migrations table: "DataMigrations"
+//   - Schema Migrations table: "SchemaMigrations"
+//
+// Applied data migrations are recorded in the "DataMigrationHistory" table.
+func Connect(ctx context.Context, projectID, instanceID, dbName string, opts ...option.ClientOption) (*Client, error) {
+	return connect(ctx, projectID, instanceID, dbName, spanner.ClientConfig{SessionLabels: spannertag.SessionLabels("migration")}, opts...)
+}
+
+func connect(ctx context.Context, projectID, instanceID, dbName string, config spanner.ClientConfig, opts ...option.ClientOption) (*Client, error) {
+	dbStr := fmt.Sprintf("projects/%s/instances/%s/databases/%s", projectID, instanceID, dbName)
+	client, err := spanner.NewClientWithConfig(ctx, dbStr, config, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "spanner.NewClientWithConfig()")
+	}
+
+	admin, err := database.NewDatabaseAdminClient(ctx, opts...)
+	if err != nil {
+		client.Close()
+
+		return nil, errors.Wrap(err, "database.NewDatabaseAdminClient()")
+	}
+
+	return &Client{
+		dbStr:                     dbStr,
+		dbName:                    dbName,
+		admin:                     admin,
+		client:                    client,
+		schemaMigrationsTable:     SchemaMigrationsTable,
+		dataMigrationsTable:       DataMigrationsTable,
+		dataMigrationHistoryTable: DataMigrationHistoryTable,
+		mutationLimit:             DefaultMutationLimit,
+		driverDB:                  spannerDriver.NewDB(*admin, *client),
+		drivers:                   make(map[string]migratedb.Driver),
+	}, nil
+}
+
+// WithEnvironment sets the environment recorded in the data migration history
+func (c *Client) WithEnvironment(environment string) *Client {
+	c.environment = environment
+
+	return c
+}
+
+// WithHeartbeat logs a progress line every interval while migrations are running. A zero interval disables it.
+func (c *Client) WithHeartbeat(interval time.Duration) *Client {
+	c.heartbeatInterval = interval
+
+	return c
+}
+
+// WithStatementTimeout sets the maximum time each data migration statement may run. A zero duration disables the timeout.
+func (c *Client) WithStatementTimeout(timeout time.Duration) *Client {
+	c.statementTimeout = timeout
+
+	return c
+}
+
+// WithMutationLimit sets the number of estimated mutations a data migration may produce in a single transaction
+// before it is rejected. A limit of zero disables the check.
+func (c *Client) WithMutationLimit(limit int64) *Client {
+	c.mutationLimit = limit
+
+	return c
+}
+
+// WithPartitionLargeDML runs UPDATE and DELETE statements that exceed the mutation limit on their own as
+// partitioned DML instead of rejecting the migration. Partitioned DML is not atomic with the rest of the
+// migration file, so these statements must be idempotent.
+func (c *Client) WithPartitionLargeDML(partition bool) *Client {
+	c.partitionLargeDML = partition
+
+	return c
+}
+
+// WithReport records every migration applied by the client, and the warnings raised while applying them, in report
+func (c *Client) WithReport(report *Report) *Client {
+	report.Database = c.dbStr
+	c.report = report
+
+	return c
+}
+
+// MigrateUpSchema will migrate all the way up, applying all up migrations from the sourceURL
+//
+// Use for DDL migrations
+func (c *Client) MigrateUpSchema(ctx context.Context, sourceURL string) error {
+	return c.migrateUpSchema(ctx, sourceURL, "")
+}
+
+// MigrateUpData will apply all data migrations from the sourceURL
+//
+// Pending migrations are checked against the mutation limit before any of them are applied.
+// Each applied migration is recorded in the data migration history table.
+//
+// Use for DML migrations
+func (c *Client) MigrateUpData(ctx context.Context, sourceURL string) error {
+	status := &heartbeat.Status{}
+	stop := heartbeat.Start(ctx, c.heartbeatInterval, "data migrations", "database", c.dbStr, "source", sourceURL, "step", status)
+	defer stop()
+
+	driver, err := c.driver(c.dataMigrationsTable)
+	if err != nil {
+		return err
+	}
+
+	pending, err := pendingMigrations(driver, sourceURL)
+	if err != nil {
+		return err
+	}
+
+	status.Set(fmt.Sprintf("estimating mutations of %d pending migration(s)", len(pending)))
+	partitioned, err := c.checkMutations(ctx, pending)
+	if err != nil {
+		return err
+	}
+
+	status.Set("creating history table")
+	if err := c.ensureHistoryTable(ctx); err != nil {
+		return err
+	}
+
+	dd := &dataDriver{
+		Driver:      driver,
+		ctx:         ctx,
+		c:           c,
+		pending:     make(map[int]pendingMigration, len(pending)),
+		partitioned: partitioned,
+	}
+	for _, p := range pending {
+		dd.pending[int(p.version)] = p
+	}
+
+	if err := c.migrateUp(c.instrument(dd, status, "data", pending), sourceURL); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GrantIAM applies database-level IAM bindings to the database. See [spanneriam.Apply].
+func (c *Client) GrantIAM(ctx context.Context, bindings []spanneriam.Binding, prune bool) ([]string, error) {
+	changes, err := spanneriam.Apply(ctx, c.admin, c.dbStr, bindings, prune)
+	if err != nil {
+		return nil, errors.Wrap(err, "spanneriam.Apply()")
+	}
+
+	return changes, nil
+}
+
+// ApplyRoles creates the missing database roles and grants of the definition and verifies the result
+func (c *Client) ApplyRoles(ctx context.Context, d *spannerrole.Definition) (spannerrole.Plan, error) {
+	plan, err := spannerrole.Apply(ctx, c.admin, c.client, c.dbStr, d)
+	if err != nil {
+		return spannerrole.Plan{}, errors.Wrap(err, "spannerrole.Apply()")
+	}
+
+	return plan, nil
+}
+
+// Close cleans up resources
+func (c *Client) Close() error {
+	c.client.Close()
+
+	if err := c.admin.Close(); err != nil {
+		return errors.Wrap(err, "database.DatabaseAdminClient.Close()")
+	}
+
+	return nil
+}
+
+// driver returns the migration driver recording versions in migrationsTable. Drivers share the client's
+// connection and are reused for every source directory, so the migrations table is only checked once.
+func (c *Client) driver(migrationsTable string) (migratedb.Driver, error) {
+	if driver, ok := c.drivers[migrationsTable]; ok {
+		return driver, nil
+	}
+
+	conf := &spannerDriver.Config{DatabaseName: c.dbStr, CleanStatements: true, MigrationsTable: migrationsTable}
+	driver, err := spannerDriver.WithInstance(c.driverDB, conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "spannerDriver.WithInstance()")
+	}
+	c.drivers[migrationsTable] = driver
+
+	return driver, nil
+}
+
+// instrument wraps driver to report progress to the heartbeat status and, when a report is configured,
+// record the pending migrations it applies
+func (c *Client) instrument(driver migratedb.Driver, status *heartbeat.Status, kind string, pending []pendingMigration) migratedb.Driver {
+	if c.report != nil {
+		rd := &reportDriver{Driver: driver, report: c.report, kind: kind, pending: make(map[int]pendingMigration, len(pending))}
+		for _, p := range pending {
+			rd.pending[int(p.version)] = p
+		}
+		driver = rd
+	}
+
+	return &statusDriver{Driver: driver, status: status}
+}
+
+// migrateUpSchema applies the schema migrations from sourceURL, recording the version in the table of the named
+// schema set. See [SchemaSet].
+func (c *Client) migrateUpSchema(ctx context.Context, sourceURL, set string) error {
+	migrationsTable := c.schemaSetTable(set)
+	status := &heartbeat.Status{}
+	stop := heartbeat.Start(ctx, c.heartbeatInterval, "schema migrations", "database", c.dbStr, "source", sourceURL, "table", migrationsTable, "step", status)
+	defer stop()
+
+	driver, err := c.driver(migrationsTable)
+	if err != nil {
+		return err
+	}
+
+	kind := "schema"
+	if set != "" {
+		kind += ":" + set
+	}
+
+	var pending []pendingMigration
+	if c.report != nil {
+		if pending, err = pendingMigrations(driver, sourceURL); err != nil {
+			return err
+		}
+	}
+
+	if err := c.migrateUp(c.instrument(driver, status, kind, pending), sourceURL); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) migrateUp(driver migratedb.Driver, sourceURL string) error {
+	m, err := migrate.NewWithDatabaseInstance(sourceURL, "spanner", driver)
+	if err != nil {
+		return errors.Wrapf(err, "migrate.NewWithDatabaseInstance(): fileURL=%s, db=%s", sourceURL, c.dbStr)
+	}
+	m.Log = new(logger)
+	defer func() {
+		if srcErr, dbErr := m.Close(); srcErr != nil || dbErr != nil {
+			log.Printf("failed to close migrate instance: source: %v, database: %v", srcErr, dbErr)
+		}
+	}()
+
+	if err := m.Up(); err != nil {
+		// migrate does not unwrap the error Spanner returned for a failed statement
+		var dbErr *migratedb.Error
+		if errors.As(err, &dbErr) {
+			err = deployerr.New(deployerr.Spanner, "migration_failed", err)
+		}
+
+		return errors.Wrapf(err, "migrate.Migrate.Up(): %s", sourceURL)
+	}
+
+	return nil
+}
+
+// logger implements migrate.Logger
+type logger struct{}
+
+func (logger) Printf(format string, v ...any) {
+	log.Printf(format, v...)
+}
+
+func (logger) Verbose() bool {
+	return false
+}
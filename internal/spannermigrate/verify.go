@@ -0,0 +1,253 @@
+package spannermigrate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	adminpb "cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/cccteam/deployment-tools/internal/spannertag"
+	"github.com/go-playground/errors/v5"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/option"
+)
+
+// MigrationState is the version recorded in a migrations table
+type MigrationState struct {
+	// Version is [migratedb.NilVersion] when no migration has been applied
+	Version int
+	Dirty   bool
+}
+
+func (s MigrationState) String() string {
+	if s.Version == migratedb.NilVersion {
+		return "no migrations applied"
+	}
+	if s.Dirty {
+		return fmt.Sprintf("version %d (dirty)", s.Version)
+	}
+
+	return fmt.Sprintf("version %d", s.Version)
+}
+
+// ReadOnlyClient runs read-only checks against a database, so it is safe to run on a schedule against production.
+// Rows are only read in read-only transactions and the migrations tables are never created. It does not hold a
+// [Client] or a database admin client, so no method that writes to the database or changes its schema is reachable.
+type ReadOnlyClient struct {
+	dbStr  string
+	client *spanner.Client
+	// ddl is the database admin client, of which only GetDatabaseDdl is reachable
+	ddl ddlReader
+}
+
+// ddlReader is the part of the database admin client a [ReadOnlyClient] uses
+type ddlReader interface {
+	GetDatabaseDdl(ctx context.Context, req *adminpb.GetDatabaseDdlRequest, opts ...gax.CallOption) (*adminpb.GetDatabaseDdlResponse, error)
+	Close() error
+}
+
+// ConnectReadOnly connects to an existing spanner database and returns a [ReadOnlyClient]. A non-empty
+// databaseRole connects as that fine-grained access control role, so the database enforces read-only
+// access when the role is only granted SELECT.
+func ConnectReadOnly(ctx context.Context, projectID, instanceID, dbName, databaseRole string, opts ...option.ClientOption) (*ReadOnlyClient, error) {
+	dbStr := fmt.Sprintf("projects/%s/instances/%s/databases/%s", projectID, instanceID, dbName)
+	client, err := spanner.NewClientWithConfig(ctx, dbStr, spanner.ClientConfig{DatabaseRole: databaseRole, SessionLabels: spannertag.SessionLabels("verify")}, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "spanner.NewClientWithConfig()")
+	}
+
+	admin, err := database.NewDatabaseAdminClient(ctx, opts...)
+	if err != nil {
+		client.Close()
+
+		return nil, errors.Wrap(err, "database.NewDatabaseAdminClient()")
+	}
+
+	return &ReadOnlyClient{dbStr: dbStr, client: client, ddl: admin}, nil
+}
+
+// SchemaState returns the version recorded in the migrations table of the named schema set. See [SchemaSet].
+func (r *ReadOnlyClient) SchemaState(ctx context.Context, set string) (MigrationState, error) {
+	table := SchemaMigrationsTable
+	if set != "" {
+		table += "_" + set
+	}
+
+	return migrationState(ctx, r.client, table)
+}
+
+// DataState returns the version recorded in the data migrations table
+func (r *ReadOnlyClient) DataState(ctx context.Context) (MigrationState, error) {
+	return migrationState(ctx, r.client, DataMigrationsTable)
+}
+
+// Schema returns the schema of the database, read with GetDatabaseDdl
+func (r *ReadOnlyClient) Schema(ctx context.Context) (*Schema, error) {
+	resp, err := r.ddl.GetDatabaseDdl(ctx, &adminpb.GetDatabaseDdlRequest{Database: r.dbStr})
+	if err != nil {
+		return nil, errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.GetDatabaseDdl()")
+	}
+
+	schema := NewSchema()
+	if err := schema.Apply(r.dbStr, resp.GetStatements()); err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// ChecksumDrift returns the data migrations whose file in sourceURL no longer matches the checksum recorded
+// in the history table when it was applied, i.e. migrations edited after they were deployed
+func (r *ReadOnlyClient) ChecksumDrift(ctx context.Context, sourceURL string) ([]string, error) {
+	migrations, err := readMigrations(sourceURL, migratedb.NilVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := r.appliedChecksums(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []string
+	for _, m := range migrations {
+		checksum, ok := applied[int64(m.version)]
+		if ok && checksum != m.checksum {
+			drift = append(drift, m.String())
+		}
+	}
+
+	return drift, nil
+}
+
+// appliedChecksums returns, by version, the checksum recorded for the most recent application of each data migration
+// in the history table
+func (r *ReadOnlyClient) appliedChecksums(ctx context.Context) (map[int64]string, error) {
+	exists, err := tableExists(ctx, r.client, DataMigrationHistoryTable)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	applied := make(map[int64]string)
+	stmt := spanner.Statement{SQL: `SELECT Version, ANY_VALUE(Checksum HAVING MAX AppliedAt)
+		FROM ` + DataMigrationHistoryTable + `
+		GROUP BY Version`}
+	if err := r.client.Single().Query(ctx, stmt).Do(func(row *spanner.Row) error {
+		var version int64
+		var checksum string
+		if err := row.Columns(&version, &checksum); err != nil {
+			return errors.Wrap(err, "spanner.Row.Columns()")
+		}
+		applied[version] = checksum
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "spanner.RowIterator.Do(): %s", DataMigrationHistoryTable)
+	}
+
+	return applied, nil
+}
+
+// CountRows returns the number of rows in table
+func (r *ReadOnlyClient) CountRows(ctx context.Context, table string) (int64, error) {
+	if !regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`).MatchString(table) {
+		return 0, errors.Newf("invalid table name %q", table)
+	}
+
+	var count int64
+	if err := r.client.Single().Query(ctx, spanner.Statement{SQL: "SELECT COUNT(*) FROM `" + table + "`"}).Do(func(row *spanner.Row) error {
+		return row.Column(0, &count)
+	}); err != nil {
+		return 0, errors.Wrapf(err, "spanner.RowIterator.Do(): %s", table)
+	}
+
+	return count, nil
+}
+
+// Close cleans up resources
+func (r *ReadOnlyClient) Close() error {
+	r.client.Close()
+	if err := r.ddl.Close(); err != nil {
+		return errors.Wrap(err, "database.DatabaseAdminClient.Close()")
+	}
+
+	return nil
+}
+
+// CheckVersion compares state with the migrations in sourceURL and returns an error when the database is
+// dirty, has pending migrations, or is at a version that does not exist in sourceURL
+func CheckVersion(state MigrationState, sourceURL string) error {
+	versions, err := PendingVersions(MigrationState{Version: migratedb.NilVersion}, sourceURL)
+	if err != nil {
+		return err
+	}
+
+	return checkVersion(state, versions)
+}
+
+// checkVersion compares state with the ascending migration versions available
+func checkVersion(state MigrationState, versions []uint) error {
+	if state.Dirty {
+		return errors.Newf("database is dirty at version %d, a migration failed part way and must be fixed manually", state.Version)
+	}
+
+	if state.Version != migratedb.NilVersion && !slices.Contains(versions, uint(state.Version)) {
+		return errors.Newf("database is at version %d, which does not exist in the migration files", state.Version)
+	}
+
+	var pending int
+	for _, v := range versions {
+		if state.Version == migratedb.NilVersion || v > uint(state.Version) {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return errors.Newf("%d migration(s) are pending, the latest is version %d", pending, versions[len(versions)-1])
+	}
+
+	return nil
+}
+
+// migrationState reads the version recorded in a migrations table without creating the table
+func migrationState(ctx context.Context, client *spanner.Client, table string) (MigrationState, error) {
+	state := MigrationState{Version: migratedb.NilVersion}
+
+	exists, err := tableExists(ctx, client, table)
+	if err != nil || !exists {
+		return state, err
+	}
+
+	if err := client.Single().Query(ctx, spanner.Statement{SQL: "SELECT Version, Dirty FROM " + table + " LIMIT 1"}).Do(func(row *spanner.Row) error {
+		var version int64
+		if err := row.Columns(&version, &state.Dirty); err != nil {
+			return errors.Wrap(err, "spanner.Row.Columns()")
+		}
+		state.Version = int(version)
+
+		return nil
+	}); err != nil {
+		return state, errors.Wrapf(err, "spanner.RowIterator.Do(): %s", table)
+	}
+
+	return state, nil
+}
+
+// PendingVersions returns the versions of the migrations in sourceURL that are newer than state
+func PendingVersions(state MigrationState, sourceURL string) ([]uint, error) {
+	migrations, err := readMigrations(sourceURL, state.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]uint, 0, len(migrations))
+	for _, m := range migrations {
+		versions = append(versions, m.version)
+	}
+
+	return versions, nil
+}
@@ -0,0 +1,34 @@
+package spannermigrate
+
+import (
+	"testing"
+
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+)
+
+func Test_checkVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		state    MigrationState
+		versions []uint
+		wantErr  bool
+	}{
+		{name: "up to date", state: MigrationState{Version: 3}, versions: []uint{1, 2, 3}},
+		{name: "empty database and no files", state: MigrationState{Version: migratedb.NilVersion}},
+		{name: "empty database with files", state: MigrationState{Version: migratedb.NilVersion}, versions: []uint{1}, wantErr: true},
+		{name: "behind", state: MigrationState{Version: 2}, versions: []uint{1, 2, 3}, wantErr: true},
+		{name: "unknown version", state: MigrationState{Version: 4}, versions: []uint{1, 2, 3}, wantErr: true},
+		{name: "dirty", state: MigrationState{Version: 3, Dirty: true}, versions: []uint{1, 2, 3}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := checkVersion(tt.state, tt.versions); (err != nil) != tt.wantErr {
+				t.Errorf("checkVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
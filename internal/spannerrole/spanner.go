@@ -0,0 +1,110 @@
+package spannerrole
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	adminpb "cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
+	"github.com/go-playground/errors/v5"
+)
+
+// Querier is implemented by spanner read-only and read-write transactions
+type Querier interface {
+	Query(ctx context.Context, statement spanner.Statement) *spanner.RowIterator
+}
+
+// Read returns the roles, privileges and memberships of the user-defined roles in the database
+func Read(ctx context.Context, txn Querier) (State, error) {
+	s := State{Roles: make(map[string]bool), Privileges: make(map[Privilege]bool), Memberships: make(map[Membership]bool)}
+
+	if err := query(ctx, txn, "SELECT ROLE_NAME FROM INFORMATION_SCHEMA.ROLES WHERE IS_SYSTEM = FALSE", func(v []string) {
+		s.Roles[v[0]] = true
+	}); err != nil {
+		return State{}, err
+	}
+
+	addPrivilege := func(p Privilege) {
+		if s.Roles[p.Grantee] {
+			s.Privileges[p] = true
+		}
+	}
+	if err := query(ctx, txn, "SELECT GRANTEE, PRIVILEGE_TYPE, TABLE_NAME FROM INFORMATION_SCHEMA.TABLE_PRIVILEGES", func(v []string) {
+		addPrivilege(Privilege{Grantee: v[0], Privilege: v[1], Object: v[2]})
+	}); err != nil {
+		return State{}, err
+	}
+	if err := query(ctx, txn, "SELECT GRANTEE, PRIVILEGE_TYPE, TABLE_NAME, COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMN_PRIVILEGES", func(v []string) {
+		addPrivilege(Privilege{Grantee: v[0], Privilege: v[1], Object: v[2], Column: v[3]})
+	}); err != nil {
+		return State{}, err
+	}
+	if err := query(ctx, txn, "SELECT GRANTEE, PRIVILEGE_TYPE, CHANGE_STREAM_NAME FROM INFORMATION_SCHEMA.CHANGE_STREAM_PRIVILEGES", func(v []string) {
+		addPrivilege(Privilege{Grantee: v[0], Privilege: v[1], Object: v[2]})
+	}); err != nil {
+		return State{}, err
+	}
+	if err := query(ctx, txn, "SELECT ROLE_NAME, GRANTEE FROM INFORMATION_SCHEMA.ROLE_GRANTEES", func(v []string) {
+		if s.Roles[v[1]] {
+			s.Memberships[Membership{Role: v[0], Grantee: v[1]}] = true
+		}
+	}); err != nil {
+		return State{}, err
+	}
+
+	return s, nil
+}
+
+// query calls fn with the string columns of every row returned by sql
+func query(ctx context.Context, txn Querier, sql string, fn func(values []string)) error {
+	if err := txn.Query(ctx, spanner.NewStatement(sql)).Do(func(r *spanner.Row) error {
+		values := make([]string, r.Size())
+		for i := range values {
+			var v spanner.NullString
+			if err := r.Column(i, &v); err != nil {
+				return errors.Wrap(err, "spanner.Row.Column()")
+			}
+			values[i] = v.StringVal
+		}
+		fn(values)
+
+		return nil
+	}); err != nil {
+		return errors.Wrapf(err, "spanner.RowIterator.Do(): %s", sql)
+	}
+
+	return nil
+}
+
+// Apply creates the missing roles and grants of the definition, then reads the database again to verify that it
+// matches. The plan that was applied is returned, including the drift that was found.
+func Apply(ctx context.Context, admin *database.DatabaseAdminClient, client *spanner.Client, dbStr string, d *Definition) (Plan, error) {
+	current, err := Read(ctx, client.Single())
+	if err != nil {
+		return Plan{}, errors.Wrap(err, "spannerrole.Read()")
+	}
+
+	plan := d.NewPlan(current)
+	if len(plan.Statements) == 0 {
+		return plan, nil
+	}
+
+	op, err := admin.UpdateDatabaseDdl(ctx, &adminpb.UpdateDatabaseDdlRequest{Database: dbStr, Statements: plan.Statements})
+	if err != nil {
+		return Plan{}, errors.Wrap(deployerr.FromSpanner(err), "database.DatabaseAdminClient.UpdateDatabaseDdl()")
+	}
+	if err := op.Wait(ctx); err != nil {
+		return Plan{}, errors.Wrap(deployerr.FromSpanner(err), "database.UpdateDatabaseDdlOperation.Wait()")
+	}
+
+	after, err := Read(ctx, client.Single())
+	if err != nil {
+		return Plan{}, errors.Wrap(err, "spannerrole.Read()")
+	}
+	if missing := d.NewPlan(after).Statements; len(missing) > 0 {
+		return Plan{}, errors.Newf("roles do not match the definition after applying it, still missing: %v", missing)
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,281 @@
+// Package spannerrole creates the database roles of Spanner fine-grained access control and their privileges
+// from a declarative roles file, and verifies them against the database on later runs.
+package spannerrole
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/cccteam/deployment-tools/internal/yamlfile"
+	"github.com/go-playground/errors/v5"
+)
+
+// Definition lists the database roles
+type Definition struct {
+	Roles []Role `yaml:"roles"`
+}
+
+// Role is a database role, the roles it inherits and the privileges granted to it
+type Role struct {
+	Name string `yaml:"name"`
+	// Inherits lists roles granted to this role, whose privileges it gains
+	Inherits []string `yaml:"inherits"`
+	Grants   []Grant  `yaml:"grants"`
+}
+
+// Grant grants privileges on one table, view or change stream
+type Grant struct {
+	// Privileges are SELECT, INSERT, UPDATE or DELETE. Views and change streams only support SELECT.
+	Privileges []string `yaml:"privileges"`
+	Table      string   `yaml:"table"`
+	// Columns limits SELECT, INSERT and UPDATE on Table to these columns
+	Columns      []string `yaml:"columns"`
+	View         string   `yaml:"view"`
+	ChangeStream string   `yaml:"changeStream"`
+}
+
+// objectType returns the DDL keyword and name of the object the grant is on
+func (g Grant) objectType() (string, string) {
+	switch {
+	case g.View != "":
+		return "VIEW", g.View
+	case g.ChangeStream != "":
+		return "CHANGE STREAM", g.ChangeStream
+	default:
+		return "TABLE", g.Table
+	}
+}
+
+// Privilege is a single privilege of a role, on an object or, when Column is set, one of its columns
+type Privilege struct {
+	Grantee   string
+	Privilege string
+	Object    string
+	Column    string
+}
+
+func (p Privilege) String() string {
+	if p.Column != "" {
+		return fmt.Sprintf("%s(%s) on %s to %s", p.Privilege, p.Column, p.Object, p.Grantee)
+	}
+
+	return fmt.Sprintf("%s on %s to %s", p.Privilege, p.Object, p.Grantee)
+}
+
+// Membership is a role granted to another role
+type Membership struct {
+	Role    string
+	Grantee string
+}
+
+func (m Membership) String() string {
+	return fmt.Sprintf("role %s to %s", m.Role, m.Grantee)
+}
+
+// State is the set of roles, privileges and memberships, either defined or found in the database
+type State struct {
+	Roles       map[string]bool
+	Privileges  map[Privilege]bool
+	Memberships map[Membership]bool
+}
+
+// Load reads the roles file at path
+func Load(path string) (*Definition, error) {
+	d := &Definition{}
+	if _, err := yamlfile.Load(path, "roles file", d); err != nil {
+		return nil, errors.Wrap(err, "yamlfile.Load()")
+	}
+
+	return d, nil
+}
+
+// Validate checks names, privileges and the object of every grant
+func (d *Definition) Validate() error {
+	identifier := regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+	var problems yamlfile.Problems
+	roles := make(map[string]bool, len(d.Roles))
+	for _, r := range d.Roles {
+		switch {
+		case !identifier.MatchString(r.Name):
+			problems.Add("role name %q is not a valid identifier", r.Name)
+		case strings.HasPrefix(strings.ToLower(r.Name), "spanner_") || strings.EqualFold(r.Name, "public"):
+			problems.Add("role %q is a system role", r.Name)
+		case roles[r.Name]:
+			problems.Add("role %q is defined more than once", r.Name)
+		}
+		roles[r.Name] = true
+	}
+
+	for _, r := range d.Roles {
+		for _, parent := range r.Inherits {
+			switch {
+			case parent == r.Name:
+				problems.Add("role %q inherits itself", r.Name)
+			case !roles[parent] && !strings.HasPrefix(parent, "spanner_"):
+				problems.Add("role %q inherits undefined role %q", r.Name, parent)
+			}
+		}
+
+		for i, g := range r.Grants {
+			name := fmt.Sprintf("role %q grant %d", r.Name, i+1)
+			objects := 0
+			for _, o := range []string{g.Table, g.View, g.ChangeStream} {
+				if o != "" {
+					objects++
+					if !identifier.MatchString(o) {
+						problems.Add("%s: %q is not a valid identifier", name, o)
+					}
+				}
+			}
+			if objects != 1 {
+				problems.Add("%s must set exactly one of table, view and changeStream", name)
+			}
+			if len(g.Columns) > 0 && g.Table == "" {
+				problems.Add("%s: columns are only supported on tables", name)
+			}
+			for _, c := range g.Columns {
+				if !identifier.MatchString(c) {
+					problems.Add("%s: column %q is not a valid identifier", name, c)
+				}
+			}
+			if len(g.Privileges) == 0 {
+				problems.Add("%s grants no privileges", name)
+			}
+			for _, p := range g.Privileges {
+				switch strings.ToUpper(p) {
+				case "SELECT":
+				case "INSERT", "UPDATE":
+					if g.Table == "" {
+						problems.Add("%s: %s is only supported on tables", name, p)
+					}
+				case "DELETE":
+					if g.Table == "" || len(g.Columns) > 0 {
+						problems.Add("%s: DELETE is only supported on whole tables", name)
+					}
+				default:
+					problems.Add("%s: unknown privilege %q, must be SELECT, INSERT, UPDATE or DELETE", name, p)
+				}
+			}
+		}
+	}
+
+	return problems.Err()
+}
+
+// State returns the state described by the definition
+func (d *Definition) State() State {
+	s := State{Roles: make(map[string]bool), Privileges: make(map[Privilege]bool), Memberships: make(map[Membership]bool)}
+	for _, r := range d.Roles {
+		s.Roles[r.Name] = true
+		for _, parent := range r.Inherits {
+			s.Memberships[Membership{Role: parent, Grantee: r.Name}] = true
+		}
+		for _, p := range rolePrivileges(r) {
+			s.Privileges[p] = true
+		}
+	}
+
+	return s
+}
+
+// rolePrivileges returns the individual privileges granted to r
+func rolePrivileges(r Role) []Privilege {
+	var privileges []Privilege
+	for _, g := range r.Grants {
+		_, object := g.objectType()
+		for _, p := range g.Privileges {
+			p = strings.ToUpper(p)
+			if len(g.Columns) == 0 {
+				privileges = append(privileges, Privilege{Grantee: r.Name, Privilege: p, Object: object})
+
+				continue
+			}
+			for _, c := range g.Columns {
+				privileges = append(privileges, Privilege{Grantee: r.Name, Privilege: p, Object: object, Column: c})
+			}
+		}
+	}
+
+	return privileges
+}
+
+// Plan is the DDL that makes the database match the definition, and what the database has beyond it
+type Plan struct {
+	// Statements create the missing roles and grant the missing privileges and memberships
+	Statements []string
+	// Drift lists roles, privileges and memberships found in the database but not defined. They are reported,
+	// not revoked.
+	Drift []string
+}
+
+// NewPlan compares the definition with the current state of the database
+func (d *Definition) NewPlan(current State) Plan {
+	var plan Plan
+	for _, r := range d.Roles {
+		if !current.Roles[r.Name] {
+			plan.Statements = append(plan.Statements, "CREATE ROLE "+r.Name)
+		}
+	}
+
+	for _, r := range d.Roles {
+		for _, g := range r.Grants {
+			kind, object := g.objectType()
+			for _, p := range g.Privileges {
+				p = strings.ToUpper(p)
+				if len(g.Columns) == 0 {
+					if !current.Privileges[Privilege{Grantee: r.Name, Privilege: p, Object: object}] {
+						plan.Statements = append(plan.Statements, fmt.Sprintf("GRANT %s ON %s %s TO ROLE %s", p, kind, object, r.Name))
+					}
+
+					continue
+				}
+
+				var missing []string
+				for _, c := range g.Columns {
+					if !current.Privileges[Privilege{Grantee: r.Name, Privilege: p, Object: object, Column: c}] {
+						missing = append(missing, c)
+					}
+				}
+				if len(missing) > 0 {
+					plan.Statements = append(plan.Statements, fmt.Sprintf("GRANT %s(%s) ON %s %s TO ROLE %s", p, strings.Join(missing, ", "), kind, object, r.Name))
+				}
+			}
+		}
+	}
+
+	for _, r := range d.Roles {
+		for _, parent := range r.Inherits {
+			if !current.Memberships[Membership{Role: parent, Grantee: r.Name}] {
+				plan.Statements = append(plan.Statements, fmt.Sprintf("GRANT ROLE %s TO ROLE %s", parent, r.Name))
+			}
+		}
+	}
+
+	desired := d.State()
+	for _, name := range slices.Sorted(maps.Keys(current.Roles)) {
+		if !desired.Roles[name] {
+			plan.Drift = append(plan.Drift, "role "+name+" is not defined")
+		}
+	}
+	var extra []string
+	for p := range current.Privileges {
+		// Column privileges are also listed for columns covered by a table-level grant, so only
+		// object-level privileges are compared
+		if p.Column == "" && !desired.Privileges[p] {
+			extra = append(extra, "privilege "+p.String()+" is not defined")
+		}
+	}
+	for m := range current.Memberships {
+		if !desired.Memberships[m] {
+			extra = append(extra, m.String()+" is not defined")
+		}
+	}
+	slices.Sort(extra)
+	plan.Drift = append(plan.Drift, extra...)
+
+	return plan
+}
@@ -0,0 +1,156 @@
+package spannerrole
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDefinition_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		def     Definition
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			def: Definition{Roles: []Role{
+				{Name: "reader", Inherits: []string{"spanner_info_reader"}, Grants: []Grant{
+					{Privileges: []string{"SELECT"}, Table: "Users", Columns: []string{"Id", "Name"}},
+					{Privileges: []string{"select"}, View: "ActiveUsers"},
+					{Privileges: []string{"SELECT"}, ChangeStream: "UserChanges"},
+				}},
+				{Name: "writer", Inherits: []string{"reader"}, Grants: []Grant{
+					{Privileges: []string{"INSERT", "UPDATE", "DELETE"}, Table: "Users"},
+				}},
+			}},
+		},
+		{name: "duplicate role", def: Definition{Roles: []Role{{Name: "a"}, {Name: "a"}}}, wantErr: true},
+		{name: "system role", def: Definition{Roles: []Role{{Name: "spanner_sys_reader"}}}, wantErr: true},
+		{name: "invalid name", def: Definition{Roles: []Role{{Name: "app-reader"}}}, wantErr: true},
+		{name: "undefined parent", def: Definition{Roles: []Role{{Name: "a", Inherits: []string{"b"}}}}, wantErr: true},
+		{
+			name:    "no object",
+			def:     Definition{Roles: []Role{{Name: "a", Grants: []Grant{{Privileges: []string{"SELECT"}}}}}},
+			wantErr: true,
+		},
+		{
+			name:    "two objects",
+			def:     Definition{Roles: []Role{{Name: "a", Grants: []Grant{{Privileges: []string{"SELECT"}, Table: "T", View: "V"}}}}},
+			wantErr: true,
+		},
+		{
+			name:    "insert on view",
+			def:     Definition{Roles: []Role{{Name: "a", Grants: []Grant{{Privileges: []string{"INSERT"}, View: "V"}}}}},
+			wantErr: true,
+		},
+		{
+			name:    "delete on columns",
+			def:     Definition{Roles: []Role{{Name: "a", Grants: []Grant{{Privileges: []string{"DELETE"}, Table: "T", Columns: []string{"C"}}}}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown privilege",
+			def:     Definition{Roles: []Role{{Name: "a", Grants: []Grant{{Privileges: []string{"ALTER"}, Table: "T"}}}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tt.def.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefinition_NewPlan(t *testing.T) {
+	t.Parallel()
+
+	def := Definition{Roles: []Role{
+		{Name: "reader", Grants: []Grant{
+			{Privileges: []string{"select"}, Table: "Users", Columns: []string{"Id", "Name"}},
+			{Privileges: []string{"SELECT"}, ChangeStream: "UserChanges"},
+		}},
+		{Name: "writer", Inherits: []string{"reader"}, Grants: []Grant{
+			{Privileges: []string{"INSERT", "DELETE"}, Table: "Users"},
+		}},
+	}}
+
+	tests := []struct {
+		name           string
+		current        State
+		wantStatements []string
+		wantDrift      []string
+	}{
+		{
+			name:    "empty database",
+			current: State{},
+			wantStatements: []string{
+				"CREATE ROLE reader",
+				"CREATE ROLE writer",
+				"GRANT SELECT(Id, Name) ON TABLE Users TO ROLE reader",
+				"GRANT SELECT ON CHANGE STREAM UserChanges TO ROLE reader",
+				"GRANT INSERT ON TABLE Users TO ROLE writer",
+				"GRANT DELETE ON TABLE Users TO ROLE writer",
+				"GRANT ROLE reader TO ROLE writer",
+			},
+		},
+		{
+			name: "up to date",
+			current: State{
+				Roles: map[string]bool{"reader": true, "writer": true},
+				Privileges: map[Privilege]bool{
+					{Grantee: "reader", Privilege: "SELECT", Object: "Users", Column: "Id"}:   true,
+					{Grantee: "reader", Privilege: "SELECT", Object: "Users", Column: "Name"}: true,
+					{Grantee: "reader", Privilege: "SELECT", Object: "UserChanges"}:           true,
+					{Grantee: "writer", Privilege: "INSERT", Object: "Users"}:                 true,
+					{Grantee: "writer", Privilege: "DELETE", Object: "Users"}:                 true,
+					// listed for a column covered by the table-level grant, not drift
+					{Grantee: "writer", Privilege: "INSERT", Object: "Users", Column: "Id"}: true,
+				},
+				Memberships: map[Membership]bool{{Role: "reader", Grantee: "writer"}: true},
+			},
+		},
+		{
+			name: "partial and drifted",
+			current: State{
+				Roles: map[string]bool{"reader": true, "legacy": true},
+				Privileges: map[Privilege]bool{
+					{Grantee: "reader", Privilege: "SELECT", Object: "Users", Column: "Id"}: true,
+					{Grantee: "reader", Privilege: "UPDATE", Object: "Users"}:               true,
+				},
+				Memberships: map[Membership]bool{{Role: "reader", Grantee: "legacy"}: true},
+			},
+			wantStatements: []string{
+				"CREATE ROLE writer",
+				"GRANT SELECT(Name) ON TABLE Users TO ROLE reader",
+				"GRANT SELECT ON CHANGE STREAM UserChanges TO ROLE reader",
+				"GRANT INSERT ON TABLE Users TO ROLE writer",
+				"GRANT DELETE ON TABLE Users TO ROLE writer",
+				"GRANT ROLE reader TO ROLE writer",
+			},
+			wantDrift: []string{
+				"role legacy is not defined",
+				"privilege UPDATE on Users to reader is not defined",
+				"role reader to legacy is not defined",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := def.NewPlan(tt.current)
+			if !slices.Equal(got.Statements, tt.wantStatements) {
+				t.Errorf("NewPlan().Statements = %q, want %q", got.Statements, tt.wantStatements)
+			}
+			if !slices.Equal(got.Drift, tt.wantDrift) {
+				t.Errorf("NewPlan().Drift = %q, want %q", got.Drift, tt.wantDrift)
+			}
+		})
+	}
+}
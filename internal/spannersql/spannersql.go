@@ -0,0 +1,45 @@
+// Package spannersql contains helpers for working with Spanner SQL scripts.
+package spannersql
+
+import (
+	"strings"
+
+	"github.com/cloudspannerecosystem/memefish"
+	"github.com/cloudspannerecosystem/memefish/token"
+	"github.com/go-playground/errors/v5"
+)
+
+// Split splits a SQL script into its statements. Statements that contain only
+// whitespace or comments are dropped. filePath is only used in error messages.
+func Split(filePath, script string) ([]string, error) {
+	raw, err := memefish.SplitRawStatements(filePath, script)
+	if err != nil {
+		return nil, errors.Wrap(err, "memefish.SplitRawStatements()")
+	}
+
+	stmts := make([]string, 0, len(raw))
+	for _, r := range raw {
+		blank, err := isBlank(r.Statement)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: invalid statement at offset %d", filePath, r.Pos)
+		}
+		if blank {
+			continue
+		}
+
+		stmts = append(stmts, strings.TrimSpace(r.Statement))
+	}
+
+	return stmts, nil
+}
+
+func isBlank(stmt string) (bool, error) {
+	lex := &memefish.Lexer{
+		File: &token.File{Buffer: stmt},
+	}
+	if err := lex.NextToken(); err != nil {
+		return false, errors.Wrap(err, "memefish.Lexer.NextToken()")
+	}
+
+	return lex.Token.Kind == token.TokenEOF, nil
+}
@@ -0,0 +1,61 @@
+package spannersql
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		script  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "single statement without terminator",
+			script: "DELETE FROM Users WHERE true",
+			want:   []string{"DELETE FROM Users WHERE true"},
+		},
+		{
+			name:   "multiple statements",
+			script: "INSERT INTO Users (Id) VALUES (1);\nUPDATE Users SET Name = 'a' WHERE Id = 1;\n",
+			want:   []string{"INSERT INTO Users (Id) VALUES (1)", "UPDATE Users SET Name = 'a' WHERE Id = 1"},
+		},
+		{
+			name:   "semicolon inside string literal",
+			script: "UPDATE Users SET Name = 'a;b' WHERE Id = 1;",
+			want:   []string{"UPDATE Users SET Name = 'a;b' WHERE Id = 1"},
+		},
+		{
+			name:   "comment only statements are dropped",
+			script: "-- leading comment\nDELETE FROM Users WHERE true;\n/* trailing */\n-- done\n",
+			want:   []string{"-- leading comment\nDELETE FROM Users WHERE true"},
+		},
+		{
+			name:   "blank script",
+			script: " \n\t;;\n",
+			want:   []string{},
+		},
+		{
+			name:    "unterminated string",
+			script:  "UPDATE Users SET Name = 'a WHERE Id = 1;",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Split("test.sql", tt.script)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Split() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Errorf("Split() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
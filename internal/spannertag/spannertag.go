@@ -0,0 +1,29 @@
+// Package spannertag names the Spanner traffic of deployment-tools, so DBAs can find it in Query Insights and the
+// transaction and lock statistics tables.
+package spannertag
+
+import "strings"
+
+const (
+	// Client is the first part of every tag and the value of the client session label
+	Client = "deployment-tools"
+
+	// maxLength is the longest request or transaction tag Spanner accepts
+	maxLength = 50
+)
+
+// Tag returns a request or transaction tag of the form deployment-tools:<part>:<part>, e.g.
+// deployment-tools:migration:v123, cut to the length Spanner accepts
+func Tag(parts ...string) string {
+	tag := strings.Join(append([]string{Client}, parts...), ":")
+	if len(tag) > maxLength {
+		return tag[:maxLength]
+	}
+
+	return tag
+}
+
+// SessionLabels returns the labels of the sessions a client opens for operation, e.g. migration or seed
+func SessionLabels(operation string) map[string]string {
+	return map[string]string{"client": Client, "operation": operation}
+}
@@ -0,0 +1,30 @@
+package spannertag
+
+import "testing"
+
+func TestTag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		parts []string
+		want  string
+	}{
+		{name: "client only", want: "deployment-tools"},
+		{name: "migration", parts: []string{"migration", "v123"}, want: "deployment-tools:migration:v123"},
+		{name: "too long", parts: []string{"seed", "AVeryLongTableNameThatDoesNotFitInATag"}, want: "deployment-tools:seed:AVeryLongTableNameThatDoesNo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := Tag(tt.parts...)
+			if got != tt.want {
+				t.Errorf("Tag() = %q, want %q", got, tt.want)
+			}
+			if len(got) > maxLength {
+				t.Errorf("len(Tag()) = %d, want at most %d", len(got), maxLength)
+			}
+		})
+	}
+}
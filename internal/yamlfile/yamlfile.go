@@ -0,0 +1,55 @@
+// Package yamlfile loads the YAML configuration files of deployment-tools, such as hooks, roles and plans.
+package yamlfile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-playground/errors/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// Validator is a configuration that checks itself once decoded. Validate may also fill in defaults.
+type Validator interface {
+	Validate() error
+}
+
+// Load decodes the YAML file at path into v, rejecting unknown fields, validates it and returns the contents of the
+// file. kind describes the file in errors, e.g. "hooks file".
+func Load(path, kind string, v Validator) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "os.ReadFile()")
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil {
+		return nil, errors.Wrapf(err, "yaml.Decoder.Decode(): %s", path)
+	}
+
+	if err := v.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s %s", kind, path)
+	}
+
+	return b, nil
+}
+
+// Problems collects the problems found by Validate, so all of them are reported at once
+type Problems []string
+
+// Add records a problem
+func (p *Problems) Add(format string, args ...any) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+// Err returns an error listing every problem, one per line, or nil when there are none
+func (p Problems) Err() error {
+	if len(p) == 0 {
+		return nil
+	}
+
+	return errors.Newf("%d problem(s):\n  %s", len(p), strings.Join(p, "\n  "))
+}
@@ -0,0 +1,70 @@
+package yamlfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/errors/v5"
+)
+
+type config struct {
+	Name  string `yaml:"name"`
+	Count int    `yaml:"count"`
+}
+
+func (c *config) Validate() error {
+	var problems Problems
+	if c.Name == "" {
+		problems.Add("no name")
+	}
+	if c.Count < 0 {
+		problems.Add("count %d is negative", c.Count)
+	}
+
+	return problems.Err()
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "valid", content: "name: a\ncount: 2\n"},
+		{name: "unknown field", content: "name: a\ncolor: red\n", wantErr: "field color not found"},
+		{name: "all problems", content: "count: -1\n", wantErr: "2 problem(s):\n  no name\n  count -1 is negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			c := &config{}
+			b, err := Load(path, "test file", c)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(errors.Cause(err).Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if string(b) != tt.content {
+				t.Errorf("Load() = %q, want the file contents %q", b, tt.content)
+			}
+			if c.Name != "a" || c.Count != 2 {
+				t.Errorf("Load() decoded %+v", c)
+			}
+		})
+	}
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 
 	"github.com/cccteam/deployment-tools/cmd"
+	"github.com/cccteam/deployment-tools/internal/deployerr"
 	"github.com/go-playground/errors/v5"
 	_ "github.com/golang-migrate/migrate/v4/source/file" // up/down script file source driver for the migrate package
 	"github.com/jtwatson/shutdown"
@@ -13,6 +15,9 @@ import (
 func main() {
 	ctx := context.Background()
 	if err := execute(ctx); err != nil {
+		if deployerr.IsReported(err) {
+			os.Exit(1)
+		}
 		log.Fatal(err)
 	}
 }